@@ -0,0 +1,362 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package a2a
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultAPIKeyHeader is the header an API key is sent in when the agent card
+// does not declare an API key security scheme
+const defaultAPIKeyHeader = "X-API-Key"
+
+// maxEventSize bounds the size of one server-sent event
+const maxEventSize = 4 * 1024 * 1024
+
+// Client calls an A2A agent
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	bearerToken string
+	apiKey      string
+
+	card   *AgentCard
+	cardMu sync.Mutex
+
+	nextID atomic.Int64
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the HTTP client used for calls. Streaming calls last as
+// long as the task, so the client should not set an overall timeout.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken authenticates calls with a bearer token
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// WithAPIKey authenticates calls with an API key. The key is sent where the
+// agent card's API key security scheme asks for it, or in the X-API-Key
+// header if the card declares none.
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithAgentCard sets the agent card instead of fetching it from the agent
+func WithAgentCard(card *AgentCard) ClientOption {
+	return func(c *Client) {
+		c.card = card
+	}
+}
+
+// NewClient creates a client for the agent at baseURL. The agent card is
+// fetched from the well-known path under baseURL, unless baseURL is the URL of
+// the card itself.
+func NewClient(baseURL string, options ...ClientOption) *Client {
+	client := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// AgentCard returns the agent card, fetching it on first use
+func (c *Client) AgentCard(ctx context.Context) (*AgentCard, error) {
+	c.cardMu.Lock()
+	defer c.cardMu.Unlock()
+
+	if c.card != nil {
+		return c.card, nil
+	}
+
+	card, err := c.fetchAgentCard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.card = card
+	return card, nil
+}
+
+// fetchAgentCard downloads the agent card, trying the path used by earlier
+// protocol versions if the current one is not found
+func (c *Client) fetchAgentCard(ctx context.Context) (*AgentCard, error) {
+	urls := []string{c.baseURL + AgentCardPath, c.baseURL + legacyAgentCardPath}
+	if strings.HasSuffix(c.baseURL, ".json") {
+		urls = []string{c.baseURL}
+	}
+
+	var lastErr error
+	for _, url := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch agent card: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("agent card not found at %s", url)
+			continue
+		}
+
+		card, err := decodeAgentCard(resp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if card.URL == "" {
+			card.URL = strings.TrimSuffix(strings.TrimSuffix(c.baseURL, AgentCardPath), legacyAgentCardPath)
+		}
+		return card, nil
+	}
+	return nil, lastErr
+}
+
+// decodeAgentCard reads the agent card from a response
+func decodeAgentCard(resp *http.Response) (*AgentCard, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch agent card: status %d", resp.StatusCode)
+	}
+
+	var card AgentCard
+	if err := json.NewDecoder(resp.Body).Decode(&card); err != nil {
+		return nil, fmt.Errorf("failed to parse agent card: %w", err)
+	}
+	return &card, nil
+}
+
+// SendMessage sends a message and returns the agent's answer: a message, or
+// the task created or continued by the message
+func (c *Client) SendMessage(ctx context.Context, params *MessageSendParams) (*Result, error) {
+	var result Result
+	if err := c.call(ctx, MethodMessageSend, params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StreamMessage sends a message and calls handle with every object the agent
+// streams back, until the stream ends or handle returns an error
+func (c *Client) StreamMessage(ctx context.Context, params *MessageSendParams, handle func(*Result) error) error {
+	resp, err := c.post(ctx, MethodMessageStream, params, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// An agent may answer a streaming call with a single JSON-RPC response
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var result Result
+		if err := decodeResponse(resp.Body, &result); err != nil {
+			return err
+		}
+		return handle(&result)
+	}
+
+	return readEvents(resp.Body, func(data []byte) error {
+		var result Result
+		if err := decodeResponse(bytes.NewReader(data), &result); err != nil {
+			return err
+		}
+		return handle(&result)
+	})
+}
+
+// GetTask returns the current state of a task
+func (c *Client) GetTask(ctx context.Context, params *TaskQueryParams) (*Task, error) {
+	var task Task
+	if err := c.call(ctx, MethodTasksGet, params, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CancelTask asks the agent to cancel a task
+func (c *Client) CancelTask(ctx context.Context, taskID string) (*Task, error) {
+	var task Task
+	if err := c.call(ctx, MethodTasksCancel, &TaskIDParams{ID: taskID}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// call makes a JSON-RPC call and decodes its result
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	resp, err := c.post(ctx, method, params, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp.Body, result)
+}
+
+// post sends a JSON-RPC request to the agent's endpoint
+func (c *Client) post(ctx context.Context, method string, params interface{}, accept string) (*http.Response, error) {
+	card, err := c.AgentCard(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+
+	body, err := json.Marshal(&Request{
+		JSONRPC: jsonRPCVersion,
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  rawParams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, card.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	c.authenticate(req, card)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("%s: agent %s rejected the credentials: status %d", method, card.Name, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s: agent returned status %d: %s", method, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// authenticate adds the configured credentials to a request
+func (c *Client) authenticate(req *http.Request, card *AgentCard) {
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	if c.apiKey == "" {
+		return
+	}
+
+	in, name := "header", defaultAPIKeyHeader
+	for _, scheme := range card.SecuritySchemes {
+		if scheme.Type == "apiKey" && scheme.Name != "" {
+			in, name = scheme.In, scheme.Name
+			break
+		}
+	}
+
+	switch in {
+	case "query":
+		query := req.URL.Query()
+		query.Set(name, c.apiKey)
+		req.URL.RawQuery = query.Encode()
+	case "cookie":
+		req.AddCookie(&http.Cookie{Name: name, Value: c.apiKey})
+	default:
+		req.Header.Set(name, c.apiKey)
+	}
+}
+
+// decodeResponse reads a JSON-RPC response and decodes its result
+func decodeResponse(r io.Reader, result interface{}) error {
+	var resp Response
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to parse agent response: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to parse agent response: %w", err)
+	}
+	return nil
+}
+
+// readEvents reads a server-sent event stream and calls handle with the data
+// of each event
+func readEvents(r io.Reader, handle func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if data.Len() > 0 {
+				if err := handle(data.Bytes()); err != nil {
+					return err
+				}
+				data.Reset()
+			}
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+		// Other fields (event, id, retry) and comments are not used
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+
+	if data.Len() > 0 {
+		return handle(data.Bytes())
+	}
+	return nil
+}
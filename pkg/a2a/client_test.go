@@ -0,0 +1,199 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientAgentCardDiscovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		cardPath string
+		clientAt string
+	}{
+		{name: "current path", cardPath: AgentCardPath},
+		{name: "legacy path", cardPath: legacyAgentCardPath},
+		{name: "card URL", cardPath: "/cards/agent.json", clientAt: "/cards/agent.json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != test.cardPath {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(&AgentCard{Name: "agent"})
+			}))
+			defer server.Close()
+
+			card, err := NewClient(server.URL + test.clientAt).AgentCard(context.Background())
+			if err != nil {
+				t.Fatalf("AgentCard: %v", err)
+			}
+			if card.Name != "agent" {
+				t.Errorf("card name = %q, want agent", card.Name)
+			}
+			if test.clientAt == "" && card.URL != server.URL {
+				t.Errorf("card URL = %q, want the base URL %q", card.URL, server.URL)
+			}
+		})
+	}
+}
+
+func TestClientAgentCardNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).AgentCard(context.Background()); err == nil {
+		t.Errorf("AgentCard returned no error for an agent without a card")
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+	}{
+		{
+			name:   "one line per event",
+			stream: "data: {\"a\":1}\n\ndata: {\"b\":2}\n\n",
+			want:   []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: {\"a\":\ndata: 1}\n\n",
+			want:   []string{"{\"a\":\n1}"},
+		},
+		{
+			name:   "trailing event without a blank line",
+			stream: "data: {\"a\":1}\n\ndata: {\"b\":2}",
+			want:   []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:   "comments and other fields",
+			stream: ": keep-alive\nevent: update\nid: 7\ndata:{\"a\":1}\n\n",
+			want:   []string{`{"a":1}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			err := readEvents(strings.NewReader(test.stream), func(data []byte) error {
+				got = append(got, string(data))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readEvents: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("events = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClientStreamMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// The status update spans two data lines and the last event has no
+		// blank line after it
+		w.Write([]byte("data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"task\",\"id\":\"t\",\"contextId\":\"c\",\"status\":{\"state\":\"submitted\"}}}\n\n" +
+			"data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"status-update\",\"taskId\":\"t\",\"contextId\":\"c\",\n" +
+			"data: \"status\":{\"state\":\"completed\"},\"final\":true}}"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithAgentCard(&AgentCard{Name: "agent", URL: server.URL}))
+	var results []*Result
+	err := client.StreamMessage(context.Background(), &MessageSendParams{Message: NewTextMessage(RoleUser, "Hi")}, func(result *Result) error {
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamMessage: %v", err)
+	}
+
+	if len(results) != 2 || results[0].Task == nil || results[1].StatusUpdate == nil {
+		t.Fatalf("results = %+v, want a task and a status update", results)
+	}
+	if state := results[1].StatusUpdate.Status.State; state != TaskStateCompleted {
+		t.Errorf("final state = %s, want %s", state, TaskStateCompleted)
+	}
+}
+
+func TestClientAPIKeyPlacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		schemes map[string]SecurityScheme
+		key     func(r *http.Request) string
+	}{
+		{
+			name: "default header",
+			key:  func(r *http.Request) string { return r.Header.Get(defaultAPIKeyHeader) },
+		},
+		{
+			name:    "header",
+			schemes: map[string]SecurityScheme{"key": {Type: "apiKey", In: "header", Name: "X-Agent-Key"}},
+			key:     func(r *http.Request) string { return r.Header.Get("X-Agent-Key") },
+		},
+		{
+			name:    "query",
+			schemes: map[string]SecurityScheme{"key": {Type: "apiKey", In: "query", Name: "key"}},
+			key:     func(r *http.Request) string { return r.URL.Query().Get("key") },
+		},
+		{
+			name:    "cookie",
+			schemes: map[string]SecurityScheme{"key": {Type: "apiKey", In: "cookie", Name: "agent_key"}},
+			key: func(r *http.Request) string {
+				cookie, err := r.Cookie("agent_key")
+				if err != nil {
+					return ""
+				}
+				return cookie.Value
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = test.key(r)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"t","contextId":"c","status":{"state":"completed"}}}`))
+			}))
+			defer server.Close()
+
+			card := &AgentCard{Name: "agent", URL: server.URL, SecuritySchemes: test.schemes}
+			client := NewClient(server.URL, WithAgentCard(card), WithAPIKey("secret"))
+			if _, err := client.GetTask(context.Background(), &TaskQueryParams{ID: "t"}); err != nil {
+				t.Fatalf("GetTask: %v", err)
+			}
+			if got != "secret" {
+				t.Errorf("API key = %q, want secret", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package a2a implements the Agent2Agent (A2A) protocol, which lets agents
+built with different frameworks call each other over HTTP.
+
+An A2A server publishes an agent card at a well-known path describing the
+agent, its skills and how to authenticate. Clients send messages with JSON-RPC
+2.0 calls; the server answers with a message or a task, whose status and
+artifacts can also be streamed as server-sent events.
+
+Example usage:
+
+	ctx := context.Background()
+
+	client := a2a.NewClient("https://agents.example.com/billing",
+		a2a.WithBearerToken(os.Getenv("BILLING_AGENT_TOKEN")))
+
+	card, err := client.AgentCard(ctx)
+	if err != nil {
+		log.Fatalf("Failed to resolve agent card: %v", err)
+	}
+	fmt.Println("Talking to", card.Name)
+
+	result, err := client.SendMessage(ctx, &a2a.MessageSendParams{
+		Message: a2a.NewTextMessage(a2a.RoleUser, "What is my balance?"),
+	})
+	if err != nil {
+		log.Fatalf("Failed to send message: %v", err)
+	}
+	if result.Task != nil {
+		fmt.Println("Task state:", result.Task.Status.State)
+	}
+
+agents.RemoteAgent uses this client to run a remote A2A agent as part of an
+agent tree.
+
+Server publishes an agent: it serves the agent card and the JSON-RPC methods,
+and runs messages with an Executor. runners.NewA2AServer creates a server for
+an ADK agent, which "adk api_server --a2a" mounts for every loaded agent.
+*/
+package a2a
@@ -0,0 +1,598 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Executor runs the agent behind a Server
+type Executor interface {
+	// Execute handles a message sent to a task. It reports the agent's
+	// progress and output through the updater and returns once the task is
+	// completed, failed or waiting for the client. The task is completed if
+	// Execute returns while it is still working, and failed if it returns an
+	// error. ctx is canceled when the client cancels the task.
+	Execute(ctx context.Context, task *Task, message *Message, updater *TaskUpdater) error
+}
+
+// ErrTaskNotFound is returned by a TaskStore for unknown tasks
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskStore stores the tasks of a Server
+type TaskStore interface {
+	// GetTask returns a task, or ErrTaskNotFound
+	GetTask(ctx context.Context, taskID string) (*Task, error)
+
+	// SaveTask creates or updates a task
+	SaveTask(ctx context.Context, task *Task) error
+}
+
+// NewTaskID creates the ID of a new task in a context. Task IDs start with
+// the context ID, so stores can find the context of a task from its ID.
+func NewTaskID(contextID string) string {
+	return contextID + "." + uuid.New().String()
+}
+
+// TaskContextID returns the context ID a task ID was created for
+func TaskContextID(taskID string) string {
+	if i := strings.LastIndex(taskID, "."); i >= 0 {
+		return taskID[:i]
+	}
+	return ""
+}
+
+// InMemoryTaskStore keeps tasks in memory
+type InMemoryTaskStore struct {
+	tasks map[string][]byte
+	mu    sync.RWMutex
+}
+
+// NewInMemoryTaskStore creates an InMemoryTaskStore
+func NewInMemoryTaskStore() *InMemoryTaskStore {
+	return &InMemoryTaskStore{tasks: make(map[string][]byte)}
+}
+
+// GetTask returns a copy of a stored task
+func (s *InMemoryTaskStore) GetTask(ctx context.Context, taskID string) (*Task, error) {
+	s.mu.RLock()
+	data, ok := s.tasks[taskID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// SaveTask stores a copy of a task
+func (s *InMemoryTaskStore) SaveTask(ctx context.Context, task *Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[task.ID] = data
+	return nil
+}
+
+// Server serves an agent over the A2A protocol: the agent card at the
+// well-known path, and JSON-RPC calls posted to any other path
+type Server struct {
+	card     *AgentCard
+	executor Executor
+	store    TaskStore
+
+	running map[string]*runningTask
+	mu      sync.Mutex
+}
+
+// runningTask is a task whose executor is running, or about to run
+type runningTask struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithTaskStore sets where the server stores tasks. The default keeps them
+// in memory.
+func WithTaskStore(store TaskStore) ServerOption {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// NewServer creates a server for the agent described by card. If card.URL
+// is empty, it is derived from the URL the card is requested at.
+func NewServer(card *AgentCard, executor Executor, options ...ServerOption) *Server {
+	server := &Server{
+		card:     card,
+		executor: executor,
+		store:    NewInMemoryTaskStore(),
+		running:  make(map[string]*runningTask),
+	}
+
+	for _, option := range options {
+		option(server)
+	}
+	return server
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, AgentCardPath) || strings.HasSuffix(r.URL.Path, legacyAgentCardPath) {
+		s.serveAgentCard(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPCError(w, nil, ErrorCodeParseError, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		writeRPCError(w, req.ID, ErrorCodeInvalidRequest, "invalid JSON-RPC request")
+		return
+	}
+
+	switch req.Method {
+	case MethodMessageSend:
+		s.handleMessageSend(w, r, &req)
+	case MethodMessageStream:
+		s.handleMessageStream(w, r, &req)
+	case MethodTasksGet:
+		s.handleTasksGet(w, r, &req)
+	case MethodTasksCancel:
+		s.handleTasksCancel(w, r, &req)
+	default:
+		writeRPCError(w, req.ID, ErrorCodeMethodNotFound, fmt.Sprintf("method %s not found", req.Method))
+	}
+}
+
+// serveAgentCard writes the agent card
+func (s *Server) serveAgentCard(w http.ResponseWriter, r *http.Request) {
+	card := *s.card
+	if card.URL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		// RequestURI keeps the path a parent mux may have stripped
+		path := strings.SplitN(r.RequestURI, "?", 2)[0]
+		path = strings.TrimSuffix(strings.TrimSuffix(path, AgentCardPath), legacyAgentCardPath)
+		card.URL = fmt.Sprintf("%s://%s%s/", scheme, r.Host, path)
+	}
+	if card.ProtocolVersion == "" {
+		card.ProtocolVersion = ProtocolVersion
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&card)
+}
+
+// handleMessageSend runs a message, waiting for the task to finish if the
+// client asked for a blocking call
+func (s *Server) handleMessageSend(w http.ResponseWriter, r *http.Request, req *Request) {
+	var params MessageSendParams
+	if !decodeParams(w, req, &params) {
+		return
+	}
+
+	task, running, rpcErr := s.prepareTask(r.Context(), params.Message)
+	if rpcErr != nil {
+		writeRPCError(w, req.ID, rpcErr.Code, rpcErr.Message)
+		return
+	}
+
+	done := s.start(task, running, params.Message, nil)
+	if params.Configuration != nil && params.Configuration.Blocking {
+		<-done
+	}
+
+	task, err := s.store.GetTask(r.Context(), task.ID)
+	if err != nil {
+		writeRPCError(w, req.ID, ErrorCodeInternalError, err.Error())
+		return
+	}
+	writeRPCResult(w, req.ID, trimHistory(task, params.Configuration))
+}
+
+// handleMessageStream runs a message and streams the task updates as
+// server-sent events
+func (s *Server) handleMessageStream(w http.ResponseWriter, r *http.Request, req *Request) {
+	var params MessageSendParams
+	if !decodeParams(w, req, &params) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeRPCError(w, req.ID, ErrorCodeUnsupportedOperation, "streaming is not supported by this server")
+		return
+	}
+
+	task, running, rpcErr := s.prepareTask(r.Context(), params.Message)
+	if rpcErr != nil {
+		writeRPCError(w, req.ID, rpcErr.Code, rpcErr.Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	var writeMu sync.Mutex
+	publish := func(result *Result) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+
+		data, err := json.Marshal(newRPCResult(req.ID, result))
+		if err != nil {
+			log.Printf("Failed to encode A2A stream event: %v", err)
+			return
+		}
+		// Write errors mean the client went away; the task keeps running
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+
+	publish(&Result{Task: task})
+	<-s.start(task, running, params.Message, publish)
+}
+
+// handleTasksGet returns a task
+func (s *Server) handleTasksGet(w http.ResponseWriter, r *http.Request, req *Request) {
+	var params TaskQueryParams
+	if !decodeParams(w, req, &params) {
+		return
+	}
+
+	task, err := s.store.GetTask(r.Context(), params.ID)
+	if err != nil {
+		writeStoreError(w, req.ID, err)
+		return
+	}
+	writeRPCResult(w, req.ID, trimHistory(task, &MessageSendConfiguration{HistoryLength: params.HistoryLength}))
+}
+
+// handleTasksCancel cancels a task that has not finished
+func (s *Server) handleTasksCancel(w http.ResponseWriter, r *http.Request, req *Request) {
+	var params TaskIDParams
+	if !decodeParams(w, req, &params) {
+		return
+	}
+
+	task, err := s.store.GetTask(r.Context(), params.ID)
+	if err != nil {
+		writeStoreError(w, req.ID, err)
+		return
+	}
+	if task.Status.State.IsTerminal() {
+		writeRPCError(w, req.ID, ErrorCodeTaskNotCancelable, fmt.Sprintf("task %s is %s", task.ID, task.Status.State))
+		return
+	}
+
+	s.mu.Lock()
+	running := s.running[task.ID]
+	s.mu.Unlock()
+
+	if running != nil {
+		// The task goroutine records the cancellation
+		running.cancel()
+		<-running.done
+	} else {
+		task.Status = TaskStatus{State: TaskStateCanceled, Timestamp: Timestamp(time.Now())}
+		if err := s.store.SaveTask(r.Context(), task); err != nil {
+			writeRPCError(w, req.ID, ErrorCodeInternalError, err.Error())
+			return
+		}
+	}
+
+	task, err = s.store.GetTask(r.Context(), params.ID)
+	if err != nil {
+		writeStoreError(w, req.ID, err)
+		return
+	}
+	writeRPCResult(w, req.ID, task)
+}
+
+// prepareTask returns the task a message continues, or creates a new one,
+// with the message added to its history. The task is reserved for the
+// message, see reserve, and must be started or released.
+func (s *Server) prepareTask(ctx context.Context, message *Message) (*Task, *runningTask, *Error) {
+	if message == nil || len(message.Parts) == 0 {
+		return nil, nil, &Error{Code: ErrorCodeInvalidParams, Message: "message has no parts"}
+	}
+	if message.MessageID == "" {
+		message.MessageID = uuid.New().String()
+	}
+	message.Kind = KindMessage
+
+	var task *Task
+	var running *runningTask
+	if message.TaskID != "" {
+		// The task is reserved before it is read, so it cannot change until
+		// this message's run saves it
+		var ok bool
+		if running, ok = s.reserve(message.TaskID); !ok {
+			return nil, nil, &Error{Code: ErrorCodeInvalidParams, Message: fmt.Sprintf("task %s is still running", message.TaskID)}
+		}
+
+		var err error
+		task, err = s.store.GetTask(ctx, message.TaskID)
+		if errors.Is(err, ErrTaskNotFound) {
+			s.release(message.TaskID, running)
+			return nil, nil, &Error{Code: ErrorCodeTaskNotFound, Message: fmt.Sprintf("task %s not found", message.TaskID)}
+		}
+		if err != nil {
+			s.release(message.TaskID, running)
+			return nil, nil, &Error{Code: ErrorCodeInternalError, Message: err.Error()}
+		}
+		if task.Status.State.IsTerminal() {
+			s.release(task.ID, running)
+			return nil, nil, &Error{Code: ErrorCodeInvalidParams, Message: fmt.Sprintf("task %s is %s and cannot be continued", task.ID, task.Status.State)}
+		}
+	} else {
+		contextID := message.ContextID
+		if contextID == "" {
+			contextID = uuid.New().String()
+		}
+		task = &Task{
+			Kind:      KindTask,
+			ID:        NewTaskID(contextID),
+			ContextID: contextID,
+			Status:    TaskStatus{State: TaskStateSubmitted, Timestamp: Timestamp(time.Now())},
+		}
+		running, _ = s.reserve(task.ID)
+	}
+
+	message.TaskID = task.ID
+	message.ContextID = task.ContextID
+	task.History = append(task.History, *message)
+
+	if err := s.store.SaveTask(ctx, task); err != nil {
+		s.release(task.ID, running)
+		return nil, nil, &Error{Code: ErrorCodeInternalError, Message: err.Error()}
+	}
+	return task, running, nil
+}
+
+// reserve registers a task as running, unless it already is. Checking and
+// registering happen in one step, so only one message runs a task at a time.
+func (s *Server) reserve(taskID string) (*runningTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[taskID]; ok {
+		return nil, false
+	}
+
+	// Tasks outlive the request that started them
+	ctx, cancel := context.WithCancel(context.Background())
+	running := &runningTask{ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	s.running[taskID] = running
+	return running, true
+}
+
+// release ends the reservation of a task
+func (s *Server) release(taskID string, running *runningTask) {
+	s.mu.Lock()
+	delete(s.running, taskID)
+	s.mu.Unlock()
+
+	running.cancel()
+	close(running.done)
+}
+
+// start runs the executor for a message on a reserved task in the background.
+// Updates are passed to publish, if set. The returned channel is closed when
+// the executor has returned and the final status is saved.
+func (s *Server) start(task *Task, running *runningTask, message *Message, publish func(*Result)) <-chan struct{} {
+	ctx := running.ctx
+	updater := &TaskUpdater{store: s.store, task: task, publish: publish}
+
+	go func() {
+		defer s.release(task.ID, running)
+
+		if err := updater.UpdateStatus(TaskStateWorking, nil); err != nil {
+			log.Printf("Failed to update A2A task %s: %v", task.ID, err)
+		}
+
+		err := s.executor.Execute(ctx, updater.Task(), message, updater)
+
+		var finalErr error
+		switch {
+		case ctx.Err() != nil:
+			finalErr = updater.UpdateStatus(TaskStateCanceled, nil)
+		case err != nil:
+			finalErr = updater.UpdateStatus(TaskStateFailed, NewTextMessage(RoleAgent, err.Error()))
+		case !updater.State().IsTerminal() && !updater.State().IsInterrupted():
+			finalErr = updater.UpdateStatus(TaskStateCompleted, nil)
+		}
+		if finalErr != nil {
+			log.Printf("Failed to update A2A task %s: %v", task.ID, finalErr)
+		}
+	}()
+
+	return running.done
+}
+
+// TaskUpdater records the progress of a task and publishes it to streaming
+// clients
+type TaskUpdater struct {
+	store   TaskStore
+	task    *Task
+	publish func(*Result)
+	mu      sync.Mutex
+}
+
+// Task returns a snapshot of the task
+func (u *TaskUpdater) Task() *Task {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	task := *u.task
+	task.Artifacts = append([]Artifact(nil), u.task.Artifacts...)
+	task.History = append([]Message(nil), u.task.History...)
+	return &task
+}
+
+// State returns the current state of the task
+func (u *TaskUpdater) State() TaskState {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.task.Status.State
+}
+
+// UpdateStatus changes the state of the task. message, if set, is an agent
+// message explaining the state, such as the question of an input-required
+// task.
+func (u *TaskUpdater) UpdateStatus(state TaskState, message *Message) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if message != nil {
+		message.Kind = KindMessage
+		if message.MessageID == "" {
+			message.MessageID = uuid.New().String()
+		}
+		if message.Role == "" {
+			message.Role = RoleAgent
+		}
+		message.TaskID = u.task.ID
+		message.ContextID = u.task.ContextID
+		u.task.History = append(u.task.History, *message)
+	}
+
+	u.task.Status = TaskStatus{State: state, Message: message, Timestamp: Timestamp(time.Now())}
+	if err := u.store.SaveTask(context.Background(), u.task); err != nil {
+		return err
+	}
+
+	if u.publish != nil {
+		u.publish(&Result{StatusUpdate: &TaskStatusUpdateEvent{
+			Kind:      KindStatusUpdate,
+			TaskID:    u.task.ID,
+			ContextID: u.task.ContextID,
+			Status:    u.task.Status,
+			Final:     state.IsTerminal() || state.IsInterrupted(),
+		}})
+	}
+	return nil
+}
+
+// AddArtifact adds an output to the task
+func (u *TaskUpdater) AddArtifact(artifact Artifact) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if artifact.ArtifactID == "" {
+		artifact.ArtifactID = uuid.New().String()
+	}
+	u.task.Artifacts = append(u.task.Artifacts, artifact)
+	if err := u.store.SaveTask(context.Background(), u.task); err != nil {
+		return err
+	}
+
+	if u.publish != nil {
+		u.publish(&Result{ArtifactUpdate: &TaskArtifactUpdateEvent{
+			Kind:      KindArtifactUpdate,
+			TaskID:    u.task.ID,
+			ContextID: u.task.ContextID,
+			Artifact:  artifact,
+			LastChunk: true,
+		}})
+	}
+	return nil
+}
+
+// trimHistory limits the history of a task to the length the client asked for
+func trimHistory(task *Task, config *MessageSendConfiguration) *Task {
+	if config == nil || config.HistoryLength == nil {
+		return task
+	}
+	if n := *config.HistoryLength; n >= 0 && n < len(task.History) {
+		task.History = task.History[len(task.History)-n:]
+	}
+	return task
+}
+
+// decodeParams decodes the params of a request, answering with an error if
+// they are invalid
+func decodeParams(w http.ResponseWriter, req *Request, params interface{}) bool {
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		writeRPCError(w, req.ID, ErrorCodeInvalidParams, fmt.Sprintf("invalid params: %v", err))
+		return false
+	}
+	return true
+}
+
+// newRPCResult creates a successful JSON-RPC response
+func newRPCResult(id interface{}, result interface{}) *Response {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return &Response{JSONRPC: jsonRPCVersion, ID: id, Error: &Error{Code: ErrorCodeInternalError, Message: err.Error()}}
+	}
+	return &Response{JSONRPC: jsonRPCVersion, ID: id, Result: data}
+}
+
+// writeRPCResult writes a successful JSON-RPC response
+func writeRPCResult(w http.ResponseWriter, id interface{}, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newRPCResult(id, result))
+}
+
+// writeRPCError writes a JSON-RPC error response
+func writeRPCError(w http.ResponseWriter, id interface{}, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&Response{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Error:   &Error{Code: code, Message: message},
+	})
+}
+
+// writeStoreError writes the error returned by the task store
+func writeStoreError(w http.ResponseWriter, id interface{}, err error) {
+	if errors.Is(err, ErrTaskNotFound) {
+		writeRPCError(w, id, ErrorCodeTaskNotFound, err.Error())
+		return
+	}
+	writeRPCError(w, id, ErrorCodeInternalError, err.Error())
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package a2a
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowTaskStore is a task store that takes a while to save a task, leaving
+// room for concurrent messages to interleave
+type slowTaskStore struct {
+	*InMemoryTaskStore
+}
+
+func (s *slowTaskStore) SaveTask(ctx context.Context, task *Task) error {
+	time.Sleep(10 * time.Millisecond)
+	return s.InMemoryTaskStore.SaveTask(ctx, task)
+}
+
+// gateExecutor asks for input on a task's first message and then blocks every
+// later message until release is closed
+type gateExecutor struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (e *gateExecutor) Execute(ctx context.Context, task *Task, message *Message, updater *TaskUpdater) error {
+	if atomic.AddInt32(&e.calls, 1) == 1 {
+		return updater.UpdateStatus(TaskStateInputRequired, NewTextMessage(RoleAgent, "Which account?"))
+	}
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func TestServerRunsOneMessagePerTask(t *testing.T) {
+	executor := &gateExecutor{release: make(chan struct{})}
+	server := httptest.NewServer(NewServer(&AgentCard{Name: "agent"}, executor,
+		WithTaskStore(&slowTaskStore{NewInMemoryTaskStore()})))
+	defer server.Close()
+	defer close(executor.release)
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+
+	result, err := client.SendMessage(ctx, &MessageSendParams{
+		Message:       NewTextMessage(RoleUser, "Pay the bill"),
+		Configuration: &MessageSendConfiguration{Blocking: true},
+	})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if result.Task == nil || result.Task.Status.State != TaskStateInputRequired {
+		t.Fatalf("result = %+v, want a task requiring input", result)
+	}
+
+	var accepted int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			message := NewTextMessage(RoleUser, "The main account")
+			message.TaskID = result.Task.ID
+			if _, err := client.SendMessage(ctx, &MessageSendParams{Message: message}); err == nil {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Errorf("%d concurrent messages to the task were accepted, want 1", accepted)
+	}
+}
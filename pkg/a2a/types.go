@@ -0,0 +1,376 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package a2a
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProtocolVersion is the version of the A2A protocol implemented by this package
+const ProtocolVersion = "0.3.0"
+
+// AgentCardPath is the well-known path of the agent card, relative to the
+// agent's base URL
+const AgentCardPath = "/.well-known/agent-card.json"
+
+// legacyAgentCardPath is where servers implementing earlier protocol
+// versions publish the agent card
+const legacyAgentCardPath = "/.well-known/agent.json"
+
+// JSON-RPC methods of the protocol
+const (
+	MethodMessageSend   = "message/send"
+	MethodMessageStream = "message/stream"
+	MethodTasksGet      = "tasks/get"
+	MethodTasksCancel   = "tasks/cancel"
+)
+
+// TaskState is the state of a task
+type TaskState string
+
+// Task states
+const (
+	TaskStateSubmitted     TaskState = "submitted"
+	TaskStateWorking       TaskState = "working"
+	TaskStateInputRequired TaskState = "input-required"
+	TaskStateAuthRequired  TaskState = "auth-required"
+	TaskStateCompleted     TaskState = "completed"
+	TaskStateCanceled      TaskState = "canceled"
+	TaskStateFailed        TaskState = "failed"
+	TaskStateRejected      TaskState = "rejected"
+	TaskStateUnknown       TaskState = "unknown"
+)
+
+// IsTerminal reports whether a task in this state will not change any more
+func (s TaskState) IsTerminal() bool {
+	switch s {
+	case TaskStateCompleted, TaskStateCanceled, TaskStateFailed, TaskStateRejected:
+		return true
+	}
+	return false
+}
+
+// IsInterrupted reports whether a task in this state waits for the client
+func (s TaskState) IsInterrupted() bool {
+	return s == TaskStateInputRequired || s == TaskStateAuthRequired
+}
+
+// Timestamp formats a time as the protocol expects
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Message roles
+const (
+	RoleUser  = "user"
+	RoleAgent = "agent"
+)
+
+// Kinds of objects exchanged by the protocol
+const (
+	KindMessage        = "message"
+	KindTask           = "task"
+	KindStatusUpdate   = "status-update"
+	KindArtifactUpdate = "artifact-update"
+	KindText           = "text"
+	KindFile           = "file"
+	KindData           = "data"
+)
+
+// AgentCard describes an agent and how to reach it
+type AgentCard struct {
+	Name               string                    `json:"name"`
+	Description        string                    `json:"description"`
+	URL                string                    `json:"url"`
+	Version            string                    `json:"version"`
+	ProtocolVersion    string                    `json:"protocolVersion,omitempty"`
+	Capabilities       AgentCapabilities         `json:"capabilities"`
+	DefaultInputModes  []string                  `json:"defaultInputModes"`
+	DefaultOutputModes []string                  `json:"defaultOutputModes"`
+	Skills             []AgentSkill              `json:"skills"`
+	SecuritySchemes    map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+	Security           []map[string][]string     `json:"security,omitempty"`
+}
+
+// AgentCapabilities lists the optional protocol features an agent supports
+type AgentCapabilities struct {
+	Streaming         bool `json:"streaming,omitempty"`
+	PushNotifications bool `json:"pushNotifications,omitempty"`
+}
+
+// AgentSkill describes something an agent can do
+type AgentSkill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// SecurityScheme describes how clients authenticate, following the OpenAPI
+// security scheme object
+type SecurityScheme struct {
+	// Type is "apiKey", "http", "oauth2" or "openIdConnect"
+	Type string `json:"type"`
+
+	// Scheme is the HTTP authentication scheme, such as "bearer"
+	Scheme string `json:"scheme,omitempty"`
+
+	// In is where an API key goes: "header", "query" or "cookie"
+	In string `json:"in,omitempty"`
+
+	// Name is the name of the API key header, query parameter or cookie
+	Name string `json:"name,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// Part is a piece of message or artifact content. Kind selects the field
+// holding the content.
+type Part struct {
+	Kind     string                 `json:"kind"`
+	Text     string                 `json:"text,omitempty"`
+	File     *FileContent           `json:"file,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// FileContent is a file sent inline as base64 bytes or by URI
+type FileContent struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Bytes    string `json:"bytes,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+// TextPart creates a text part
+func TextPart(text string) Part {
+	return Part{Kind: KindText, Text: text}
+}
+
+// Message is a turn of the conversation between a client and an agent
+type Message struct {
+	Kind      string                 `json:"kind"`
+	MessageID string                 `json:"messageId"`
+	Role      string                 `json:"role"`
+	Parts     []Part                 `json:"parts"`
+	ContextID string                 `json:"contextId,omitempty"`
+	TaskID    string                 `json:"taskId,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// NewTextMessage creates a message with a single text part
+func NewTextMessage(role, text string) *Message {
+	return &Message{
+		Kind:      KindMessage,
+		MessageID: uuid.New().String(),
+		Role:      role,
+		Parts:     []Part{TextPart(text)},
+	}
+}
+
+// Text returns the text parts of the message, joined by newlines
+func (m *Message) Text() string {
+	if m == nil {
+		return ""
+	}
+	return partsText(m.Parts)
+}
+
+// partsText joins the text parts of some content
+func partsText(parts []Part) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Kind == KindText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Task is a unit of work an agent performs for a client
+type Task struct {
+	Kind      string                 `json:"kind"`
+	ID        string                 `json:"id"`
+	ContextID string                 `json:"contextId"`
+	Status    TaskStatus             `json:"status"`
+	Artifacts []Artifact             `json:"artifacts,omitempty"`
+	History   []Message              `json:"history,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskStatus is the state of a task, with the agent message that explains it
+type TaskStatus struct {
+	State     TaskState `json:"state"`
+	Message   *Message  `json:"message,omitempty"`
+	Timestamp string    `json:"timestamp,omitempty"`
+}
+
+// Artifact is an output produced by a task
+type Artifact struct {
+	ArtifactID  string                 `json:"artifactId"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parts       []Part                 `json:"parts"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskStatusUpdateEvent is streamed when the status of a task changes
+type TaskStatusUpdateEvent struct {
+	Kind      string                 `json:"kind"`
+	TaskID    string                 `json:"taskId"`
+	ContextID string                 `json:"contextId"`
+	Status    TaskStatus             `json:"status"`
+	Final     bool                   `json:"final"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// TaskArtifactUpdateEvent is streamed when a task produces an artifact, or a
+// chunk of one
+type TaskArtifactUpdateEvent struct {
+	Kind      string                 `json:"kind"`
+	TaskID    string                 `json:"taskId"`
+	ContextID string                 `json:"contextId"`
+	Artifact  Artifact               `json:"artifact"`
+	Append    bool                   `json:"append,omitempty"`
+	LastChunk bool                   `json:"lastChunk,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// MessageSendParams are the parameters of message/send and message/stream
+type MessageSendParams struct {
+	Message       *Message                  `json:"message"`
+	Configuration *MessageSendConfiguration `json:"configuration,omitempty"`
+	Metadata      map[string]interface{}    `json:"metadata,omitempty"`
+}
+
+// MessageSendConfiguration configures how the agent answers a message
+type MessageSendConfiguration struct {
+	AcceptedOutputModes []string `json:"acceptedOutputModes,omitempty"`
+	HistoryLength       *int     `json:"historyLength,omitempty"`
+	Blocking            bool     `json:"blocking,omitempty"`
+}
+
+// TaskQueryParams are the parameters of tasks/get
+type TaskQueryParams struct {
+	ID            string `json:"id"`
+	HistoryLength *int   `json:"historyLength,omitempty"`
+}
+
+// TaskIDParams are the parameters of tasks/cancel
+type TaskIDParams struct {
+	ID string `json:"id"`
+}
+
+// Result is one of the objects an agent sends back: a message or a task from
+// message/send, and any of the four from message/stream. Exactly one field
+// is set.
+type Result struct {
+	Message        *Message
+	Task           *Task
+	StatusUpdate   *TaskStatusUpdateEvent
+	ArtifactUpdate *TaskArtifactUpdateEvent
+}
+
+// UnmarshalJSON decodes a result by its kind
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var header struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	*r = Result{}
+	switch header.Kind {
+	case KindMessage:
+		r.Message = &Message{}
+		return json.Unmarshal(data, r.Message)
+	case KindTask:
+		r.Task = &Task{}
+		return json.Unmarshal(data, r.Task)
+	case KindStatusUpdate:
+		r.StatusUpdate = &TaskStatusUpdateEvent{}
+		return json.Unmarshal(data, r.StatusUpdate)
+	case KindArtifactUpdate:
+		r.ArtifactUpdate = &TaskArtifactUpdateEvent{}
+		return json.Unmarshal(data, r.ArtifactUpdate)
+	}
+	return fmt.Errorf("unknown result kind %q", header.Kind)
+}
+
+// MarshalJSON encodes the field that is set
+func (r Result) MarshalJSON() ([]byte, error) {
+	switch {
+	case r.Message != nil:
+		return json.Marshal(r.Message)
+	case r.Task != nil:
+		return json.Marshal(r.Task)
+	case r.StatusUpdate != nil:
+		return json.Marshal(r.StatusUpdate)
+	case r.ArtifactUpdate != nil:
+		return json.Marshal(r.ArtifactUpdate)
+	}
+	return []byte("null"), nil
+}
+
+// JSON-RPC error codes used by the protocol
+const (
+	ErrorCodeParseError           = -32700
+	ErrorCodeInvalidRequest       = -32600
+	ErrorCodeMethodNotFound       = -32601
+	ErrorCodeInvalidParams        = -32602
+	ErrorCodeInternalError        = -32603
+	ErrorCodeTaskNotFound         = -32001
+	ErrorCodeTaskNotCancelable    = -32002
+	ErrorCodeUnsupportedOperation = -32004
+)
+
+// jsonRPCVersion is the JSON-RPC version of every request and response
+const jsonRPCVersion = "2.0"
+
+// Request is a JSON-RPC request
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error. It is returned by the client when the agent
+// answers a call with an error.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("a2a error %d: %s", e.Code, e.Message)
+}
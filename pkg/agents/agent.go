@@ -17,22 +17,18 @@ package agents
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/nvcnvn/adk-golang/pkg/events"
 	"github.com/nvcnvn/adk-golang/pkg/models"
 	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 	"github.com/nvcnvn/adk-golang/pkg/tools"
 )
 
-// BeforeAgentCallback is a function that's called before an agent processes a message
-type BeforeAgentCallback func(ctx context.Context, message string) (string, bool)
-
-// AfterAgentCallback is a function that's called after an agent processes a message
-type AfterAgentCallback func(ctx context.Context, response string) string
-
 // BaseAgent defines the interface for all agents
 type BaseAgent interface {
 	// Name returns the name of the agent
@@ -47,40 +43,145 @@ type BaseAgent interface {
 	// RootAgent returns the root agent in the agent tree
 	RootAgent() BaseAgent
 
-	// FindAgent finds an agent by name in the agent tree
+	// FindAgent finds an agent by name in this agent and its descendants
 	FindAgent(name string) BaseAgent
+
+	// SubAgents returns the children of the agent
+	SubAgents() []BaseAgent
+
+	// ParentAgent returns the parent of the agent, or nil for a root agent
+	ParentAgent() BaseAgent
+}
+
+// LlmFlow drives the interaction between an LlmAgent and its model: it builds
+// requests, calls the model, executes tools and loops until a final response.
+type LlmFlow interface {
+	// Run executes the flow with the given invocation context
+	Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error)
+
+	// RunLive executes the flow in live mode with the given invocation context
+	RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error)
+}
+
+// LlmFlowFactory creates the flow used by an LlmAgent that has no explicit Flow
+type LlmFlowFactory func(agent *LlmAgent) LlmFlow
+
+var (
+	defaultLlmFlowFactory   LlmFlowFactory
+	defaultLlmFlowFactoryMu sync.RWMutex
+)
+
+// SetDefaultLlmFlowFactory sets the factory used to create flows for LlmAgents.
+// The flows/llm_flows package registers its factory on import, which avoids
+// an import cycle between the agents and flows packages.
+func SetDefaultLlmFlowFactory(factory LlmFlowFactory) {
+	defaultLlmFlowFactoryMu.Lock()
+	defer defaultLlmFlowFactoryMu.Unlock()
+	defaultLlmFlowFactory = factory
 }
 
+// DefaultOutputSchemaRetries is the number of repair attempts NewLlmAgent
+// allows for responses that do not match the output schema
+const DefaultOutputSchemaRetries = 2
+
 // LlmAgent is a specialized agent that uses an LLM model
 type LlmAgent struct {
 	// name is the name of the agent
 	name string
 
-	// SystemInstructions contain system instructions for the LLM
+	// description describes what the agent does; other agents use it to
+	// decide whether to transfer to this agent
+	description string
+
+	// SystemInstructions contain system instructions for the LLM. Placeholders
+	// such as {key} are resolved from the session state, see InjectSessionState.
 	SystemInstructions string
 
+	// InstructionProvider, if set, builds the instructions at run time instead
+	// of SystemInstructions
+	InstructionProvider InstructionProvider
+
 	// CanonicalModel is the LLM model used by this agent
-	CanonicalModel models.BaseLlm
+	CanonicalModel models.LLM
+
+	// Flow is the flow used to run this agent. If nil, the default flow
+	// registered with SetDefaultLlmFlowFactory is used.
+	Flow LlmFlow
 
 	// CanonicalTools are the tools available to this agent
 	CanonicalTools []tools.Tool
 
+	// BeforeAgentCallback is called before the agent runs and may skip it
+	BeforeAgentCallback BeforeAgentCallback
+
+	// AfterAgentCallback is called after the agent has run and may add to or
+	// replace its final response
+	AfterAgentCallback AfterAgentCallback
+
 	// BeforeModelCallback is called before the model is invoked
 	BeforeModelCallback func(callbackContext *CallbackContext, llmRequest *models.LlmRequest) *models.LlmResponse
 
 	// AfterModelCallback is called after the model responds
 	AfterModelCallback func(callbackContext *CallbackContext, llmResponse *models.LlmResponse) *models.LlmResponse
 
+	// BeforeToolCallback is called before a tool runs. It may modify args in
+	// place. A non-nil result skips the tool and is used as its response.
+	BeforeToolCallback func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext) map[string]interface{}
+
+	// AfterToolCallback is called after a tool runs successfully. A non-nil
+	// result replaces the tool's response.
+	AfterToolCallback func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext, result map[string]interface{}) map[string]interface{}
+
+	// ToolConfirmationPolicy, if set, decides which tool calls need the user's
+	// approval, in addition to tools that always require it. Calls waiting
+	// for approval end the invocation and resume when the user answers.
+	ToolConfirmationPolicy tools.ToolConfirmationPolicy
+
+	// ToolConfig, if set, controls how the model uses the agent's tools, for
+	// instance to force a function call
+	ToolConfig *models.ToolConfig
+
+	// ErrorPolicy, if set, decides whether failed model and tool calls are
+	// retried, fed back to the model or end the agent's turn. By default
+	// DefaultErrorPolicy is used.
+	ErrorPolicy ErrorPolicy
+
+	// OutputKey, if set, is the session state key under which the agent's
+	// final response is stored
+	OutputKey string
+
+	// OutputSchema, if set, is the JSON schema of the agent's final response.
+	// The model is asked for JSON output and responses are validated against
+	// the schema; use DecodeOutput to read the result into a Go value. An
+	// agent with an OutputSchema cannot have tools, see ValidateTree.
+	OutputSchema map[string]interface{}
+
+	// OutputSchemaRetries is how many times the model is asked to correct a
+	// response that does not match OutputSchema before the agent fails
+	OutputSchemaRetries int
+
+	// DisallowTransferToParent prevents the model from transferring the
+	// conversation back to the parent agent
+	DisallowTransferToParent bool
+
+	// DisallowTransferToPeers prevents the model from transferring the
+	// conversation to the other sub-agents of the parent agent
+	DisallowTransferToPeers bool
+
+	// subAgents are the agents this agent can transfer to
+	subAgents []BaseAgent
+
 	// parentAgent is the parent of this agent
 	parentAgent BaseAgent
 }
 
 // NewLlmAgent creates a new LLM-based agent
-func NewLlmAgent(name string, model models.BaseLlm) *LlmAgent {
+func NewLlmAgent(name string, model models.LLM) *LlmAgent {
 	return &LlmAgent{
-		name:           name,
-		CanonicalModel: model,
-		CanonicalTools: make([]tools.Tool, 0),
+		name:                name,
+		CanonicalModel:      model,
+		CanonicalTools:      make([]tools.Tool, 0),
+		OutputSchemaRetries: DefaultOutputSchemaRetries,
 	}
 }
 
@@ -89,38 +190,110 @@ func (a *LlmAgent) Name() string {
 	return a.name
 }
 
+// Description returns the description of the agent
+func (a *LlmAgent) Description() string {
+	return a.description
+}
+
+// SetDescription sets the description of the agent
+func (a *LlmAgent) SetDescription(description string) {
+	a.description = description
+}
+
 // Run executes the agent with the given invocation context
 func (a *LlmAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
-	// This would be implemented based on the flows
-	// For now, return an empty channel
-	eventCh := make(chan *events.Event)
-	close(eventCh)
-	return eventCh, nil
+	flow, err := a.llmFlow()
+	if err != nil {
+		return nil, err
+	}
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.BeforeAgentCallback, a.AfterAgentCallback, flow.Run)
 }
 
 // RunLive executes the agent in live mode with the given invocation context
 func (a *LlmAgent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
-	// This would be implemented based on the flows
-	// For now, return an empty channel
-	eventCh := make(chan *events.Event)
-	close(eventCh)
-	return eventCh, nil
+	flow, err := a.llmFlow()
+	if err != nil {
+		return nil, err
+	}
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.BeforeAgentCallback, a.AfterAgentCallback, flow.RunLive)
+}
+
+// llmFlow returns the flow this agent runs with
+func (a *LlmAgent) llmFlow() (LlmFlow, error) {
+	if a.CanonicalModel == nil {
+		return nil, fmt.Errorf("agent %s has no model", a.name)
+	}
+
+	if a.Flow != nil {
+		return a.Flow, nil
+	}
+
+	defaultLlmFlowFactoryMu.RLock()
+	factory := defaultLlmFlowFactory
+	defaultLlmFlowFactoryMu.RUnlock()
+
+	if factory == nil {
+		return nil, fmt.Errorf("no flow configured for agent %s: set Flow or import the flows/llm_flows package", a.name)
+	}
+	return factory(a), nil
 }
 
 // RootAgent returns the root agent in the agent tree
 func (a *LlmAgent) RootAgent() BaseAgent {
-	if a.parentAgent == nil {
-		return a
-	}
-	return a.parentAgent.RootAgent()
+	return rootAgent(a)
 }
 
-// FindAgent finds an agent by name in the agent tree
+// FindAgent finds an agent by name in this agent and its descendants
 func (a *LlmAgent) FindAgent(name string) BaseAgent {
-	if a.name == name {
-		return a
+	return findAgent(a, name)
+}
+
+// SubAgents returns the sub-agents of this agent
+func (a *LlmAgent) SubAgents() []BaseAgent {
+	return a.subAgents
+}
+
+// AddSubAgents adds sub-agents to this agent and makes it their parent
+func (a *LlmAgent) AddSubAgents(subAgents ...BaseAgent) {
+	adoptSubAgents(a, subAgents)
+	a.subAgents = append(a.subAgents, subAgents...)
+}
+
+// ParentAgent returns the parent agent of this agent
+func (a *LlmAgent) ParentAgent() BaseAgent {
+	return a.parentAgent
+}
+
+// SetParentAgent sets the parent agent
+func (a *LlmAgent) SetParentAgent(parent BaseAgent) {
+	a.parentAgent = parent
+}
+
+// TransferTargets returns the agents this agent may transfer the conversation
+// to: its sub-agents and, unless the agent disallows them, its parent and the
+// parent's other sub-agents, whatever the kind of the parent
+func (a *LlmAgent) TransferTargets() []BaseAgent {
+	targets := make([]BaseAgent, 0)
+	targets = append(targets, a.subAgents...)
+
+	parent := a.parentAgent
+	if parent == nil {
+		return targets
 	}
-	return nil
+
+	if !a.DisallowTransferToParent {
+		targets = append(targets, parent)
+	}
+
+	if !a.DisallowTransferToPeers {
+		for _, peer := range parent.SubAgents() {
+			if peer.Name() != a.Name() {
+				targets = append(targets, peer)
+			}
+		}
+	}
+
+	return targets
 }
 
 // Agent represents an AI agent that can process user inputs and generate responses.
@@ -131,7 +304,10 @@ type Agent struct {
 	description string
 	tools       []tools.Tool
 	subAgents   []*Agent
-	parentAgent *Agent
+	parentAgent BaseAgent
+	llm         models.LLM
+	runConfig   *RunConfig
+	outputKey   string
 
 	// Callbacks
 	beforeAgentCallback BeforeAgentCallback
@@ -150,6 +326,15 @@ type Config struct {
 	Tools       []tools.Tool
 	SubAgents   []*Agent
 
+	// LLM overrides the model looked up by Model name
+	LLM models.LLM
+
+	// RunConfig controls limits such as the maximum number of LLM calls
+	RunConfig *RunConfig
+
+	// OutputKey is the session state key under which the final response is stored
+	OutputKey string
+
 	// Callbacks
 	BeforeAgentCallback BeforeAgentCallback
 	AfterAgentCallback  AfterAgentCallback
@@ -200,6 +385,27 @@ func WithSubAgents(subAgents ...*Agent) Option {
 	}
 }
 
+// WithLLM sets the LLM used by the agent instead of looking it up by model name.
+func WithLLM(llm models.LLM) Option {
+	return func(c *Config) {
+		c.LLM = llm
+	}
+}
+
+// WithRunConfig sets the run configuration of the agent.
+func WithRunConfig(runConfig *RunConfig) Option {
+	return func(c *Config) {
+		c.RunConfig = runConfig
+	}
+}
+
+// WithOutputKey stores the agent's final response in session state under the given key.
+func WithOutputKey(outputKey string) Option {
+	return func(c *Config) {
+		c.OutputKey = outputKey
+	}
+}
+
 // WithBeforeAgentCallback sets a callback that runs before agent processing.
 func WithBeforeAgentCallback(callback BeforeAgentCallback) Option {
 	return func(c *Config) {
@@ -217,7 +423,8 @@ func WithAfterAgentCallback(callback AfterAgentCallback) Option {
 // NewAgent creates a new agent with the provided options.
 func NewAgent(options ...Option) *Agent {
 	config := &Config{
-		Model: "gemini-1.5-pro", // Default model
+		Model:     "gemini-1.5-pro", // Default model
+		RunConfig: NewRunConfig(),
 	}
 
 	for _, option := range options {
@@ -237,24 +444,27 @@ func NewAgent(options ...Option) *Agent {
 		description:         config.Description,
 		tools:               config.Tools,
 		subAgents:           config.SubAgents,
+		llm:                 config.LLM,
+		runConfig:           config.RunConfig,
+		outputKey:           config.OutputKey,
 		beforeAgentCallback: config.BeforeAgentCallback,
 		afterAgentCallback:  config.AfterAgentCallback,
 	}
 
 	// Set parent agent for sub-agents
-	for _, subAgent := range agent.subAgents {
-		// Validate sub-agent hierarchy
-		if err := ValidateAgentHierarchy(subAgent, agent); err != nil {
-			telemetry.Logger.Printf("Warning: %v", err)
-		}
-		subAgent.parentAgent = agent
-	}
+	adoptSubAgents(agent, agent.SubAgents())
 
 	return agent
 }
 
-// Process handles a user message and generates a response.
+// Process handles a user message and generates a response. The agent
+// callbacks run around the processing.
 func (a *Agent) Process(ctx context.Context, message string) (string, error) {
+	return processWithAgentCallbacks(ctx, a, a.beforeAgentCallback, a.afterAgentCallback, message, a.process)
+}
+
+// process generates the response to a user message
+func (a *Agent) process(ctx context.Context, message string) (string, error) {
 	// Create a span for tracking this processing
 	ctx, span := telemetry.StartSpan(ctx, "Agent.Process")
 	defer span.End()
@@ -263,51 +473,14 @@ func (a *Agent) Process(ctx context.Context, message string) (string, error) {
 	span.SetAttribute("agent.model", a.model)
 	span.SetAttribute("input.length", fmt.Sprintf("%d", len(message)))
 
-	// Run before agent callback if present
-	if a.beforeAgentCallback != nil {
-		if result, skipProcessing := a.beforeAgentCallback(ctx, message); skipProcessing {
-			return result, nil
-		}
-	}
-
 	// Get the model from the registry
-	modelRegistry := models.GetRegistry()
-	model, ok := modelRegistry.Get(a.model)
-
-	if !ok {
-		// Fall back to mock implementation if model is not available
-		span.SetAttribute("model.fallback", "true")
-		return "I'm sorry, but the requested model is not available. (This is a placeholder response.)", nil
-	}
-
-	// Prepare messages
-	msgs := []models.Message{
-		{
-			Role:    "system",
-			Content: a.instruction,
-		},
-		{
-			Role:    "user",
-			Content: message,
-		},
-	}
-
-	// If there are tools, add them to the system message
-	if len(a.tools) > 0 {
-		toolsJSON, err := json.Marshal(a.getToolDefinitions())
-		if err == nil {
-			msgs = append([]models.Message{
-				{
-					Role: "system",
-					Content: fmt.Sprintf("%s\n\nYou have access to the following tools: %s",
-						a.instruction, string(toolsJSON)),
-				},
-			}, msgs[1:]...)
-		}
+	llm, err := a.getLLM()
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+		return "", events.NewError(events.ErrorCodeModel, fmt.Errorf("model %s is not available: %w", a.model, err))
 	}
 
-	// Generate response
-	response, err := model.Generate(ctx, msgs)
+	response, err := a.runToolLoop(ctx, llm, message)
 	if err != nil {
 		span.SetAttribute("error", err.Error())
 		return "", err
@@ -315,37 +488,22 @@ func (a *Agent) Process(ctx context.Context, message string) (string, error) {
 
 	span.SetAttribute("output.length", fmt.Sprintf("%d", len(response)))
 
-	// Run after agent callback if present
-	if a.afterAgentCallback != nil {
-		response = a.afterAgentCallback(ctx, response)
-	}
-
 	return response, nil
 }
 
 // RootAgent returns the root agent in the hierarchy
 func (a *Agent) RootAgent() BaseAgent {
-	root := a
-	for root.parentAgent != nil {
-		root = root.parentAgent
-	}
-	return root
+	return rootAgent(a)
 }
 
 // FindAgent searches for an agent by name in the agent tree
 func (a *Agent) FindAgent(name string) BaseAgent {
-	// Check if this is the agent we're looking for
-	if a.name == name {
-		return a
-	}
-
-	// Search in sub-agents
-	return a.FindSubAgent(name)
+	return findAgent(a, name)
 }
 
 // FindSubAgent searches for an agent by name in sub-agents
 func (a *Agent) FindSubAgent(name string) BaseAgent {
-	for _, subAgent := range a.subAgents {
+	for _, subAgent := range a.SubAgents() {
 		if found := subAgent.FindAgent(name); found != nil {
 			return found
 		}
@@ -353,22 +511,121 @@ func (a *Agent) FindSubAgent(name string) BaseAgent {
 	return nil
 }
 
-// getToolDefinitions returns a JSON-serializable representation of the tools.
-func (a *Agent) getToolDefinitions() []map[string]interface{} {
-	var defs []map[string]interface{}
+// getLLM returns the LLM configured for the agent or looks it up by model name
+func (a *Agent) getLLM() (models.LLM, error) {
+	if a.llm != nil {
+		return a.llm, nil
+	}
+	return models.GetUnifiedModelFactory().GetLLM(a.model)
+}
+
+// runToolLoop calls the model, executes the function calls it returns and
+// feeds the results back until the model answers without calling a tool.
+func (a *Agent) runToolLoop(ctx context.Context, llm models.LLM, message string) (string, error) {
+	request := &models.LlmRequest{
+		SystemInstructions: a.instruction,
+	}
+	request.AppendContent(models.RoleUser, &models.Part{Text: message, Role: models.RoleUser})
 
+	toolContext := &tools.ToolContext{
+		EventActions: events.NewEventActions(),
+	}
 	for _, tool := range a.tools {
-		schema := tool.Schema()
-		def := map[string]interface{}{
-			"name":        tool.Name(),
-			"description": tool.Description(),
-			"input":       schema.Input,
-			"output":      schema.Output,
+		if err := tools.AddToLlmRequest(ctx, toolContext, tool, request); err != nil {
+			return "", fmt.Errorf("failed to add tool %s: %w", tool.Name(), err)
 		}
-		defs = append(defs, def)
 	}
 
-	return defs
+	maxLlmCalls := 0
+	if a.runConfig != nil {
+		maxLlmCalls = a.runConfig.MaxLlmCalls
+	}
+
+	for calls := 0; ; calls++ {
+		if maxLlmCalls > 0 && calls >= maxLlmCalls {
+			return "", events.NewError(events.ErrorCodeBudgetExceeded,
+				fmt.Errorf("maximum number of LLM calls (%d) exceeded", maxLlmCalls))
+		}
+
+		response, err := llm.GenerateContent(ctx, request)
+		if err != nil {
+			return "", events.NewError(events.ErrorCodeModel, fmt.Errorf("model call failed: %w", err))
+		}
+		if response.ErrorCode != "" {
+			return "", events.NewError(events.ErrorCodeModel,
+				fmt.Errorf("model error %s: %s", response.ErrorCode, response.ErrorMessage))
+		}
+		if response.Content == nil {
+			return "", nil
+		}
+
+		functionCalls := make([]*models.FunctionCall, 0)
+		for _, part := range response.Content.Parts {
+			if part.FunctionCall != nil {
+				functionCalls = append(functionCalls, part.FunctionCall)
+			}
+		}
+
+		if len(functionCalls) == 0 {
+			return textFromContent(response.Content), nil
+		}
+
+		// Keep the model turn in the conversation, then answer each call
+		for _, part := range response.Content.Parts {
+			part.Role = models.RoleAssistant
+		}
+		request.AppendContent(models.RoleAssistant, response.Content.Parts...)
+
+		for _, functionCall := range functionCalls {
+			if functionCall.ID == "" {
+				functionCall.ID = uuid.New().String()
+			}
+			request.AppendContent(models.RoleUser, &models.Part{
+				Role:             models.RoleUser,
+				FunctionResponse: a.executeFunctionCall(ctx, toolContext, functionCall),
+			})
+		}
+	}
+}
+
+// executeFunctionCall runs the tool requested by the model. Failures are
+// reported back to the model as the function response.
+func (a *Agent) executeFunctionCall(ctx context.Context, toolContext *tools.ToolContext, functionCall *models.FunctionCall) *models.FunctionResponse {
+	functionResponse := &models.FunctionResponse{
+		Name: functionCall.Name,
+		ID:   functionCall.ID,
+	}
+
+	tool := tools.FindTool(a.tools, functionCall.Name)
+	if tool == nil {
+		functionResponse.Content = fmt.Sprintf("Error: Tool %s not found", functionCall.Name)
+		return functionResponse
+	}
+
+	ctx, span := telemetry.StartSpan(ctx, "Agent.ExecuteTool")
+	defer span.End()
+	span.SetAttribute("tool.name", functionCall.Name)
+
+	result, err := tools.AsFunctionCallExecutor(tool).ExecuteFunctionCall(ctx, toolContext, functionCall)
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+		functionResponse.Content = fmt.Sprintf("Error executing tool: %v", err)
+		return functionResponse
+	}
+
+	functionResponse.Content = result
+	return functionResponse
+}
+
+// textFromContent concatenates the text of all non-thought parts
+func textFromContent(content *models.Content) string {
+	var text strings.Builder
+	for _, part := range content.Parts {
+		if part.Text != "" && !part.Thought {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String()
 }
 
 // Name returns the name of the agent.
@@ -391,23 +648,43 @@ func (a *Agent) Description() string {
 	return a.description
 }
 
+// OutputKey returns the session state key under which the final response is stored.
+func (a *Agent) OutputKey() string {
+	return a.outputKey
+}
+
 // Tools returns the tools available to the agent.
 func (a *Agent) Tools() []tools.Tool {
 	return a.tools
 }
 
 // SubAgents returns the sub-agents of this agent.
-func (a *Agent) SubAgents() []*Agent {
-	return a.subAgents
+func (a *Agent) SubAgents() []BaseAgent {
+	subAgents := make([]BaseAgent, 0, len(a.subAgents))
+	for _, subAgent := range a.subAgents {
+		subAgents = append(subAgents, subAgent)
+	}
+	return subAgents
 }
 
 // ParentAgent returns the parent agent of this agent.
-func (a *Agent) ParentAgent() *Agent {
+func (a *Agent) ParentAgent() BaseAgent {
 	return a.parentAgent
 }
 
-// Run executes the agent with the given invocation context
+// SetParentAgent sets the parent agent of this agent.
+func (a *Agent) SetParentAgent(parent BaseAgent) {
+	a.parentAgent = parent
+}
+
+// Run executes the agent with the given invocation context. The agent
+// callbacks run around the agent.
 func (a *Agent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.beforeAgentCallback, a.afterAgentCallback, a.run)
+}
+
+// run responds to the message of the invocation event
+func (a *Agent) run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
 	eventCh := make(chan *events.Event)
 
 	// Create a span for tracking this processing
@@ -431,51 +708,65 @@ func (a *Agent) Run(ctx context.Context, invocationContext *InvocationContext) (
 			}
 
 			// Process the user message
-			response, err := a.Process(ctx, userMsg)
-			if err != nil {
-				// Send error event
-				eventCh <- &events.Event{
-					InvocationID: invocationContext.InvocationID,
-					Author:       a.name,
-					Content: &events.Content{
-						Parts: []*models.Part{
-							{Text: fmt.Sprintf("Error processing message: %v", err)},
-						},
-					},
-				}
-				return
-			}
+			response, err := a.process(ctx, userMsg)
 
 			// Send response event
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
+			event := events.NewEvent()
+			event.InvocationID = invocationContext.InvocationID
+			event.Author = a.name
+			event.Branch = invocationContext.Branch
+			if err != nil {
+				event.ErrorCode = processErrorCode(ctx, err)
+				event.ErrorMessage = fmt.Sprintf("Error processing message: %v", err)
+			} else {
+				event.Content = &events.Content{
 					Parts: []*models.Part{
-						{Text: response},
+						{Text: response, Role: "assistant"},
 					},
-				},
+				}
+				if a.outputKey != "" {
+					event.Actions.StateDelta[a.outputKey] = response
+				}
 			}
+			invocationContext.AppendEvent(event)
+			eventCh <- event
 		}
 	}()
 
 	return eventCh, nil
 }
 
+// processErrorCode returns the error code of an event reporting that process
+// failed: the code of the context if it stopped the agent, else the code of
+// the error, see events.ErrorCodeOf, or events.ErrorCodeFlow
+func processErrorCode(ctx context.Context, err error) string {
+	if code := events.ContextErrorCode(context.Cause(ctx)); code != "" {
+		return code
+	}
+	if code := events.ErrorCodeOf(err); code != "" {
+		return code
+	}
+	return events.ErrorCodeFlow
+}
+
 // RunLive executes the agent in live mode with the given invocation context
 func (a *Agent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
 	// For now, implement live mode same as regular mode
 	return a.Run(ctx, invocationContext)
 }
 
+// ExportHook is called with each agent passed to Export
+type ExportHook func(agent BaseAgent)
+
 // Agent registry to keep track of exported agents
 type agentRegistry struct {
-	agents map[string]*Agent
-	mu     sync.RWMutex
+	agents     map[string]BaseAgent
+	exportHook ExportHook
+	mu         sync.RWMutex
 }
 
 var (
-	registry     = &agentRegistry{agents: make(map[string]*Agent)}
+	registry     = &agentRegistry{agents: make(map[string]BaseAgent)}
 	registryOnce sync.Once
 )
 
@@ -483,35 +774,76 @@ var (
 func getRegistry() *agentRegistry {
 	registryOnce.Do(func() {
 		registry = &agentRegistry{
-			agents: make(map[string]*Agent),
+			agents: make(map[string]BaseAgent),
 		}
 	})
 	return registry
 }
 
 // Register registers an agent with the registry.
-func (r *agentRegistry) Register(name string, agent *Agent) {
+func (r *agentRegistry) Register(name string, agent BaseAgent) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.agents[name] = agent
 }
 
 // Get returns an agent from the registry by name.
-func (r *agentRegistry) Get(name string) (*Agent, bool) {
+func (r *agentRegistry) Get(name string) (BaseAgent, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	agent, ok := r.agents[name]
 	return agent, ok
 }
 
-// Export makes an agent available to the ADK CLI.
-func Export(agent *Agent) {
+// List returns the registered agents sorted by name.
+func (r *agentRegistry) List() []BaseAgent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]BaseAgent, 0, len(names))
+	for _, name := range names {
+		result = append(result, r.agents[name])
+	}
+	return result
+}
+
+// Export makes an agent, which may be the root of an agent tree, available
+// to the ADK CLI.
+func Export(agent BaseAgent) {
 	registry := getRegistry()
 	registry.Register(agent.Name(), agent)
+
+	registry.mu.RLock()
+	hook := registry.exportHook
+	registry.mu.RUnlock()
+
+	if hook != nil {
+		hook(agent)
+	}
+}
+
+// SetExportHook sets a function called with each agent exported from now on.
+// The ADK CLI uses it to take over a program as soon as it exports its agent.
+func SetExportHook(hook ExportHook) {
+	registry := getRegistry()
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.exportHook = hook
+}
+
+// ExportedAgents returns every agent exported with Export, sorted by name.
+func ExportedAgents() []BaseAgent {
+	return getRegistry().List()
 }
 
 // GetExportedAgent retrieves an agent that was exported with Export.
-func GetExportedAgent(name string) (*Agent, bool) {
+func GetExportedAgent(name string) (BaseAgent, bool) {
 	registry := getRegistry()
 	return registry.Get(name)
 }
@@ -0,0 +1,249 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package agent_config loads agents from declarative YAML definitions.
+//
+// A definition describes a single agent and, recursively, its sub-agents:
+//
+//	name: coordinator
+//	model: gemini-2.0-flash
+//	description: Routes questions to the right specialist.
+//	instruction: You help users with {topic}.
+//	tools:
+//	  - name: google_search
+//	  - openapi:
+//	      spec_path: petstore.yaml
+//	  - mcp:
+//	      command: npx
+//	      args: ["-y", "@modelcontextprotocol/server-filesystem"]
+//	sub_agents:
+//	  - config_path: billing.yaml
+//	  - agent_class: SequentialAgent
+//	    name: pipeline
+//	    sub_agents:
+//	      - name: writer
+//	        instruction: Write a draft.
+//	      - name: reviewer
+//	        instruction: Review the draft.
+package agent_config
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"gopkg.in/yaml.v3"
+)
+
+// Agent classes that can be used in the agent_class field
+const (
+	LlmAgentClass        = "LlmAgent"
+	SequentialAgentClass = "SequentialAgent"
+	ParallelAgentClass   = "ParallelAgent"
+	LoopAgentClass       = "LoopAgent"
+)
+
+// AgentConfig is the YAML definition of an agent
+type AgentConfig struct {
+	// AgentClass selects the kind of agent; defaults to LlmAgent
+	AgentClass string `yaml:"agent_class,omitempty"`
+
+	// Name is the name of the agent and must be unique in the agent tree
+	Name string `yaml:"name"`
+
+	// Description tells other agents what this agent does
+	Description string `yaml:"description,omitempty"`
+
+	// Model is the model used by an LlmAgent. If empty, the model of the
+	// closest LlmAgent ancestor is used.
+	Model string `yaml:"model,omitempty"`
+
+	// Instruction is the system instruction of an LlmAgent. It may contain
+	// state placeholders such as {key}.
+	Instruction string `yaml:"instruction,omitempty"`
+
+	// OutputKey is the session state key under which the final response of
+	// an LlmAgent is stored
+	OutputKey string `yaml:"output_key,omitempty"`
+
+	// DisallowTransferToParent prevents an LlmAgent from transferring back to its parent
+	DisallowTransferToParent bool `yaml:"disallow_transfer_to_parent,omitempty"`
+
+	// DisallowTransferToPeers prevents an LlmAgent from transferring to its peers
+	DisallowTransferToPeers bool `yaml:"disallow_transfer_to_peers,omitempty"`
+
+	// Tools are the tools available to an LlmAgent
+	Tools []ToolConfig `yaml:"tools,omitempty"`
+
+	// SubAgents are the children of the agent
+	SubAgents []SubAgentConfig `yaml:"sub_agents,omitempty"`
+
+	// MaxIterations bounds the iterations of a LoopAgent
+	MaxIterations int `yaml:"max_iterations,omitempty"`
+
+	// MaxConcurrency limits how many branches of a ParallelAgent run at once
+	MaxConcurrency int `yaml:"max_concurrency,omitempty"`
+
+	// FailurePolicy is fail_fast or collect_all for a ParallelAgent
+	FailurePolicy string `yaml:"failure_policy,omitempty"`
+
+	// BranchTimeout bounds each branch of a ParallelAgent, e.g. "30s"
+	BranchTimeout time.Duration `yaml:"branch_timeout,omitempty"`
+}
+
+// SubAgentConfig is a sub-agent defined inline or in a separate file
+type SubAgentConfig struct {
+	AgentConfig `yaml:",inline"`
+
+	// ConfigPath is the path of a file holding the sub-agent definition,
+	// relative to the file that references it
+	ConfigPath string `yaml:"config_path,omitempty"`
+}
+
+// ToolConfig references a tool or toolset. Exactly one field must be set.
+type ToolConfig struct {
+	// Name is the name of a built-in or registered tool, see RegisterTool
+	Name string `yaml:"name,omitempty"`
+
+	// OpenAPI generates tools from an OpenAPI specification
+	OpenAPI *OpenAPIToolsetConfig `yaml:"openapi,omitempty"`
+
+	// MCP loads the tools of an MCP server
+	MCP *MCPToolsetConfig `yaml:"mcp,omitempty"`
+}
+
+// OpenAPIToolsetConfig defines an OpenAPI toolset
+type OpenAPIToolsetConfig struct {
+	// SpecPath is the path of a JSON or YAML specification, relative to the
+	// file that references it
+	SpecPath string `yaml:"spec_path,omitempty"`
+
+	// Spec is an inline JSON or YAML specification
+	Spec string `yaml:"spec,omitempty"`
+}
+
+// MCPToolsetConfig defines the connection to an MCP server. Set Command to
+// start a local server over stdio, or URL to connect to a server over SSE.
+type MCPToolsetConfig struct {
+	Command string            `yaml:"command,omitempty"`
+	Args    []string          `yaml:"args,omitempty"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Parse decodes and validates an agent definition. Unknown fields are rejected.
+func Parse(data []byte) (*AgentConfig, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var config AgentConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid agent definition: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks the fields of the definition. Sub-agents stored in
+// separate files are validated when they are loaded.
+func (c *AgentConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("agent name is required")
+	}
+
+	class := c.class()
+	switch class {
+	case LlmAgentClass:
+	case SequentialAgentClass, ParallelAgentClass, LoopAgentClass:
+		if c.Model != "" || c.Instruction != "" || c.OutputKey != "" || len(c.Tools) > 0 {
+			return fmt.Errorf("agent %s: model, instruction, output_key and tools are only supported by %s", c.Name, LlmAgentClass)
+		}
+		if len(c.SubAgents) == 0 {
+			return fmt.Errorf("agent %s: %s requires sub_agents", c.Name, class)
+		}
+	default:
+		return fmt.Errorf("agent %s: unknown agent_class %q", c.Name, c.AgentClass)
+	}
+
+	if class != LoopAgentClass && c.MaxIterations != 0 {
+		return fmt.Errorf("agent %s: max_iterations is only supported by %s", c.Name, LoopAgentClass)
+	}
+
+	if class != ParallelAgentClass && (c.MaxConcurrency != 0 || c.FailurePolicy != "" || c.BranchTimeout != 0) {
+		return fmt.Errorf("agent %s: max_concurrency, failure_policy and branch_timeout are only supported by %s", c.Name, ParallelAgentClass)
+	}
+
+	switch agents.FailurePolicy(c.FailurePolicy) {
+	case "", agents.FailFast, agents.CollectAll:
+	default:
+		return fmt.Errorf("agent %s: unknown failure_policy %q", c.Name, c.FailurePolicy)
+	}
+
+	for i, tool := range c.Tools {
+		if err := tool.validate(); err != nil {
+			return fmt.Errorf("agent %s: tool %d: %w", c.Name, i, err)
+		}
+	}
+
+	for i, subAgent := range c.SubAgents {
+		if subAgent.ConfigPath != "" {
+			if subAgent.Name != "" || subAgent.AgentClass != "" {
+				return fmt.Errorf("agent %s: sub-agent %d sets config_path together with an inline definition", c.Name, i)
+			}
+			continue
+		}
+		if err := subAgent.AgentConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// class returns the agent class, defaulting to LlmAgent
+func (c *AgentConfig) class() string {
+	if c.AgentClass == "" {
+		return LlmAgentClass
+	}
+	return c.AgentClass
+}
+
+// validate checks that exactly one kind of tool is referenced
+func (t ToolConfig) validate() error {
+	set := 0
+	if t.Name != "" {
+		set++
+	}
+	if t.OpenAPI != nil {
+		set++
+		if (t.OpenAPI.SpecPath == "") == (t.OpenAPI.Spec == "") {
+			return fmt.Errorf("openapi toolset requires exactly one of spec_path and spec")
+		}
+	}
+	if t.MCP != nil {
+		set++
+		if (t.MCP.Command == "") == (t.MCP.URL == "") {
+			return fmt.Errorf("mcp toolset requires exactly one of command and url")
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("exactly one of name, openapi and mcp must be set")
+	}
+	return nil
+}
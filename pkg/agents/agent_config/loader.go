@@ -0,0 +1,343 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent_config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/auth"
+	// Registers the default flow, which loaded LlmAgents need to run
+	_ "github.com/nvcnvn/adk-golang/pkg/flows/llm_flows"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/tools/mcp_tool"
+	"github.com/nvcnvn/adk-golang/pkg/tools/openapi_tool/openapi_spec_parser"
+	"gopkg.in/yaml.v3"
+)
+
+// RootAgentFile is the file name looked up when a directory is loaded
+const RootAgentFile = "root_agent.yaml"
+
+var (
+	registeredTools   = map[string]tools.Tool{}
+	registeredToolsMu sync.RWMutex
+)
+
+// RegisterTool makes a tool available to agent definitions under its name.
+// Built-in tools are registered by default.
+func RegisterTool(tool tools.Tool) {
+	registeredToolsMu.Lock()
+	defer registeredToolsMu.Unlock()
+	registeredTools[tool.Name()] = tool
+}
+
+// lookupTool returns the tool registered under the given name
+func lookupTool(name string) (tools.Tool, bool) {
+	registeredToolsMu.RLock()
+	defer registeredToolsMu.RUnlock()
+	tool, ok := registeredTools[name]
+	return tool, ok
+}
+
+func init() {
+	RegisterTool(tools.GoogleSearch)
+	RegisterTool(tools.ExitLoopTool)
+	RegisterTool(tools.TransferToAgentTool)
+	RegisterTool(tools.BuiltInCodeExecution)
+}
+
+// ModelResolver creates the LLM for a model name
+type ModelResolver func(name string) (models.LLM, error)
+
+// Loader builds agents from definitions
+type Loader struct {
+	ctx              context.Context
+	modelResolver    ModelResolver
+	mcpClientFactory mcp_tool.ClientFactory
+}
+
+// Option configures a Loader
+type Option func(*Loader)
+
+// WithContext sets the context used to connect to MCP servers
+func WithContext(ctx context.Context) Option {
+	return func(l *Loader) {
+		l.ctx = ctx
+	}
+}
+
+// WithModelResolver sets how model names are turned into LLMs. The default
+// uses the unified model factory.
+func WithModelResolver(resolver ModelResolver) Option {
+	return func(l *Loader) {
+		l.modelResolver = resolver
+	}
+}
+
+// WithMCPClientFactory sets the client factory used to connect to MCP servers
+func WithMCPClientFactory(factory mcp_tool.ClientFactory) Option {
+	return func(l *Loader) {
+		l.mcpClientFactory = factory
+	}
+}
+
+// NewLoader creates a new Loader
+func NewLoader(options ...Option) *Loader {
+	loader := &Loader{
+		ctx:              context.Background(),
+		modelResolver:    models.GetUnifiedModelFactory().GetLLM,
+		mcpClientFactory: mcp_tool.DefaultClientFactory{},
+	}
+
+	for _, option := range options {
+		option(loader)
+	}
+	return loader
+}
+
+// IsAgentConfigFile reports whether a path names a YAML agent definition
+func IsAgentConfigFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// LoadFile loads an agent from a YAML file, or from the root_agent.yaml file
+// of a directory, using a Loader with the given options
+func LoadFile(path string, options ...Option) (agents.BaseAgent, error) {
+	return NewLoader(options...).LoadFile(path)
+}
+
+// LoadFile loads an agent from a YAML file, or from the root_agent.yaml file
+// of a directory
+func (l *Loader) LoadFile(path string) (agents.BaseAgent, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, RootAgentFile)
+	}
+
+	config, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.buildRoot(config, filepath.Dir(path))
+}
+
+// Build creates an agent from a parsed definition. Relative paths in the
+// definition are resolved against baseDir.
+func (l *Loader) Build(config *AgentConfig, baseDir string) (agents.BaseAgent, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return l.buildRoot(config, baseDir)
+}
+
+// buildRoot creates the root agent of a definition and validates its tree
+func (l *Loader) buildRoot(config *AgentConfig, baseDir string) (agents.BaseAgent, error) {
+	agent, err := l.build(config, baseDir, "", map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if err := agents.ValidateTree(agent); err != nil {
+		return nil, fmt.Errorf("invalid agent tree: %w", err)
+	}
+	return agent, nil
+}
+
+// readConfig reads and parses a definition file
+func readConfig(path string) (*AgentConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent definition: %w", err)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// build creates the agent for a definition. parentModel is the model of the
+// closest LlmAgent ancestor and names tracks the agent names already in use.
+func (l *Loader) build(config *AgentConfig, baseDir, parentModel string, names map[string]bool) (agents.BaseAgent, error) {
+	if names[config.Name] {
+		return nil, fmt.Errorf("duplicate agent name %s", config.Name)
+	}
+	names[config.Name] = true
+
+	model := parentModel
+	if config.Model != "" {
+		model = config.Model
+	}
+
+	subAgents := make([]agents.BaseAgent, 0, len(config.SubAgents))
+	for _, subConfig := range config.SubAgents {
+		subAgent, err := l.buildSubAgent(subConfig, baseDir, model, names)
+		if err != nil {
+			return nil, err
+		}
+		subAgents = append(subAgents, subAgent)
+	}
+
+	switch config.class() {
+	case SequentialAgentClass:
+		return agents.NewSequentialAgent(agents.SequentialAgentConfig{
+			Name:        config.Name,
+			Description: config.Description,
+			SubAgents:   subAgents,
+		}), nil
+	case ParallelAgentClass:
+		return agents.NewParallelAgent(agents.ParallelAgentConfig{
+			Name:           config.Name,
+			Description:    config.Description,
+			SubAgents:      subAgents,
+			MaxConcurrency: config.MaxConcurrency,
+			FailurePolicy:  agents.FailurePolicy(config.FailurePolicy),
+			BranchTimeout:  config.BranchTimeout,
+		}), nil
+	case LoopAgentClass:
+		return agents.NewLoopAgent(agents.LoopAgentConfig{
+			Name:          config.Name,
+			Description:   config.Description,
+			SubAgents:     subAgents,
+			MaxIterations: config.MaxIterations,
+		}), nil
+	}
+
+	return l.buildLlmAgent(config, baseDir, model, subAgents)
+}
+
+// buildSubAgent creates a sub-agent defined inline or in a separate file
+func (l *Loader) buildSubAgent(subConfig SubAgentConfig, baseDir, parentModel string, names map[string]bool) (agents.BaseAgent, error) {
+	if subConfig.ConfigPath == "" {
+		config := subConfig.AgentConfig
+		return l.build(&config, baseDir, parentModel, names)
+	}
+
+	path := resolvePath(baseDir, subConfig.ConfigPath)
+	config, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.build(config, filepath.Dir(path), parentModel, names)
+}
+
+// buildLlmAgent creates an LlmAgent with its model and tools
+func (l *Loader) buildLlmAgent(config *AgentConfig, baseDir, model string, subAgents []agents.BaseAgent) (agents.BaseAgent, error) {
+	if model == "" {
+		return nil, fmt.Errorf("agent %s: model is required when no ancestor defines one", config.Name)
+	}
+
+	llm, err := l.modelResolver(model)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: failed to create model %s: %w", config.Name, model, err)
+	}
+
+	agent := agents.NewLlmAgent(config.Name, llm)
+	agent.SetDescription(config.Description)
+	agent.SystemInstructions = config.Instruction
+	agent.OutputKey = config.OutputKey
+	agent.DisallowTransferToParent = config.DisallowTransferToParent
+	agent.DisallowTransferToPeers = config.DisallowTransferToPeers
+
+	for i, toolConfig := range config.Tools {
+		agentTools, err := l.buildTools(toolConfig, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: tool %d: %w", config.Name, i, err)
+		}
+		agent.CanonicalTools = append(agent.CanonicalTools, agentTools...)
+	}
+
+	agent.AddSubAgents(subAgents...)
+	return agent, nil
+}
+
+// buildTools resolves a tool reference into one or more tools
+func (l *Loader) buildTools(config ToolConfig, baseDir string) ([]tools.Tool, error) {
+	switch {
+	case config.OpenAPI != nil:
+		return l.buildOpenAPITools(config.OpenAPI, baseDir)
+	case config.MCP != nil:
+		return l.buildMCPTools(config.MCP)
+	}
+
+	tool, ok := lookupTool(config.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %s", config.Name)
+	}
+	return []tools.Tool{tool}, nil
+}
+
+// buildOpenAPITools generates the tools of an OpenAPI specification
+func (l *Loader) buildOpenAPITools(config *OpenAPIToolsetConfig, baseDir string) ([]tools.Tool, error) {
+	data := []byte(config.Spec)
+	if config.SpecPath != "" {
+		var err error
+		data, err = os.ReadFile(resolvePath(baseDir, config.SpecPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+		}
+	}
+
+	// YAML is a superset of JSON, so both spec formats decode here
+	var spec map[string]any
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+
+	toolset := openapi_spec_parser.NewOpenAPIToolset(spec, nil, auth.AuthCredential{})
+	return toolset.GetTools(), nil
+}
+
+// buildMCPTools connects to an MCP server and loads its tools. The
+// connection stays open for the lifetime of the process.
+func (l *Loader) buildMCPTools(config *MCPToolsetConfig) ([]tools.Tool, error) {
+	var params mcp_tool.ConnectionParams
+	if config.Command != "" {
+		params = mcp_tool.StdioServerParams{Command: config.Command, Args: config.Args}
+	} else {
+		params = mcp_tool.SseServerParams{URL: config.URL, Headers: config.Headers}
+	}
+
+	toolset := mcp_tool.NewMcpToolset(params).WithClientFactory(l.mcpClientFactory)
+	if err := toolset.Initialize(l.ctx); err != nil {
+		return nil, err
+	}
+
+	mcpTools, err := toolset.LoadTools(l.ctx)
+	if err != nil {
+		_ = toolset.Close()
+		return nil, err
+	}
+
+	result := make([]tools.Tool, 0, len(mcpTools))
+	for i := range mcpTools {
+		result = append(result, &mcpTools[i])
+	}
+	return result, nil
+}
+
+// resolvePath resolves a path relative to the directory of a definition file
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent_config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// namedLlm is a model that only knows its name
+type namedLlm struct {
+	name string
+}
+
+func (m *namedLlm) SupportedModels() []string { return []string{m.name} }
+
+func (m *namedLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	return nil, fmt.Errorf("model %s cannot answer", m.name)
+}
+
+func (m *namedLlm) GenerateContentStream(ctx context.Context, request *models.LlmRequest) (<-chan *models.LlmResponse, error) {
+	return nil, fmt.Errorf("model %s cannot answer", m.name)
+}
+
+func (m *namedLlm) Connect(ctx context.Context, request *models.LlmRequest) (models.LlmConnection, error) {
+	return nil, fmt.Errorf("model %s cannot answer", m.name)
+}
+
+// resolveNamedLlm resolves every model name to a namedLlm
+func resolveNamedLlm(name string) (models.LLM, error) {
+	return &namedLlm{name: name}, nil
+}
+
+// writeFiles writes files, given by their path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+}
+
+// llmModel returns the name of the model of an LlmAgent in the tree
+func llmModel(t *testing.T, root agents.BaseAgent, name string) string {
+	t.Helper()
+
+	agent, ok := root.FindAgent(name).(*agents.LlmAgent)
+	if !ok {
+		t.Fatalf("agent %s is not an LlmAgent in the tree", name)
+	}
+	return agent.CanonicalModel.(*namedLlm).name
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+		check   func(t *testing.T, root agents.BaseAgent)
+	}{
+		{
+			name: "valid tree",
+			files: map[string]string{
+				RootAgentFile: `
+name: coordinator
+model: gemini-2.0-flash
+instruction: Route the question.
+tools:
+  - name: transfer_to_agent
+sub_agents:
+  - config_path: specialists/billing.yaml
+  - agent_class: SequentialAgent
+    name: pipeline
+    sub_agents:
+      - name: writer
+        instruction: Write a draft.
+      - name: reviewer
+        model: gemini-2.5-pro
+        instruction: Review the draft.
+`,
+				"specialists/billing.yaml": `
+name: billing
+instruction: Answer billing questions.
+sub_agents:
+  - config_path: refunds.yaml
+`,
+				// Resolved relative to specialists/billing.yaml
+				"specialists/refunds.yaml": `
+name: refunds
+output_key: refund
+`,
+			},
+			check: func(t *testing.T, root agents.BaseAgent) {
+				if root.Name() != "coordinator" {
+					t.Errorf("root is %s, want coordinator", root.Name())
+				}
+				if _, ok := root.FindAgent("pipeline").(*agents.SequentialAgent); !ok {
+					t.Errorf("pipeline is not a SequentialAgent")
+				}
+				if refunds, ok := root.FindAgent("refunds").(*agents.LlmAgent); !ok || refunds.OutputKey != "refund" {
+					t.Errorf("refunds agent = %+v, want an LlmAgent with output key refund", root.FindAgent("refunds"))
+				}
+				if model := llmModel(t, root, "writer"); model != "gemini-2.0-flash" {
+					t.Errorf("writer model = %s, want the inherited gemini-2.0-flash", model)
+				}
+				if model := llmModel(t, root, "reviewer"); model != "gemini-2.5-pro" {
+					t.Errorf("reviewer model = %s, want gemini-2.5-pro", model)
+				}
+				if parent := root.FindAgent("refunds").ParentAgent(); parent == nil || parent.Name() != "billing" {
+					t.Errorf("refunds parent = %v, want billing", parent)
+				}
+			},
+		},
+		{
+			name: "unknown field",
+			files: map[string]string{RootAgentFile: `
+name: assistant
+model: gemini-2.0-flash
+temperature: 0.2
+`},
+			wantErr: "field temperature not found",
+		},
+		{
+			name: "config_path missing next to the referencing file",
+			files: map[string]string{
+				RootAgentFile: `
+name: coordinator
+model: gemini-2.0-flash
+sub_agents:
+  - config_path: specialists/billing.yaml
+`,
+				"specialists/billing.yaml": `
+name: billing
+sub_agents:
+  - config_path: specialists/refunds.yaml
+`,
+				// Only reachable relative to the root file
+				"specialists/refunds.yaml": `name: refunds`,
+			},
+			wantErr: filepath.Join("specialists", "specialists", "refunds.yaml"),
+		},
+		{
+			name: "bad agent_class",
+			files: map[string]string{RootAgentFile: `
+agent_class: RobotAgent
+name: robot
+`},
+			wantErr: `unknown agent_class "RobotAgent"`,
+		},
+		{
+			name: "duplicate name",
+			files: map[string]string{RootAgentFile: `
+name: assistant
+model: gemini-2.0-flash
+sub_agents:
+  - name: helper
+  - name: helper
+`},
+			wantErr: "duplicate agent name helper",
+		},
+		{
+			name: "config_path cycle",
+			files: map[string]string{
+				RootAgentFile: `
+name: coordinator
+model: gemini-2.0-flash
+sub_agents:
+  - config_path: helper.yaml
+`,
+				"helper.yaml": `
+name: helper
+sub_agents:
+  - config_path: root_agent.yaml
+`,
+			},
+			wantErr: "duplicate agent name coordinator",
+		},
+		{
+			name: "tree rejected by ValidateTree",
+			files: map[string]string{RootAgentFile: `
+name: assistant
+model: gemini-2.0-flash
+tools:
+  - name: transfer_to_agent
+`},
+			wantErr: "invalid agent tree",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, test.files)
+
+			root, err := LoadFile(dir, WithModelResolver(resolveNamedLlm))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("LoadFile error = %v, want it to mention %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile: %v", err)
+			}
+			test.check(t, root)
+		})
+	}
+}
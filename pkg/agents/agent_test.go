@@ -0,0 +1,175 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// answerLlm is a model that returns the same response, or error, to every
+// request
+type answerLlm struct {
+	response *models.LlmResponse
+	err      error
+}
+
+func (m *answerLlm) SupportedModels() []string { return nil }
+
+func (m *answerLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	return m.response, m.err
+}
+
+func (m *answerLlm) GenerateContentStream(ctx context.Context, request *models.LlmRequest) (<-chan *models.LlmResponse, error) {
+	return nil, errors.New("streaming is not supported")
+}
+
+func (m *answerLlm) Connect(ctx context.Context, request *models.LlmRequest) (models.LlmConnection, error) {
+	return nil, errors.New("live connections are not supported")
+}
+
+func TestAgentReportsProcessingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		llm      *answerLlm
+		model    string
+		wantCode string
+		wantText string
+	}{
+		{
+			name:     "answer",
+			llm:      &answerLlm{response: &models.LlmResponse{Content: &models.Content{Parts: []*models.Part{{Text: "Hello"}}}}},
+			wantText: "Hello",
+		},
+		{
+			name:     "model call fails",
+			llm:      &answerLlm{err: errors.New("connection refused")},
+			wantCode: events.ErrorCodeModel,
+		},
+		{
+			name:     "model reports an error",
+			llm:      &answerLlm{response: &models.LlmResponse{ErrorCode: "SAFETY", ErrorMessage: "blocked"}},
+			wantCode: events.ErrorCodeModel,
+		},
+		{
+			name:     "model not available",
+			model:    "no-such-model",
+			wantCode: events.ErrorCodeModel,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := []Option{WithName("agent"), WithModel(test.model), WithOutputKey("answer")}
+			if test.llm != nil {
+				options = append(options, WithLLM(test.llm))
+			}
+			agent := NewAgent(options...)
+			invocationContext := NewInvocationContext("invocation", agent, nil)
+			invocationContext.InvocationEvent = events.NewEvent()
+			invocationContext.InvocationEvent.Content = &models.Content{Role: models.RoleUser, Parts: []*models.Part{{Text: "Hi"}}}
+
+			eventCh, err := agent.Run(context.Background(), invocationContext)
+			result := drain(t, eventCh, err)
+			if len(result) != 1 {
+				t.Fatalf("got %d events, want 1", len(result))
+			}
+			event := result[0]
+
+			if event.ErrorCode != test.wantCode {
+				t.Errorf("error code = %q, want %q", event.ErrorCode, test.wantCode)
+			}
+			if test.wantCode != "" {
+				if event.ErrorMessage == "" || event.Content != nil {
+					t.Errorf("error event has message %q and content %+v, want a message and no content", event.ErrorMessage, event.Content)
+				}
+				if _, ok := event.Actions.StateDelta["answer"]; ok {
+					t.Errorf("the error was stored under the output key")
+				}
+				return
+			}
+			if event.Content == nil || event.Content.GetText() != test.wantText {
+				t.Errorf("content = %+v, want %q", event.Content, test.wantText)
+			}
+		})
+	}
+}
+
+func TestLlmAgentTransferTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		parent      func(subAgents ...BaseAgent) BaseAgent
+		configure   func(writer *LlmAgent)
+		wantTargets []string
+	}{
+		{
+			name:        "no parent",
+			wantTargets: []string{"editor"},
+		},
+		{
+			name: "llm parent",
+			parent: func(subAgents ...BaseAgent) BaseAgent {
+				parent := NewLlmAgent("coordinator", nil)
+				parent.AddSubAgents(subAgents...)
+				return parent
+			},
+			wantTargets: []string{"editor", "coordinator", "reviewer"},
+		},
+		{
+			name: "sequential parent",
+			parent: func(subAgents ...BaseAgent) BaseAgent {
+				return NewSequentialAgent(SequentialAgentConfig{Name: "pipeline", SubAgents: subAgents})
+			},
+			wantTargets: []string{"editor", "pipeline", "reviewer"},
+		},
+		{
+			name: "parent and peers disallowed",
+			parent: func(subAgents ...BaseAgent) BaseAgent {
+				return NewSequentialAgent(SequentialAgentConfig{Name: "pipeline", SubAgents: subAgents})
+			},
+			configure: func(writer *LlmAgent) {
+				writer.DisallowTransferToParent = true
+				writer.DisallowTransferToPeers = true
+			},
+			wantTargets: []string{"editor"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer := NewLlmAgent("writer", nil)
+			writer.AddSubAgents(NewLlmAgent("editor", nil))
+			if test.parent != nil {
+				test.parent(writer, NewLlmAgent("reviewer", nil))
+			}
+			if test.configure != nil {
+				test.configure(writer)
+			}
+
+			var names []string
+			for _, target := range writer.TransferTargets() {
+				names = append(names, target.Name())
+			}
+			if fmt.Sprint(names) != fmt.Sprint(test.wantTargets) {
+				t.Errorf("transfer targets = %v, want %v", names, test.wantTargets)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+)
+
+// parentSetter is implemented by agents that can be attached to a parent
+type parentSetter interface {
+	SetParentAgent(parent BaseAgent)
+}
+
+// adoptSubAgents makes parent the parent of each sub-agent that supports it
+func adoptSubAgents(parent BaseAgent, subAgents []BaseAgent) {
+	for _, subAgent := range subAgents {
+		if err := ValidateAgentHierarchy(subAgent, parent); err != nil {
+			telemetry.Logger.Printf("Warning: %v", err)
+		}
+		if child, ok := subAgent.(parentSetter); ok {
+			child.SetParentAgent(parent)
+		}
+	}
+}
+
+// rootAgent follows the parent links of an agent up to the root of its tree
+func rootAgent(agent BaseAgent) BaseAgent {
+	seen := map[BaseAgent]bool{agent: true}
+	for {
+		parent := agent.ParentAgent()
+		if parent == nil || seen[parent] {
+			return agent
+		}
+		seen[parent] = true
+		agent = parent
+	}
+}
+
+// findAgent searches an agent and its descendants, depth first, for the agent
+// with the given name
+func findAgent(agent BaseAgent, name string) BaseAgent {
+	return findAgentIn(agent, name, map[BaseAgent]bool{})
+}
+
+// findAgentIn searches the tree below agent, skipping agents already visited
+func findAgentIn(agent BaseAgent, name string, visited map[BaseAgent]bool) BaseAgent {
+	if visited[agent] {
+		return nil
+	}
+	visited[agent] = true
+
+	if agent.Name() == name {
+		return agent
+	}
+	for _, subAgent := range agent.SubAgents() {
+		if found := findAgentIn(subAgent, name, visited); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// ValidateTree checks the agent tree below root and returns every problem
+// found, joined into one error:
+//
+//   - two agents share a name, so transfers and FindAgent are ambiguous;
+//   - an agent is a sub-agent of two parents, or its ParentAgent does not
+//     match the agent listing it;
+//   - an agent is its own ancestor;
+//   - an LLM agent declares the transfer_to_agent tool without having any
+//     agent to transfer to, or a transfer target cannot be found by name;
+//   - two tools of one agent share a name;
+//   - an LLM agent has both an output schema and tools.
+func ValidateTree(root BaseAgent) error {
+	v := &treeValidator{
+		root:    root,
+		names:   make(map[string]BaseAgent),
+		parents: make(map[BaseAgent]BaseAgent),
+	}
+	v.visit(root, nil, nil)
+	v.checkTransfers()
+
+	return errors.Join(v.problems...)
+}
+
+// treeValidator collects the problems found while walking an agent tree
+type treeValidator struct {
+	root     BaseAgent
+	names    map[string]BaseAgent
+	parents  map[BaseAgent]BaseAgent
+	order    []BaseAgent
+	problems []error
+}
+
+// addProblem records a problem
+func (v *treeValidator) addProblem(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Errorf(format, args...))
+}
+
+// visit checks an agent and walks its sub-agents. path holds the ancestors
+// of the agent, from the root.
+func (v *treeValidator) visit(agent, parent BaseAgent, path []BaseAgent) {
+	for i, ancestor := range path {
+		if ancestor == agent {
+			names := make([]string, 0, len(path)-i+1)
+			for _, a := range path[i:] {
+				names = append(names, a.Name())
+			}
+			names = append(names, agent.Name())
+			v.addProblem("agent tree has a cycle: %s", strings.Join(names, " -> "))
+			return
+		}
+	}
+
+	if parent != nil {
+		if previous, ok := v.parents[agent]; ok {
+			v.addProblem("agent %s is a sub-agent of both %s and %s", agent.Name(), previous.Name(), parent.Name())
+			return
+		}
+		v.parents[agent] = parent
+
+		if declared := agent.ParentAgent(); declared != nil && declared != parent {
+			v.addProblem("agent %s is a sub-agent of %s but its parent is %s", agent.Name(), parent.Name(), declared.Name())
+		}
+	}
+
+	if other, ok := v.names[agent.Name()]; ok && other != agent {
+		v.addProblem("agent name %s is used by more than one agent", agent.Name())
+	} else {
+		v.names[agent.Name()] = agent
+		v.order = append(v.order, agent)
+	}
+
+	v.checkTools(agent)
+
+	path = append(path, agent)
+	for _, subAgent := range agent.SubAgents() {
+		v.visit(subAgent, agent, path)
+	}
+}
+
+// checkTools reports tools of one agent that share a name, and LLM agents
+// combining tools with an output schema
+func (v *treeValidator) checkTools(agent BaseAgent) {
+	agentTools := AgentTools(agent)
+	seen := make(map[string]bool)
+	for _, tool := range agentTools {
+		if seen[tool.Name()] {
+			v.addProblem("agent %s has more than one tool named %s", agent.Name(), tool.Name())
+		}
+		seen[tool.Name()] = true
+	}
+
+	if llmAgent, ok := agent.(*LlmAgent); ok && llmAgent.OutputSchema != nil && len(agentTools) > 0 {
+		v.addProblem("agent %s has an output schema and tools, an agent with an output schema cannot call tools", agent.Name())
+	}
+}
+
+// checkTransfers reports LLM agents whose transfer targets cannot be resolved
+func (v *treeValidator) checkTransfers() {
+	for _, agent := range v.order {
+		llmAgent, ok := agent.(*LlmAgent)
+		if !ok {
+			continue
+		}
+
+		targets := llmAgent.TransferTargets()
+		if len(targets) == 0 && tools.FindTool(llmAgent.CanonicalTools, tools.TransferToAgentTool.Name()) != nil {
+			v.addProblem("agent %s has the %s tool but no agent to transfer to", llmAgent.Name(), tools.TransferToAgentTool.Name())
+		}
+
+		for _, target := range targets {
+			if found := findAgent(v.root, target.Name()); found != target {
+				v.addProblem("agent %s cannot reach transfer target %s by name", llmAgent.Name(), target.Name())
+			}
+		}
+	}
+}
+
+// AgentTools returns the tools of an agent, if it has any
+func AgentTools(agent BaseAgent) []tools.Tool {
+	switch a := agent.(type) {
+	case *LlmAgent:
+		return a.CanonicalTools
+	case interface{ Tools() []tools.Tool }:
+		return a.Tools()
+	}
+	return nil
+}
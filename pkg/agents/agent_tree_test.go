@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+)
+
+func TestValidateTreeOutputSchemaWithTools(t *testing.T) {
+	schema := map[string]interface{}{"type": "object"}
+	lookup := tools.NewTool("lookup", "Looks things up", tools.ToolSchema{}, nil)
+
+	tests := []struct {
+		name    string
+		schema  map[string]interface{}
+		tools   []tools.Tool
+		wantErr bool
+	}{
+		{name: "output schema only", schema: schema},
+		{name: "tools only", tools: []tools.Tool{lookup}},
+		{name: "output schema and tools", schema: schema, tools: []tools.Tool{lookup}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := NewLlmAgent("agent", nil)
+			agent.OutputSchema = test.schema
+			agent.CanonicalTools = test.tools
+
+			err := ValidateTree(agent)
+			if test.wantErr && (err == nil || !strings.Contains(err.Error(), "output schema")) {
+				t.Errorf("ValidateTree = %v, want the output schema with tools reported", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("ValidateTree = %v, want no error", err)
+			}
+		})
+	}
+}
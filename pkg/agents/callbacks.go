@@ -0,0 +1,274 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/artifacts"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+// BeforeAgentCallback is called before an agent runs. Returning non-nil
+// content skips the agent; the content is emitted as the agent's response.
+type BeforeAgentCallback func(ctx context.Context, callbackContext *CallbackContext) *models.Content
+
+// AfterAgentCallback is called after an agent has run, with the agent's last
+// final response event, or nil if there was none. Returning non-nil content
+// emits a new response event after the final event, or in place of it when
+// replace is true.
+type AfterAgentCallback func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (content *models.Content, replace bool)
+
+// NewCallbackContext creates a CallbackContext for the given invocation.
+// State and artifact changes are recorded in fresh event actions.
+func NewCallbackContext(invocationContext *InvocationContext) *CallbackContext {
+	return &CallbackContext{
+		InvocationContext: invocationContext,
+		EventActions:      events.NewEventActions(),
+	}
+}
+
+// InvocationID returns the ID of the current invocation
+func (c *CallbackContext) InvocationID() string {
+	return c.InvocationContext.InvocationID
+}
+
+// AgentName returns the name of the agent the callback runs for
+func (c *CallbackContext) AgentName() string {
+	return c.InvocationContext.GetAgentName()
+}
+
+// GetState returns a state value, including changes made by this callback
+func (c *CallbackContext) GetState(key string) (interface{}, bool) {
+	if c.EventActions != nil {
+		if value, ok := c.EventActions.StateDelta[key]; ok {
+			return value, true
+		}
+	}
+	return c.InvocationContext.GetState(key)
+}
+
+// SetState records a state change, applied to the session with the callback's event
+func (c *CallbackContext) SetState(key string, value interface{}) {
+	c.actions().StateDelta[key] = value
+}
+
+// LoadArtifact loads an artifact of the current session. If version is nil,
+// the latest version is returned.
+func (c *CallbackContext) LoadArtifact(ctx context.Context, filename string, version *int) (*artifacts.Part, error) {
+	session, err := c.artifactSession()
+	if err != nil {
+		return nil, err
+	}
+	return c.InvocationContext.ArtifactService.LoadArtifact(ctx, session.AppName, session.UserID, session.ID, filename, version)
+}
+
+// SaveArtifact saves an artifact in the current session and returns its version
+func (c *CallbackContext) SaveArtifact(ctx context.Context, filename string, artifact artifacts.Part) (int, error) {
+	session, err := c.artifactSession()
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := c.InvocationContext.ArtifactService.SaveArtifact(ctx, session.AppName, session.UserID, session.ID, filename, artifact)
+	if err != nil {
+		return 0, err
+	}
+
+	c.actions().ArtifactDelta[filename] = version
+	return version, nil
+}
+
+// artifactSession returns the session artifacts are stored in
+func (c *CallbackContext) artifactSession() (*sessions.Session, error) {
+	if c.InvocationContext.ArtifactService == nil {
+		return nil, fmt.Errorf("artifact service is not initialized")
+	}
+	session := c.InvocationContext.Session
+	if session == nil {
+		return nil, fmt.Errorf("invocation has no session")
+	}
+	return session, nil
+}
+
+// actions returns the event actions, creating them if needed
+func (c *CallbackContext) actions() *events.EventActions {
+	if c.EventActions == nil {
+		c.EventActions = events.NewEventActions()
+	}
+	if c.EventActions.StateDelta == nil {
+		c.EventActions.StateDelta = make(map[string]interface{})
+	}
+	if c.EventActions.ArtifactDelta == nil {
+		c.EventActions.ArtifactDelta = make(map[string]int)
+	}
+	return c.EventActions
+}
+
+// hasChanges reports whether the callback changed state or artifacts
+func (c *CallbackContext) hasChanges() bool {
+	return c.EventActions != nil && (len(c.EventActions.StateDelta) > 0 || len(c.EventActions.ArtifactDelta) > 0)
+}
+
+// callbackEvent creates the event emitted for the result of an agent callback
+func callbackEvent(callbackContext *CallbackContext, author string, content *models.Content) *events.Event {
+	invocationContext := callbackContext.InvocationContext
+
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = author
+	event.Branch = invocationContext.Branch
+	event.Content = content
+	if callbackContext.EventActions != nil {
+		event.Actions = callbackContext.EventActions
+	}
+	return event
+}
+
+// runWithAgentCallbacks runs an agent between its before and after callbacks.
+// State changes made by a callback are emitted in an event of their own
+// unless the callback also returned content.
+func runWithAgentCallbacks(ctx context.Context, invocationContext *InvocationContext, agentName string,
+	before BeforeAgentCallback, after AfterAgentCallback,
+	run func(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error)) (<-chan *events.Event, error) {
+	if before == nil && after == nil {
+		return run(ctx, invocationContext)
+	}
+
+	var beforeEvent *events.Event
+	skip := false
+	if before != nil {
+		callbackContext := NewCallbackContext(invocationContext)
+		content := before(ctx, callbackContext)
+		if content != nil || callbackContext.hasChanges() {
+			beforeEvent = callbackEvent(callbackContext, agentName, content)
+			// Record the event now so the agent sees the state changes
+			invocationContext.AppendEvent(beforeEvent)
+		}
+		skip = content != nil
+	}
+
+	var agentEvents <-chan *events.Event
+	if !skip {
+		var err error
+		agentEvents, err = run(ctx, invocationContext)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	eventCh := make(chan *events.Event)
+
+	go func() {
+		defer close(eventCh)
+
+		if beforeEvent != nil {
+			eventCh <- beforeEvent
+		}
+		if skip {
+			return
+		}
+
+		if after == nil {
+			forwardEvents(agentEvents, eventCh)
+			return
+		}
+
+		// Hold back the latest final response so the callback can replace it
+		var finalEvent *events.Event
+		for event := range agentEvents {
+			if finalEvent != nil {
+				eventCh <- finalEvent
+				finalEvent = nil
+			}
+			if event.IsFinalResponse() {
+				finalEvent = event
+				continue
+			}
+			eventCh <- event
+		}
+
+		callbackContext := NewCallbackContext(invocationContext)
+		content, replace := after(ctx, callbackContext, finalEvent)
+
+		var afterEvent *events.Event
+		if content != nil || callbackContext.hasChanges() {
+			afterEvent = callbackEvent(callbackContext, agentName, content)
+		}
+
+		if finalEvent != nil {
+			if replace && content != nil {
+				// The replacement keeps what the final event did, such as
+				// its state changes, a transfer or an escalation
+				actions := events.NewEventActions()
+				actions.Update(finalEvent.Actions)
+				actions.Update(afterEvent.Actions)
+				afterEvent.Actions = actions
+				invocationContext.replaceEvent(finalEvent, afterEvent)
+				eventCh <- afterEvent
+				return
+			}
+			eventCh <- finalEvent
+		}
+
+		if afterEvent != nil {
+			invocationContext.AppendEvent(afterEvent)
+			eventCh <- afterEvent
+		}
+	}()
+
+	return eventCh, nil
+}
+
+// processWithAgentCallbacks applies an agent's callbacks around the
+// string-in/string-out API. The callbacks run on a fresh invocation, and
+// content they return is used as the reply. The invocation has no session, so
+// state changes made by the before callback are seen by the after callback
+// and then dropped.
+func processWithAgentCallbacks(ctx context.Context, agent BaseAgent, before BeforeAgentCallback, after AfterAgentCallback,
+	message string, process func(ctx context.Context, message string) (string, error)) (string, error) {
+	if before == nil && after == nil {
+		return process(ctx, message)
+	}
+
+	invocationContext := newMessageInvocationContext(agent, message)
+
+	if before != nil {
+		callbackContext := NewCallbackContext(invocationContext)
+		if content := before(ctx, callbackContext); content != nil {
+			return content.GetText(), nil
+		}
+		if callbackContext.hasChanges() {
+			invocationContext.AppendEvent(callbackEvent(callbackContext, agent.Name(), nil))
+		}
+	}
+
+	response, err := process(ctx, message)
+	if err != nil || after == nil {
+		return response, err
+	}
+
+	callbackContext := NewCallbackContext(invocationContext)
+	finalEvent := callbackEvent(callbackContext, agent.Name(), &models.Content{
+		Parts: []*models.Part{{Text: response, Role: "assistant"}},
+	})
+	if content, _ := after(ctx, callbackContext, finalEvent); content != nil {
+		return content.GetText(), nil
+	}
+	return response, nil
+}
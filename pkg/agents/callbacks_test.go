@@ -0,0 +1,220 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// textContent returns content holding text
+func textContent(text string) *models.Content {
+	return &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{{Text: text}}}
+}
+
+// answerRun returns an agent run function that records a state change and
+// answers with text. runs counts its calls and seen records the "before"
+// state value at the time it ran.
+func answerRun(text string, runs *int, seen *interface{}) func(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return func(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+		*runs++
+		*seen, _ = invocationContext.GetState("before")
+
+		event := events.NewEvent()
+		event.InvocationID = invocationContext.InvocationID
+		event.Author = "agent"
+		event.Content = textContent(text)
+		event.Actions.StateDelta["result"] = text
+		invocationContext.AppendEvent(event)
+
+		eventCh := make(chan *events.Event, 1)
+		eventCh <- event
+		close(eventCh)
+		return eventCh, nil
+	}
+}
+
+func TestAgentCallbacks(t *testing.T) {
+	tests := []struct {
+		name      string
+		before    BeforeAgentCallback
+		after     AfterAgentCallback
+		wantRuns  int
+		wantSeen  interface{}
+		wantTexts []string
+		wantState map[string]interface{}
+	}{
+		{
+			name: "before skips the agent",
+			before: func(ctx context.Context, callbackContext *CallbackContext) *models.Content {
+				callbackContext.SetState("before", true)
+				return textContent("skipped")
+			},
+			wantTexts: []string{"skipped"},
+			wantState: map[string]interface{}{"before": true},
+		},
+		{
+			name: "before changes state",
+			before: func(ctx context.Context, callbackContext *CallbackContext) *models.Content {
+				callbackContext.SetState("before", true)
+				return nil
+			},
+			wantRuns:  1,
+			wantSeen:  true,
+			wantTexts: []string{"", "answer"},
+			wantState: map[string]interface{}{"before": true, "result": "answer"},
+		},
+		{
+			name: "after appends",
+			after: func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (*models.Content, bool) {
+				return textContent("appended"), false
+			},
+			wantRuns:  1,
+			wantTexts: []string{"answer", "appended"},
+			wantState: map[string]interface{}{"result": "answer"},
+		},
+		{
+			name: "after replaces",
+			after: func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (*models.Content, bool) {
+				callbackContext.SetState("after", true)
+				return textContent("replaced"), true
+			},
+			wantRuns:  1,
+			wantTexts: []string{"replaced"},
+			wantState: map[string]interface{}{"result": "answer", "after": true},
+		},
+		{
+			name: "after changes state",
+			after: func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (*models.Content, bool) {
+				callbackContext.SetState("after", finalEvent.Content.GetText())
+				return nil, false
+			},
+			wantRuns:  1,
+			wantTexts: []string{"answer", ""},
+			wantState: map[string]interface{}{"result": "answer", "after": "answer"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invocationContext := NewInvocationContext("invocation", &stubAgent{name: "agent"}, nil)
+			runs := 0
+			var seen interface{}
+
+			eventCh, err := runWithAgentCallbacks(context.Background(), invocationContext, "agent",
+				test.before, test.after, answerRun("answer", &runs, &seen))
+			result := drain(t, eventCh, err)
+
+			if runs != test.wantRuns {
+				t.Errorf("agent ran %d times, want %d", runs, test.wantRuns)
+			}
+			if seen != test.wantSeen {
+				t.Errorf("agent saw before = %v, want %v", seen, test.wantSeen)
+			}
+
+			var texts []string
+			for _, event := range result {
+				text := ""
+				if event.Content != nil {
+					text = event.Content.GetText()
+				}
+				texts = append(texts, text)
+			}
+			if len(texts) != len(test.wantTexts) {
+				t.Fatalf("event texts = %q, want %q", texts, test.wantTexts)
+			}
+			for i := range texts {
+				if texts[i] != test.wantTexts[i] {
+					t.Errorf("event texts = %q, want %q", texts, test.wantTexts)
+					break
+				}
+			}
+
+			// Every event sent is recorded, and only those
+			if recorded := invocationContext.GetEvents(); len(recorded) != len(result) {
+				t.Errorf("%d events recorded, want the %d sent", len(recorded), len(result))
+			}
+			for key, want := range test.wantState {
+				if value, _ := invocationContext.GetState(key); value != want {
+					t.Errorf("state %s = %v, want %v", key, value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAgentOutputKeySurvivesReplacingCallback(t *testing.T) {
+	var callbackAgent string
+	agent := NewAgent(
+		WithName("agent"),
+		WithLLM(&answerLlm{response: &models.LlmResponse{Content: textContent("Hello")}}),
+		WithOutputKey("answer"),
+		WithAfterAgentCallback(func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (*models.Content, bool) {
+			callbackAgent = callbackContext.AgentName()
+			return textContent("Hello!"), true
+		}),
+	)
+
+	// The agent runs under a context bound to its parent
+	invocationContext := NewInvocationContext("invocation", &stubAgent{name: "parent"}, nil)
+	invocationContext.InvocationEvent = events.NewEvent()
+	invocationContext.InvocationEvent.Content = &models.Content{Role: models.RoleUser, Parts: []*models.Part{{Text: "Hi"}}}
+
+	eventCh, err := agent.Run(context.Background(), invocationContext)
+	result := drain(t, eventCh, err)
+
+	if len(result) != 1 || result[0].Content.GetText() != "Hello!" {
+		t.Fatalf("events = %+v, want the replaced response", result)
+	}
+	if value := result[0].Actions.StateDelta["answer"]; value != "Hello" {
+		t.Errorf("answer in the replacement's state delta = %v, want Hello", value)
+	}
+	if value, _ := invocationContext.GetState("answer"); value != "Hello" {
+		t.Errorf("answer state = %v, want Hello", value)
+	}
+	if callbackAgent != "agent" {
+		t.Errorf("callback ran for agent %q, want agent", callbackAgent)
+	}
+}
+
+func TestProcessCallbacksShareState(t *testing.T) {
+	var seen interface{}
+	agent := NewAgent(
+		WithName("agent"),
+		WithLLM(&answerLlm{response: &models.LlmResponse{Content: textContent("Hello")}}),
+		WithBeforeAgentCallback(func(ctx context.Context, callbackContext *CallbackContext) *models.Content {
+			callbackContext.SetState("greeted", true)
+			return nil
+		}),
+		WithAfterAgentCallback(func(ctx context.Context, callbackContext *CallbackContext, finalEvent *events.Event) (*models.Content, bool) {
+			seen, _ = callbackContext.GetState("greeted")
+			return nil, false
+		}),
+	)
+
+	response, err := agent.Process(context.Background(), "Hi")
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if response != "Hello" {
+		t.Errorf("response = %q, want Hello", response)
+	}
+	if seen != true {
+		t.Errorf("after callback saw greeted = %v, want true", seen)
+	}
+}
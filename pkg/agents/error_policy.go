@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// ErrorAction is what an agent's flow does about a failed call
+type ErrorAction int
+
+const (
+	// ErrorActionAbort ends the agent's turn with an error event
+	ErrorActionAbort ErrorAction = iota
+
+	// ErrorActionRetry makes the failed call again
+	ErrorActionRetry
+
+	// ErrorActionContinue feeds the error back to the model as the result of
+	// the failed call. A failed model call cannot be fed back and aborts, and
+	// a refused transfer cannot be retried and continues.
+	ErrorActionContinue
+)
+
+// ErrorPolicy decides what an agent's flow does about a failed model or tool
+// call, or a refused transfer. attempt is the number of times the call was
+// already retried. Failures that end the whole invocation, such as a
+// cancellation or a used up budget, always abort, and a tool call that may
+// still be running is never retried. Sequential and loop agents use it to
+// decide whether to run their next sub-agent after one failed.
+type ErrorPolicy func(err *events.Error, attempt int) ErrorAction
+
+// DefaultErrorPolicy feeds failed or timed out tool calls and refused
+// transfers back to the model, so it can try something else, and aborts on
+// other failures
+func DefaultErrorPolicy(err *events.Error, attempt int) ErrorAction {
+	switch err.Code {
+	case events.ErrorCodeTool, events.ErrorCodeToolTimeout, events.ErrorCodeAuth, events.ErrorCodeTransfer:
+		return ErrorActionContinue
+	}
+	return ErrorActionAbort
+}
+
+// RetryModelErrors returns a policy that retries failed model calls, including
+// calls that ran out of time, up to maxRetries times. Other failures, such as
+// tool timeouts, are left to DefaultErrorPolicy.
+func RetryModelErrors(maxRetries int) ErrorPolicy {
+	return func(err *events.Error, attempt int) ErrorAction {
+		switch err.Code {
+		case events.ErrorCodeModel, events.ErrorCodeDeadlineExceeded:
+			if attempt < maxRetries {
+				return ErrorActionRetry
+			}
+		}
+		return DefaultErrorPolicy(err, attempt)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+// InstructionProvider builds the instructions of an LlmAgent at run time.
+// Placeholders in the returned instructions are not resolved.
+type InstructionProvider func(ctx context.Context, invocationContext *InvocationContext) (string, error)
+
+// artifactPlaceholderPrefix marks placeholders resolved from the artifact service
+const artifactPlaceholderPrefix = "artifact."
+
+// placeholderPattern matches {name} placeholders, including doubled braces
+var placeholderPattern = regexp.MustCompile(`{+[^{}]*}+`)
+
+// identifierPattern matches a valid state key without prefix
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// InjectSessionState resolves the placeholders in an instruction template.
+//
+//   - {key} is replaced with the state value of key; keys may carry the
+//     app:, user: or temp: prefix.
+//   - {artifact.filename} is replaced with the text of the latest version of
+//     the artifact in the session.
+//   - {key?} and {artifact.filename?} are replaced with an empty string when
+//     the value does not exist.
+//
+// Text in braces that is not a valid state key is left untouched. A missing
+// value for a required placeholder is an error.
+func InjectSessionState(ctx context.Context, template string, invocationContext *InvocationContext) (string, error) {
+	var resolveErr error
+
+	result := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+
+		value, err := resolvePlaceholder(ctx, match, invocationContext)
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return value
+	})
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return result, nil
+}
+
+// resolvePlaceholder returns the replacement text for a single placeholder
+func resolvePlaceholder(ctx context.Context, match string, invocationContext *InvocationContext) (string, error) {
+	name := strings.TrimSpace(strings.Trim(match, "{}"))
+
+	optional := strings.HasSuffix(name, "?")
+	name = strings.TrimSuffix(name, "?")
+
+	if strings.HasPrefix(name, artifactPlaceholderPrefix) {
+		filename := strings.TrimPrefix(name, artifactPlaceholderPrefix)
+		return loadArtifactText(ctx, invocationContext, filename, optional)
+	}
+
+	if !isValidStateName(name) {
+		return match, nil
+	}
+
+	value, ok := invocationContext.GetState(name)
+	if !ok {
+		if optional {
+			return "", nil
+		}
+		return "", fmt.Errorf("context variable not found: %s", name)
+	}
+	return formatStateValue(value), nil
+}
+
+// loadArtifactText returns the text of the latest version of an artifact
+func loadArtifactText(ctx context.Context, invocationContext *InvocationContext, filename string, optional bool) (string, error) {
+	if invocationContext.ArtifactService == nil {
+		return "", fmt.Errorf("artifact service is not initialized")
+	}
+
+	session := invocationContext.Session
+	if session == nil {
+		return "", fmt.Errorf("cannot load artifact %s: invocation has no session", filename)
+	}
+
+	artifact, err := invocationContext.ArtifactService.LoadArtifact(ctx, session.AppName, session.UserID, session.ID, filename, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to load artifact %s: %w", filename, err)
+	}
+	if artifact == nil {
+		if optional {
+			return "", nil
+		}
+		return "", fmt.Errorf("artifact %s not found", filename)
+	}
+
+	if artifact.Text != "" {
+		return artifact.Text, nil
+	}
+	return string(artifact.Data), nil
+}
+
+// isValidStateName reports whether name is a state key, optionally prefixed
+// with one of the state scopes
+func isValidStateName(name string) bool {
+	for _, prefix := range []string{sessions.AppPrefix, sessions.UserPrefix, sessions.TempPrefix} {
+		if strings.HasPrefix(name, prefix) {
+			return identifierPattern.MatchString(strings.TrimPrefix(name, prefix))
+		}
+	}
+	return identifierPattern.MatchString(name)
+}
+
+// formatStateValue renders a state value for use in instructions
+func formatStateValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/artifacts"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+func TestInjectSessionState(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		noArtifacts bool
+		want        string
+		wantErr     string
+	}{
+		{
+			name:     "state value",
+			template: "Greet {name}.",
+			want:     "Greet Ada.",
+		},
+		{
+			name:     "prefixed keys",
+			template: "{app:theme} {user:language} {temp:step}",
+			want:     "dark en 2",
+		},
+		{
+			name:     "non-string values",
+			template: "{count} {profile}",
+			want:     `3 {"city":"Paris"}`,
+		},
+		{
+			name:     "doubled braces",
+			template: "Greet {{name}}.",
+			want:     "Greet Ada.",
+		},
+		{
+			name:     "value written earlier in the invocation",
+			template: "Step {step}.",
+			want:     "Step 5.",
+		},
+		{
+			name:     "text that is not a state key",
+			template: `Answer with {"ok": true} or { name with spaces }.`,
+			want:     `Answer with {"ok": true} or { name with spaces }.`,
+		},
+		{
+			name:     "optional missing value",
+			template: "Greet {nickname?}.",
+			want:     "Greet .",
+		},
+		{
+			name:     "required missing value",
+			template: "Greet {nickname}.",
+			wantErr:  "context variable not found: nickname",
+		},
+		{
+			name:     "artifact text",
+			template: "Notes: {artifact.notes.txt}",
+			want:     "Notes: second draft",
+		},
+		{
+			name:     "artifact data",
+			template: "Data: {artifact.data.csv}",
+			want:     "Data: a,b",
+		},
+		{
+			name:     "optional missing artifact",
+			template: "Notes: {artifact.missing.txt?}",
+			want:     "Notes: ",
+		},
+		{
+			name:     "required missing artifact",
+			template: "Notes: {artifact.missing.txt}",
+			wantErr:  "artifact missing.txt not found",
+		},
+		{
+			name:        "artifact without an artifact service",
+			template:    "Notes: {artifact.notes.txt}",
+			noArtifacts: true,
+			wantErr:     "artifact service is not initialized",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			session := sessions.NewSession("app", "user", map[string]interface{}{
+				"name":          "Ada",
+				"count":         3,
+				"profile":       map[string]interface{}{"city": "Paris"},
+				"step":          1,
+				"app:theme":     "dark",
+				"user:language": "en",
+			}, "session")
+
+			invocationContext := NewInvocationContext("invocation", &stubAgent{name: "agent"}, nil)
+			invocationContext.Session = session
+
+			// Values of the current invocation win over the session state
+			event := events.NewEvent()
+			event.InvocationID = "invocation"
+			event.Actions.StateDelta["step"] = 5
+			event.Actions.StateDelta["temp:step"] = 2
+			invocationContext.AppendEvent(event)
+
+			if !test.noArtifacts {
+				artifactService := artifacts.NewInMemoryArtifactService()
+				for _, artifact := range []struct {
+					filename string
+					part     artifacts.Part
+				}{
+					{"notes.txt", artifacts.Part{Text: "first draft"}},
+					{"notes.txt", artifacts.Part{Text: "second draft"}},
+					{"data.csv", artifacts.Part{Data: []byte("a,b")}},
+				} {
+					if _, err := artifactService.SaveArtifact(ctx, "app", "user", "session", artifact.filename, artifact.part); err != nil {
+						t.Fatalf("SaveArtifact: %v", err)
+					}
+				}
+				invocationContext.ArtifactService = artifactService
+			}
+
+			got, err := InjectSessionState(ctx, test.template, invocationContext)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("InjectSessionState error = %v, want it to mention %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InjectSessionState: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("InjectSessionState = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -16,8 +16,11 @@ package agents
 
 import (
 	"fmt"
+	"sync"
 
+	"github.com/nvcnvn/adk-golang/pkg/artifacts"
 	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
 	"github.com/nvcnvn/adk-golang/pkg/types"
 )
 
@@ -106,8 +109,15 @@ type InvocationContext struct {
 	// InvocationEvent is the event that triggered this invocation
 	InvocationEvent *events.Event `json:"invocationEvent,omitempty"`
 
-	// Events contains all events associated with this invocation
-	Events []*events.Event `json:"events,omitempty"`
+	// Session is the session this invocation belongs to, if any
+	Session *sessions.Session `json:"-"`
+
+	// ArtifactService stores the artifacts of the session, if any
+	ArtifactService artifacts.ArtifactService `json:"-"`
+
+	// history holds the events recorded so far. It is shared by every context
+	// derived from this one, so sub-agents see each other's events.
+	history *eventHistory
 
 	// LiveRequestQueue holds the queue for live requests
 	LiveRequestQueue *LiveRequestQueue `json:"-"`
@@ -125,15 +135,115 @@ func NewInvocationContext(invocationID string, agent BaseAgent, runConfig *types
 		}
 	}
 
-	return &InvocationContext{
+	ctx := &InvocationContext{
 		InvocationContextData: types.InvocationContextData{
 			InvocationID: invocationID,
 			RunConfig:    runConfig,
 		},
 		Agent:                agent,
-		Events:               make([]*events.Event, 0),
 		ActiveStreamingTools: make(map[string]*ActiveStreamingTool),
 	}
+	ctx.Share()
+	return ctx
+}
+
+// eventHistory is an append-only, concurrency-safe list of events
+type eventHistory struct {
+	events []*events.Event
+	mu     sync.RWMutex
+}
+
+// Share initializes the state shared by the copies of this context: the event
+// history, LLM call count and end flag. NewInvocationContext calls it.
+func (ctx *InvocationContext) Share() {
+	if ctx.history == nil {
+		ctx.history = &eventHistory{}
+	}
+	ctx.InvocationContextData.Share()
+}
+
+// getHistory returns the shared event history
+func (ctx *InvocationContext) getHistory() *eventHistory {
+	ctx.Share()
+	return ctx.history
+}
+
+// AppendEvent records an event in the invocation history
+func (ctx *InvocationContext) AppendEvent(event *events.Event) {
+	history := ctx.getHistory()
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	history.events = append(history.events, event)
+}
+
+// replaceEvent swaps a recorded event for another one. The new event is
+// appended if the old one was not recorded.
+func (ctx *InvocationContext) replaceEvent(old, replacement *events.Event) {
+	history := ctx.getHistory()
+	history.mu.Lock()
+	defer history.mu.Unlock()
+
+	for i, event := range history.events {
+		if event == old {
+			history.events[i] = replacement
+			return
+		}
+	}
+	history.events = append(history.events, replacement)
+}
+
+// GetEvents returns a snapshot of the events recorded in the invocation history
+func (ctx *InvocationContext) GetEvents() []*events.Event {
+	history := ctx.getHistory()
+	history.mu.RLock()
+	defer history.mu.RUnlock()
+
+	result := make([]*events.Event, len(history.events))
+	copy(result, history.events)
+	return result
+}
+
+// WithAgent returns a copy of the invocation context bound to the given agent.
+// The copy shares the event history, LLM call count and end flag with the
+// original.
+func (ctx *InvocationContext) WithAgent(agent BaseAgent) *InvocationContext {
+	ctx.Share()
+
+	child := *ctx
+	child.Agent = agent
+	return &child
+}
+
+// WithBranch returns a copy of the invocation context on the given branch.
+// Events produced under the copy are tagged with the branch, which keeps the
+// conversation history of sibling branches apart.
+func (ctx *InvocationContext) WithBranch(branch string) *InvocationContext {
+	child := ctx.WithAgent(ctx.Agent)
+	child.Branch = branch
+	return child
+}
+
+// GetState returns a state value as seen by this invocation. State changes
+// made by events of the current invocation take precedence over the session
+// state, so values written earlier in the invocation, including temp: keys
+// that are never persisted, are visible before the session is updated.
+func (ctx *InvocationContext) GetState(key string) (interface{}, bool) {
+	recorded := ctx.GetEvents()
+	for i := len(recorded) - 1; i >= 0; i-- {
+		event := recorded[i]
+		if event.InvocationID != ctx.InvocationID || event.Actions == nil {
+			continue
+		}
+		if value, ok := event.Actions.StateDelta[key]; ok {
+			return value, true
+		}
+	}
+
+	if ctx.Session == nil || ctx.Session.State == nil {
+		return nil, false
+	}
+	return ctx.Session.GetState(key)
 }
 
 // GetID returns the invocation ID
@@ -146,16 +256,6 @@ func (ctx *InvocationContext) GetAgentName() string {
 	return ctx.Agent.Name()
 }
 
-// IsEndInvocation returns whether the invocation should end
-func (ctx *InvocationContext) IsEndInvocation() bool {
-	return ctx.EndInvocation
-}
-
-// SetEndInvocation sets whether the invocation should end
-func (ctx *InvocationContext) SetEndInvocation(end bool) {
-	ctx.EndInvocation = end
-}
-
 // GetTranscriptionCache returns the transcription cache
 func (ctx *InvocationContext) GetTranscriptionCache() interface{} {
 	return ctx.TranscriptionCache
@@ -0,0 +1,125 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// stubAgent is an agent that counts its runs, emits one text event and then
+// optionally ends the invocation
+type stubAgent struct {
+	name   string
+	end    bool
+	runs   int32
+	parent BaseAgent
+}
+
+func (a *stubAgent) Name() string { return a.name }
+
+func (a *stubAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	atomic.AddInt32(&a.runs, 1)
+	invocationContext = invocationContext.WithAgent(a)
+	if a.end {
+		invocationContext.SetEndInvocation(true)
+	}
+
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = a.name
+	event.Content = &models.Content{Parts: []*models.Part{{Text: a.name}}}
+
+	eventCh := make(chan *events.Event, 1)
+	eventCh <- event
+	close(eventCh)
+	return eventCh, nil
+}
+
+func (a *stubAgent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return a.Run(ctx, invocationContext)
+}
+
+func (a *stubAgent) RootAgent() BaseAgent { return rootAgent(a) }
+
+func (a *stubAgent) FindAgent(name string) BaseAgent {
+	if a.name == name {
+		return a
+	}
+	return nil
+}
+
+func (a *stubAgent) SubAgents() []BaseAgent { return nil }
+
+func (a *stubAgent) ParentAgent() BaseAgent { return a.parent }
+
+func (a *stubAgent) SetParentAgent(parent BaseAgent) { a.parent = parent }
+
+// drain reads all events of an agent run
+func drain(t *testing.T, eventCh <-chan *events.Event, err error) []*events.Event {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var result []*events.Event
+	for event := range eventCh {
+		result = append(result, event)
+	}
+	return result
+}
+
+func TestEndInvocationIsSharedWithCopies(t *testing.T) {
+	parent := NewInvocationContext("invocation", &stubAgent{name: "parent"}, nil)
+	child := parent.WithAgent(&stubAgent{name: "child"}).WithBranch("parent.child")
+
+	child.SetEndInvocation(true)
+	if !parent.IsEndInvocation() {
+		t.Errorf("end flag set by a sub-agent's context is not seen by its parent")
+	}
+}
+
+func TestSequentialAgentStopsWhenInvocationEnds(t *testing.T) {
+	first := &stubAgent{name: "first", end: true}
+	second := &stubAgent{name: "second"}
+	sequential := NewSequentialAgent(SequentialAgentConfig{Name: "sequential", SubAgents: []BaseAgent{first, second}})
+
+	eventCh, err := sequential.Run(context.Background(), NewInvocationContext("invocation", sequential, nil))
+	drain(t, eventCh, err)
+
+	if runs := atomic.LoadInt32(&second.runs); runs != 0 {
+		t.Errorf("second sub-agent ran %d times after the invocation ended", runs)
+	}
+}
+
+func TestLoopAgentStopsWhenInvocationEnds(t *testing.T) {
+	first := &stubAgent{name: "first", end: true}
+	second := &stubAgent{name: "second"}
+	loop := NewLoopAgent(LoopAgentConfig{Name: "loop", SubAgents: []BaseAgent{first, second}, MaxIterations: 3})
+
+	eventCh, err := loop.Run(context.Background(), NewInvocationContext("invocation", loop, nil))
+	drain(t, eventCh, err)
+
+	if runs := atomic.LoadInt32(&first.runs); runs != 1 {
+		t.Errorf("first sub-agent ran %d times, want 1", runs)
+	}
+	if runs := atomic.LoadInt32(&second.runs); runs != 0 {
+		t.Errorf("second sub-agent ran %d times after the invocation ended", runs)
+	}
+}
@@ -18,21 +18,37 @@ package agents
 import (
 	"context"
 	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 )
 
 // LoopAgent runs its sub-agents repeatedly until a condition is met or max iterations is reached.
 type LoopAgent struct {
 	Agent
-	subAgents     []*Agent
+	subAgents     []BaseAgent
 	maxIterations int
+	errorPolicy   ErrorPolicy
 }
 
 // LoopAgentConfig holds configuration for creating a LoopAgent.
 type LoopAgentConfig struct {
 	Name          string
 	Description   string
-	SubAgents     []*Agent
+	SubAgents     []BaseAgent
 	MaxIterations int
+
+	// BeforeAgentCallback is called before the agent runs and may skip it
+	BeforeAgentCallback BeforeAgentCallback
+
+	// AfterAgentCallback is called after the agent has run and may add to or
+	// replace its final response
+	AfterAgentCallback AfterAgentCallback
+
+	// ErrorPolicy, if set, decides whether the next sub-agent runs after one
+	// ended its turn with an error event; only ErrorActionContinue lets it
+	// run. By default the agent stops at the first failed sub-agent.
+	ErrorPolicy ErrorPolicy
 }
 
 // NewLoopAgent creates a new agent that processes sub-agents in a loop.
@@ -43,18 +59,29 @@ func NewLoopAgent(config LoopAgentConfig) *LoopAgent {
 		maxIter = 10
 	}
 
-	return &LoopAgent{
+	agent := &LoopAgent{
 		Agent: Agent{
-			name:        config.Name,
-			description: config.Description,
+			name:                config.Name,
+			description:         config.Description,
+			beforeAgentCallback: config.BeforeAgentCallback,
+			afterAgentCallback:  config.AfterAgentCallback,
 		},
 		subAgents:     config.SubAgents,
 		maxIterations: maxIter,
+		errorPolicy:   config.ErrorPolicy,
 	}
+
+	adoptSubAgents(agent, config.SubAgents)
+	return agent
 }
 
 // Process handles a message by processing it through all sub-agents repeatedly.
 func (a *LoopAgent) Process(ctx context.Context, message string) (string, error) {
+	return processWithAgentCallbacks(ctx, a, a.beforeAgentCallback, a.afterAgentCallback, message, a.process)
+}
+
+// process passes the message through the sub-agents
+func (a *LoopAgent) process(ctx context.Context, message string) (string, error) {
 	currentMessage := message
 	var err error
 	iterations := 0
@@ -65,7 +92,7 @@ func (a *LoopAgent) Process(ctx context.Context, message string) (string, error)
 
 		// Process through each sub-agent in sequence
 		for _, subAgent := range a.subAgents {
-			currentMessage, err = subAgent.Process(ctx, currentMessage)
+			currentMessage, err = ProcessMessage(ctx, subAgent, currentMessage)
 			if err != nil {
 				return "", err
 			}
@@ -83,11 +110,83 @@ func (a *LoopAgent) Process(ctx context.Context, message string) (string, error)
 	return currentMessage, nil
 }
 
+// Run executes the sub-agents in sequence, repeating until the maximum number
+// of iterations is reached or a sub-agent escalates (for example by calling
+// the exit_loop tool). Every sub-agent event is forwarded.
+func (a *LoopAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.beforeAgentCallback, a.afterAgentCallback, a.run)
+}
+
+// run iterates over the sub-agents
+func (a *LoopAgent) run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	eventCh := make(chan *events.Event)
+	ctx, span := telemetry.StartSpan(ctx, "LoopAgent.Run")
+	span.SetAttribute("agent.name", a.name)
+
+	invocationContext = invocationContext.WithAgent(a)
+
+	go func() {
+		defer close(eventCh)
+		defer span.End()
+
+		for iteration := 1; iteration <= a.maxIterations; iteration++ {
+			span.SetAttribute("iteration", fmt.Sprintf("%d", iteration))
+
+			for _, subAgent := range a.subAgents {
+				if invocationContext.IsEndInvocation() {
+					return
+				}
+				if ctx.Err() != nil {
+					eventCh <- stopEvent(ctx, invocationContext, a.name)
+					return
+				}
+
+				subEvents, err := subAgent.Run(ctx, invocationContext)
+				if err != nil {
+					span.SetAttribute("error", err.Error())
+					eventCh <- subAgentErrorEvent(invocationContext, a.name, subAgent, err)
+					return
+				}
+
+				// The escalating sub-agent is allowed to finish its turn
+				escalated, failure := forwardEvents(subEvents, eventCh)
+				if escalated {
+					span.SetAttribute("escalated_by", subAgent.Name())
+					return
+				}
+
+				// A failure caused by the context already reported why it stopped
+				if failure != nil && (ctx.Err() != nil || !continueAfterFailure(a.errorPolicy, failure)) {
+					span.SetAttribute("failed_sub_agent", subAgent.Name())
+					return
+				}
+			}
+		}
+	}()
+
+	return eventCh, nil
+}
+
+// RunLive is not supported for loop agents.
+func (a *LoopAgent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return nil, fmt.Errorf("live mode is not supported by loop agent %s", a.name)
+}
+
 // SubAgents returns the sub-agents of this loop agent.
-func (a *LoopAgent) SubAgents() []*Agent {
+func (a *LoopAgent) SubAgents() []BaseAgent {
 	return a.subAgents
 }
 
+// RootAgent returns the root agent in the agent tree
+func (a *LoopAgent) RootAgent() BaseAgent {
+	return rootAgent(a)
+}
+
+// FindAgent finds an agent by name in this agent and its descendants
+func (a *LoopAgent) FindAgent(name string) BaseAgent {
+	return findAgent(a, name)
+}
+
 // MaxIterations returns the maximum number of iterations for this loop agent.
 func (a *LoopAgent) MaxIterations() int {
 	return a.maxIterations
@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// DecodeOutput decodes the structured output of an agent into target, which
+// must be a pointer. The output can be the text of the agent's final response
+// or the value stored in session state under the agent's OutputKey.
+func DecodeOutput(output interface{}, target interface{}) error {
+	var value interface{}
+	switch v := output.(type) {
+	case string:
+		parsed, err := models.ParseJSONResponse(v)
+		if err != nil {
+			return err
+		}
+		value = parsed
+	case []byte:
+		parsed, err := models.ParseJSONResponse(string(v))
+		if err != nil {
+			return err
+		}
+		value = parsed
+	default:
+		value = v
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode output: %w", err)
+	}
+	return nil
+}
@@ -17,66 +17,337 @@ package agents
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
+)
+
+// FailurePolicy controls how a ParallelAgent reacts when one of its branches fails.
+type FailurePolicy string
+
+const (
+	// FailFast cancels the remaining branches as soon as one branch fails
+	FailFast FailurePolicy = "fail_fast"
+
+	// CollectAll lets every branch finish and reports per-branch results and errors
+	CollectAll FailurePolicy = "collect_all"
 )
 
+// BranchResult holds the outcome of a single ParallelAgent branch.
+type BranchResult struct {
+	// AgentName is the name of the sub-agent that ran the branch
+	AgentName string
+
+	// Response is the sub-agent's reply, empty if the branch failed
+	Response string
+
+	// Err is the error returned by the branch, if any
+	Err error
+}
+
 // ParallelAgent runs its sub-agents in parallel and aggregates their responses.
 type ParallelAgent struct {
 	Agent
-	subAgents []*Agent
+	subAgents      []BaseAgent
+	maxConcurrency int
+	failurePolicy  FailurePolicy
+	branchTimeout  time.Duration
 }
 
 // ParallelAgentConfig holds configuration for creating a ParallelAgent.
 type ParallelAgentConfig struct {
 	Name        string
 	Description string
-	SubAgents   []*Agent
+	SubAgents   []BaseAgent
+
+	// MaxConcurrency limits how many sub-agents run at once; 0 means no limit
+	MaxConcurrency int
+
+	// FailurePolicy selects fail-fast or collect-all behavior; defaults to FailFast
+	FailurePolicy FailurePolicy
+
+	// BranchTimeout bounds the duration of each branch; 0 means no timeout
+	BranchTimeout time.Duration
+
+	// BeforeAgentCallback is called before the agent runs and may skip it
+	BeforeAgentCallback BeforeAgentCallback
+
+	// AfterAgentCallback is called after the agent has run and may add to or
+	// replace its final response
+	AfterAgentCallback AfterAgentCallback
 }
 
 // NewParallelAgent creates a new agent that processes sub-agents in parallel.
 func NewParallelAgent(config ParallelAgentConfig) *ParallelAgent {
-	return &ParallelAgent{
+	failurePolicy := config.FailurePolicy
+	if failurePolicy == "" {
+		failurePolicy = FailFast
+	}
+
+	agent := &ParallelAgent{
 		Agent: Agent{
-			name:        config.Name,
-			description: config.Description,
+			name:                config.Name,
+			description:         config.Description,
+			beforeAgentCallback: config.BeforeAgentCallback,
+			afterAgentCallback:  config.AfterAgentCallback,
 		},
-		subAgents: config.SubAgents,
+		subAgents:      config.SubAgents,
+		maxConcurrency: config.MaxConcurrency,
+		failurePolicy:  failurePolicy,
+		branchTimeout:  config.BranchTimeout,
 	}
+
+	adoptSubAgents(agent, config.SubAgents)
+	return agent
 }
 
 // Process handles a message by processing it through all sub-agents in parallel.
+// With FailFast the first branch error is returned. With CollectAll the
+// responses of the successful branches are combined, and an error is only
+// returned if every branch failed.
 func (a *ParallelAgent) Process(ctx context.Context, message string) (string, error) {
-	var wg sync.WaitGroup
-	responses := make([]string, len(a.subAgents))
-	errors := make([]error, len(a.subAgents))
+	return processWithAgentCallbacks(ctx, a, a.beforeAgentCallback, a.afterAgentCallback, message, a.process)
+}
+
+// process passes the message through the sub-agents
+func (a *ParallelAgent) process(ctx context.Context, message string) (string, error) {
+	results, err := a.ProcessBranches(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	responses := make([]string, 0, len(results))
+	branchErrors := make([]error, 0)
+	for _, result := range results {
+		if result.Err != nil {
+			branchErrors = append(branchErrors, result.Err)
+			continue
+		}
+		responses = append(responses, result.Response)
+	}
 
-	// Process through each sub-agent in parallel
+	if len(responses) == 0 && len(branchErrors) > 0 {
+		return "", fmt.Errorf("all branches of parallel agent %s failed: %w", a.name, errors.Join(branchErrors...))
+	}
+
+	// Combine responses
+	return strings.Join(responses, "\n\n"), nil
+}
+
+// ProcessBranches sends the message to every sub-agent and returns one result
+// per sub-agent, in the order the sub-agents were configured. With FailFast
+// the remaining branches are cancelled after the first failure and that
+// failure is also returned as the error.
+func (a *ParallelAgent) ProcessBranches(ctx context.Context, message string) ([]BranchResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]BranchResult, len(a.subAgents))
+	slots := a.newSlots()
+
+	var firstErr error
+	var failOnce sync.Once
+
+	var wg sync.WaitGroup
 	for i, subAgent := range a.subAgents {
+		results[i].AgentName = subAgent.Name()
+
 		wg.Add(1)
-		go func(idx int, agent *Agent) {
+		go func(idx int, agent BaseAgent) {
 			defer wg.Done()
-			resp, err := agent.Process(ctx, message)
-			responses[idx] = resp
-			errors[idx] = err
+
+			resp, err := a.processBranch(ctx, slots, agent, message)
+			results[idx].Response = resp
+			results[idx].Err = err
+
+			if err != nil && a.failurePolicy == FailFast {
+				failOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
 		}(i, subAgent)
 	}
 
 	// Wait for all sub-agents to complete
 	wg.Wait()
 
-	// Check for errors
-	for _, err := range errors {
-		if err != nil {
-			return "", err
+	return results, firstErr
+}
+
+// processBranch runs a single sub-agent once a concurrency slot is available
+func (a *ParallelAgent) processBranch(ctx context.Context, slots chan struct{}, agent BaseAgent, message string) (string, error) {
+	release, err := acquireSlot(ctx, slots)
+	if err != nil {
+		return "", fmt.Errorf("branch %s was not started: %w", agent.Name(), err)
+	}
+	defer release()
+
+	branchCtx, cancel := a.branchContext(ctx)
+	defer cancel()
+
+	resp, err := ProcessMessage(branchCtx, agent, message)
+	if err != nil {
+		if errors.Is(branchCtx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("branch %s timed out after %s: %w", agent.Name(), a.branchTimeout, err)
 		}
+		return "", fmt.Errorf("branch %s failed: %w", agent.Name(), err)
 	}
 
-	// Combine responses
-	return strings.Join(responses, "\n\n"), nil
+	return resp, nil
+}
+
+// Run executes all sub-agents concurrently and forwards their events as they
+// arrive. Each sub-agent runs on its own branch, so siblings do not see each
+// other's events in their conversation history. The concurrency limit, branch
+// timeout and failure policy apply as they do for Process; a branch fails when
+// it cannot be started, emits an error event or times out.
+func (a *ParallelAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.beforeAgentCallback, a.afterAgentCallback, a.run)
+}
+
+// run starts one branch per sub-agent
+func (a *ParallelAgent) run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	eventCh := make(chan *events.Event)
+	ctx, span := telemetry.StartSpan(ctx, "ParallelAgent.Run")
+	span.SetAttribute("agent.name", a.name)
+
+	ctx, cancel := context.WithCancel(ctx)
+	invocationContext = invocationContext.WithAgent(a)
+	slots := a.newSlots()
+
+	var wg sync.WaitGroup
+	for _, subAgent := range a.subAgents {
+		wg.Add(1)
+		go func(agent BaseAgent) {
+			defer wg.Done()
+
+			if !a.runBranch(ctx, slots, invocationContext, agent, eventCh) && a.failurePolicy == FailFast {
+				span.SetAttribute("failed_branch", agent.Name())
+				cancel()
+			}
+		}(subAgent)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		span.End()
+		close(eventCh)
+	}()
+
+	return eventCh, nil
+}
+
+// runBranch runs a single sub-agent on its own branch and forwards its
+// events. It reports whether the branch succeeded.
+func (a *ParallelAgent) runBranch(ctx context.Context, slots chan struct{}, invocationContext *InvocationContext, agent BaseAgent, eventCh chan<- *events.Event) bool {
+	branch := subAgentBranch(invocationContext.Branch, a.name, agent.Name())
+	branchContext := invocationContext.WithBranch(branch)
+
+	release, err := acquireSlot(ctx, slots)
+	if err != nil {
+		eventCh <- subAgentErrorEvent(branchContext, a.name, agent, err)
+		return false
+	}
+	defer release()
+
+	branchCtx, cancel := a.branchContext(ctx)
+	defer cancel()
+
+	subEvents, err := agent.Run(branchCtx, branchContext)
+	if err != nil {
+		eventCh <- subAgentErrorEvent(branchContext, a.name, agent, err)
+		return false
+	}
+
+	succeeded := true
+	for event := range subEvents {
+		if event.ErrorCode != "" {
+			succeeded = false
+		}
+		eventCh <- event
+	}
+
+	if errors.Is(branchCtx.Err(), context.DeadlineExceeded) {
+		err := fmt.Errorf("timed out after %s: %w", a.branchTimeout, context.DeadlineExceeded)
+		eventCh <- subAgentErrorEvent(branchContext, a.name, agent, err)
+		return false
+	}
+
+	return succeeded
+}
+
+// branchContext derives the context for a single branch, applying the branch timeout
+func (a *ParallelAgent) branchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.branchTimeout > 0 {
+		return context.WithTimeout(ctx, a.branchTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// newSlots returns a semaphore limiting concurrent branches, or nil if unlimited
+func (a *ParallelAgent) newSlots() chan struct{} {
+	if a.maxConcurrency <= 0 {
+		return nil
+	}
+	return make(chan struct{}, a.maxConcurrency)
+}
+
+// acquireSlot waits for a free slot in the semaphore, or for ctx to be done.
+// A nil semaphore never blocks.
+func acquireSlot(ctx context.Context, slots chan struct{}) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// MaxConcurrency returns the maximum number of sub-agents run at once; 0 means no limit.
+func (a *ParallelAgent) MaxConcurrency() int {
+	return a.maxConcurrency
+}
+
+// FailurePolicy returns the failure policy of this parallel agent.
+func (a *ParallelAgent) FailurePolicy() FailurePolicy {
+	return a.failurePolicy
+}
+
+// BranchTimeout returns the per-branch timeout; 0 means no timeout.
+func (a *ParallelAgent) BranchTimeout() time.Duration {
+	return a.branchTimeout
+}
+
+// RunLive is not supported for parallel agents.
+func (a *ParallelAgent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return nil, fmt.Errorf("live mode is not supported by parallel agent %s", a.name)
 }
 
 // SubAgents returns the sub-agents of this parallel agent.
-func (a *ParallelAgent) SubAgents() []*Agent {
+func (a *ParallelAgent) SubAgents() []BaseAgent {
 	return a.subAgents
 }
+
+// RootAgent returns the root agent in the agent tree
+func (a *ParallelAgent) RootAgent() BaseAgent {
+	return rootAgent(a)
+}
+
+// FindAgent finds an agent by name in this agent and its descendants
+func (a *ParallelAgent) FindAgent(name string) BaseAgent {
+	return findAgent(a, name)
+}
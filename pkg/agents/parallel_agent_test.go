@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// blockingAgent is an agent whose run only ends when its context is done
+type blockingAgent struct {
+	stubAgent
+}
+
+func (a *blockingAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	eventCh := make(chan *events.Event)
+	go func() {
+		defer close(eventCh)
+		<-ctx.Done()
+	}()
+	return eventCh, nil
+}
+
+// failingAgent is an agent that cannot be started
+type failingAgent struct {
+	stubAgent
+}
+
+func (a *failingAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return nil, errors.New("no model")
+}
+
+// branchErrors runs a parallel agent and returns its error events
+func branchErrors(t *testing.T, parallel *ParallelAgent) []*events.Event {
+	t.Helper()
+
+	eventCh, err := parallel.Run(context.Background(), NewInvocationContext("invocation", parallel, nil))
+	var errorEvents []*events.Event
+	for _, event := range drain(t, eventCh, err) {
+		if event.ErrorCode != "" {
+			errorEvents = append(errorEvents, event)
+		}
+	}
+	return errorEvents
+}
+
+func TestParallelAgentBranchTimeoutErrorCode(t *testing.T) {
+	parallel := NewParallelAgent(ParallelAgentConfig{
+		Name:          "parallel",
+		SubAgents:     []BaseAgent{&blockingAgent{stubAgent{name: "slow"}}},
+		BranchTimeout: 10 * time.Millisecond,
+	})
+
+	errorEvents := branchErrors(t, parallel)
+	if len(errorEvents) != 1 {
+		t.Fatalf("got %d error events, want 1", len(errorEvents))
+	}
+	if code := errorEvents[0].ErrorCode; code != events.ErrorCodeDeadlineExceeded {
+		t.Errorf("error code = %q, want %q", code, events.ErrorCodeDeadlineExceeded)
+	}
+	if errorEvents[0].ErrorMessage == "" || errorEvents[0].Branch != "parallel.slow" {
+		t.Errorf("error event = %+v, want a message on branch parallel.slow", errorEvents[0])
+	}
+}
+
+func TestParallelAgentBranchStartErrorCode(t *testing.T) {
+	parallel := NewParallelAgent(ParallelAgentConfig{
+		Name:      "parallel",
+		SubAgents: []BaseAgent{&failingAgent{stubAgent{name: "broken"}}},
+	})
+
+	errorEvents := branchErrors(t, parallel)
+	if len(errorEvents) != 1 {
+		t.Fatalf("got %d error events, want 1", len(errorEvents))
+	}
+	if code := errorEvents[0].ErrorCode; code != events.ErrorCodeFlow {
+		t.Errorf("error code = %q, want %q", code, events.ErrorCodeFlow)
+	}
+}
@@ -15,34 +15,56 @@
 package agents
 
 import (
-	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"time"
+	"log"
 
+	"github.com/nvcnvn/adk-golang/pkg/a2a"
 	"github.com/nvcnvn/adk-golang/pkg/events"
 	"github.com/nvcnvn/adk-golang/pkg/models"
 	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 )
 
-// RemoteAgent represents an agent that runs remotely via HTTP API calls.
+// Error codes of the events a RemoteAgent emits when the remote task does not complete
+const (
+	RemoteAgentErrorCode   = "REMOTE_AGENT_ERROR"
+	RemoteTaskFailedCode   = "REMOTE_TASK_FAILED"
+	RemoteTaskCanceledCode = "REMOTE_TASK_CANCELED"
+	RemoteTaskRejectedCode = "REMOTE_TASK_REJECTED"
+)
+
+// RemoteAgent is an agent that runs remotely and is called with the
+// Agent2Agent (A2A) protocol. The agent card is fetched on first use; agents
+// that support streaming report progress as the task runs.
+//
+// The A2A context and any task waiting for input are kept in the session
+// state, so the next message of the conversation continues them.
 type RemoteAgent struct {
 	name        string
 	url         string
 	description string
-	httpClient  *http.Client
+	client      *a2a.Client
 	parentAgent BaseAgent
+
+	// BeforeAgentCallback is called before the remote agent is called and may skip it
+	BeforeAgentCallback BeforeAgentCallback
+
+	// AfterAgentCallback is called after the remote agent has responded and
+	// may add to or replace its final response
+	AfterAgentCallback AfterAgentCallback
 }
 
-// NewRemoteAgent creates a new remote agent
-func NewRemoteAgent(name, url, description string) *RemoteAgent {
+// NewRemoteAgent creates a new remote agent for the A2A agent at url. Options
+// configure the A2A client, such as a2a.WithBearerToken or a2a.WithAPIKey.
+func NewRemoteAgent(name, url, description string, options ...a2a.ClientOption) *RemoteAgent {
 	return &RemoteAgent{
 		name:        name,
 		url:         url,
 		description: description,
-		httpClient:  &http.Client{Timeout: 120 * time.Second},
+		client:      a2a.NewClient(url, options...),
 	}
 }
 
@@ -54,116 +76,365 @@ func (a *RemoteAgent) Name() string {
 // Description returns the description of the agent
 func (a *RemoteAgent) Description() string {
 	if a.description == "" {
-		return "Remote agent communicating via HTTP"
+		return "Remote agent communicating via the A2A protocol"
 	}
 	return a.description
 }
 
+// URL returns the URL of the remote agent
+func (a *RemoteAgent) URL() string {
+	return a.url
+}
+
+// AgentCard returns the agent card of the remote agent
+func (a *RemoteAgent) AgentCard(ctx context.Context) (*a2a.AgentCard, error) {
+	return a.client.AgentCard(ctx)
+}
+
 // Run executes the agent with the given invocation context
 func (a *RemoteAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.BeforeAgentCallback, a.AfterAgentCallback, a.run)
+}
+
+// run sends the user message to the remote agent and emits its answer as events
+func (a *RemoteAgent) run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
 	eventCh := make(chan *events.Event)
 
-	ctx, span := telemetry.StartSpan(ctx, "RemoteAgent.Run")
-	defer span.End()
+	go func() {
+		defer close(eventCh)
 
-	span.SetAttribute("agent.name", a.name)
-	span.SetAttribute("agent.url", a.url)
+		ctx, span := telemetry.StartSpan(ctx, "RemoteAgent.Run")
+		defer span.End()
 
-	data := map[string]interface{}{
-		"invocation_id": invocationContext.InvocationID,
-		"context": map[string]interface{}{
-			"invocation_id": invocationContext.InvocationID,
-			"agent_name":    a.name,
-		},
-	}
+		span.SetAttribute("agent.name", a.name)
+		span.SetAttribute("agent.url", a.url)
 
-	go func() {
-		defer close(eventCh)
+		task := &remoteTask{
+			agent:             a,
+			invocationContext: invocationContext,
+			eventCh:           eventCh,
+			contextID:         a.stateString(invocationContext, "context_id"),
+			taskID:            a.stateString(invocationContext, "task_id"),
+		}
 
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			span.SetAttribute("error", err.Error())
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
-					Parts: []*models.Part{
-						{Text: fmt.Sprintf("Error preparing request: %v", err)},
-					},
-				},
-			}
-			return
+		err := task.send(ctx)
+		var rpcErr *a2a.Error
+		if errors.As(err, &rpcErr) && rpcErr.Code == a2a.ErrorCodeTaskNotFound && task.taskID != "" {
+			// The pending task expired on the remote side; start a new one
+			task.taskID = ""
+			err = task.send(ctx)
 		}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewBuffer(jsonData))
 		if err != nil {
 			span.SetAttribute("error", err.Error())
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
-					Parts: []*models.Part{
-						{Text: fmt.Sprintf("Error creating request: %v", err)},
-					},
-				},
-			}
-			return
+			task.emitError(RemoteAgentErrorCode, fmt.Sprintf("Error communicating with remote agent: %v", err))
 		}
-		req.Header.Set("Content-Type", "application/json")
+		task.finish()
+	}()
 
-		resp, err := a.httpClient.Do(req)
-		if err != nil {
-			span.SetAttribute("error", err.Error())
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
-					Parts: []*models.Part{
-						{Text: fmt.Sprintf("Error communicating with remote agent: %v", err)},
-					},
-				},
-			}
-			return
+	return eventCh, nil
+}
+
+// stateKey returns the session state key holding a value of the A2A conversation
+func (a *RemoteAgent) stateKey(name string) string {
+	return "a2a:" + a.name + ":" + name
+}
+
+// stateString reads a string from the session state
+func (a *RemoteAgent) stateString(invocationContext *InvocationContext, name string) string {
+	value, _ := invocationContext.GetState(a.stateKey(name))
+	text, _ := value.(string)
+	return text
+}
+
+// remoteTask tracks one call to a remote agent and turns its answer into events
+type remoteTask struct {
+	agent             *RemoteAgent
+	invocationContext *InvocationContext
+	eventCh           chan<- *events.Event
+
+	contextID string
+	taskID    string
+	state     a2a.TaskState
+
+	// resumed is set while the stream of a continued task has not yet
+	// reported anything: its first task snapshot still has the status the
+	// task was waiting in, whose message was already emitted
+	resumed bool
+
+	// last is held back so the state changes can be attached to it
+	last *events.Event
+}
+
+// send sends the user message, streaming the answer if the agent supports it
+func (t *remoteTask) send(ctx context.Context) error {
+	card, err := t.agent.client.AgentCard(ctx)
+	if err != nil {
+		return err
+	}
+
+	message := &a2a.Message{
+		Kind:      a2a.KindMessage,
+		MessageID: t.invocationContext.InvocationID,
+		Role:      a2a.RoleUser,
+		Parts:     A2AParts(t.userContent()),
+		ContextID: t.contextID,
+		TaskID:    t.taskID,
+	}
+	params := &a2a.MessageSendParams{Message: message}
+
+	if card.Capabilities.Streaming {
+		t.resumed = t.taskID != ""
+		return t.agent.client.StreamMessage(ctx, params, func(result *a2a.Result) error {
+			t.handle(result)
+			t.resumed = false
+			return nil
+		})
+	}
+
+	params.Configuration = &a2a.MessageSendConfiguration{Blocking: true}
+	result, err := t.agent.client.SendMessage(ctx, params)
+	if err != nil {
+		return err
+	}
+	t.handle(result)
+	return nil
+}
+
+// userContent returns the message the agent was invoked with
+func (t *remoteTask) userContent() *models.Content {
+	if t.invocationContext.InvocationEvent != nil && t.invocationContext.InvocationEvent.Content != nil {
+		return t.invocationContext.InvocationEvent.Content
+	}
+
+	recorded := t.invocationContext.GetEvents()
+	for i := len(recorded) - 1; i >= 0; i-- {
+		if recorded[i].Author == "user" && recorded[i].Content != nil {
+			return recorded[i].Content
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			errMsg := fmt.Sprintf("Remote agent returned error status: %d", resp.StatusCode)
-			span.SetAttribute("error", errMsg)
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
-					Parts: []*models.Part{
-						{Text: errMsg},
-					},
-				},
-			}
+	}
+	return &models.Content{}
+}
+
+// handle turns a message, task or task update into events
+func (t *remoteTask) handle(result *a2a.Result) {
+	switch {
+	case result.Message != nil:
+		t.track(result.Message.ContextID, result.Message.TaskID)
+		t.emit(result.Message.Parts, false)
+
+	case result.Task != nil:
+		task := result.Task
+		if t.resumed && task.ID == t.taskID && task.Status.State.IsInterrupted() {
 			return
 		}
+		t.track(task.ContextID, task.ID)
+		for _, artifact := range task.Artifacts {
+			t.emit(artifact.Parts, false)
+		}
+		t.updateStatus(task.Status)
 
-		var responseEvents []*events.Event
-		if err := json.NewDecoder(resp.Body).Decode(&responseEvents); err != nil {
-			span.SetAttribute("error", err.Error())
-			eventCh <- &events.Event{
-				InvocationID: invocationContext.InvocationID,
-				Author:       a.name,
-				Content: &events.Content{
-					Parts: []*models.Part{
-						{Text: fmt.Sprintf("Error parsing response from remote agent: %v", err)},
-					},
-				},
+	case result.StatusUpdate != nil:
+		t.track(result.StatusUpdate.ContextID, result.StatusUpdate.TaskID)
+		t.updateStatus(result.StatusUpdate.Status)
+
+	case result.ArtifactUpdate != nil:
+		t.track(result.ArtifactUpdate.ContextID, result.ArtifactUpdate.TaskID)
+		t.emit(result.ArtifactUpdate.Artifact.Parts, false)
+	}
+}
+
+// track records the context and task the agent answered in
+func (t *remoteTask) track(contextID, taskID string) {
+	if contextID != "" {
+		t.contextID = contextID
+	}
+	if taskID != "" {
+		t.taskID = taskID
+	}
+}
+
+// updateStatus emits the message that comes with a task status
+func (t *remoteTask) updateStatus(status a2a.TaskStatus) {
+	t.state = status.State
+
+	var parts []a2a.Part
+	if status.Message != nil {
+		parts = status.Message.Parts
+	}
+
+	switch status.State {
+	case a2a.TaskStateSubmitted, a2a.TaskStateWorking:
+		// Progress reports are not part of the agent's answer
+		t.emit(parts, true)
+	case a2a.TaskStateInputRequired:
+		if len(parts) == 0 {
+			parts = []a2a.Part{a2a.TextPart("The remote agent needs more input to continue.")}
+		}
+		t.emit(parts, false)
+	case a2a.TaskStateAuthRequired:
+		if len(parts) == 0 {
+			parts = []a2a.Part{a2a.TextPart("The remote agent needs authorization to continue.")}
+		}
+		t.emit(parts, false)
+	case a2a.TaskStateFailed:
+		t.emitError(RemoteTaskFailedCode, statusText(status, "remote task failed"))
+	case a2a.TaskStateCanceled:
+		t.emitError(RemoteTaskCanceledCode, statusText(status, "remote task was canceled"))
+	case a2a.TaskStateRejected:
+		t.emitError(RemoteTaskRejectedCode, statusText(status, "remote agent rejected the task"))
+	default:
+		t.emit(parts, false)
+	}
+}
+
+// statusText returns the text of a status message, or fallback if it has none
+func statusText(status a2a.TaskStatus, fallback string) string {
+	if text := status.Message.Text(); text != "" {
+		return text
+	}
+	return fallback
+}
+
+// emit sends an event with the given content; empty content is skipped
+func (t *remoteTask) emit(parts []a2a.Part, partial bool) {
+	content := ContentFromA2A(parts, "assistant")
+	if len(content.Parts) == 0 {
+		return
+	}
+
+	event := t.newEvent()
+	event.Content = content
+	event.Partial = partial
+	t.queue(event)
+}
+
+// emitError sends an error event
+func (t *remoteTask) emitError(code, message string) {
+	event := t.newEvent()
+	event.ErrorCode = code
+	event.ErrorMessage = message
+	event.Content = &models.Content{
+		Parts: []*models.Part{{Text: message, Role: "assistant"}},
+	}
+	t.queue(event)
+}
+
+// newEvent creates an event authored by the remote agent
+func (t *remoteTask) newEvent() *events.Event {
+	event := events.NewEvent()
+	event.InvocationID = t.invocationContext.InvocationID
+	event.Author = t.agent.name
+	event.Branch = t.invocationContext.Branch
+	return event
+}
+
+// queue passes on the previous event and holds back this one
+func (t *remoteTask) queue(event *events.Event) {
+	if t.last != nil {
+		t.eventCh <- t.last
+	}
+	t.last = event
+}
+
+// finish emits the last event with the state of the A2A conversation: the
+// context to continue, and the task if it waits for input
+func (t *remoteTask) finish() {
+	event := t.last
+	t.last = nil
+	if event == nil || event.Partial {
+		// Partial events are not saved, so the state goes in an event of its own
+		if event != nil {
+			t.eventCh <- event
+		}
+		event = t.newEvent()
+	}
+
+	pendingTask := ""
+	if t.state.IsInterrupted() {
+		pendingTask = t.taskID
+	}
+
+	event.Actions.StateDelta[t.agent.stateKey("context_id")] = t.contextID
+	event.Actions.StateDelta[t.agent.stateKey("task_id")] = pendingTask
+	if t.state != "" {
+		event.Actions.StateDelta[t.agent.stateKey("task_state")] = string(t.state)
+	}
+	t.eventCh <- event
+}
+
+// A2AParts converts the text and files of ADK content into A2A message parts
+func A2AParts(content *models.Content) []a2a.Part {
+	parts := make([]a2a.Part, 0, len(content.Parts))
+	for _, part := range content.Parts {
+		switch {
+		case part.Text != "":
+			parts = append(parts, a2a.TextPart(part.Text))
+		case part.InlineData != nil:
+			parts = append(parts, a2a.Part{Kind: a2a.KindFile, File: &a2a.FileContent{
+				MimeType: part.InlineData.MimeType,
+				Bytes:    base64.StdEncoding.EncodeToString(part.InlineData.Data),
+			}})
+		case part.FileData != nil:
+			parts = append(parts, a2a.Part{Kind: a2a.KindFile, File: &a2a.FileContent{
+				MimeType: part.FileData.MimeType,
+				URI:      part.FileData.FileURI,
+			}})
+		}
+	}
+	return parts
+}
+
+// ContentFromA2A converts A2A message or artifact parts into ADK content with
+// the given role
+func ContentFromA2A(parts []a2a.Part, role string) *models.Content {
+	content := &models.Content{Parts: make([]*models.Part, 0, len(parts))}
+	for _, part := range parts {
+		if part.Kind == a2a.KindFile {
+			if filePart := fileFromA2A(part.File); filePart != nil {
+				filePart.Role = role
+				content.Parts = append(content.Parts, filePart)
 			}
-			return
+			continue
 		}
 
-		for _, event := range responseEvents {
-			event.Author = a.name
-			eventCh <- event
+		var text string
+		switch part.Kind {
+		case a2a.KindText:
+			text = part.Text
+		case a2a.KindData:
+			data, err := json.Marshal(part.Data)
+			if err != nil {
+				continue
+			}
+			text = string(data)
 		}
-	}()
+		if text != "" {
+			content.Parts = append(content.Parts, &models.Part{Text: text, Role: role})
+		}
+	}
+	return content
+}
 
-	return eventCh, nil
+// fileFromA2A converts an A2A file into an inline data or file data part. It
+// returns nil if the file has no valid content.
+func fileFromA2A(file *a2a.FileContent) *models.Part {
+	if file == nil {
+		return nil
+	}
+
+	if file.Bytes != "" {
+		data, err := base64.StdEncoding.DecodeString(file.Bytes)
+		if err != nil {
+			log.Printf("Invalid bytes in A2A file %s: %v", file.Name, err)
+			return nil
+		}
+		return models.NewInlineDataPart(data, file.MimeType)
+	}
+	if file.URI != "" {
+		return models.NewFileDataPart(file.URI, file.MimeType)
+	}
+	return nil
 }
 
 // RunLive executes the agent in live mode with the given invocation context
@@ -173,10 +444,7 @@ func (a *RemoteAgent) RunLive(ctx context.Context, invocationContext *Invocation
 
 // RootAgent returns the root agent in the agent tree
 func (a *RemoteAgent) RootAgent() BaseAgent {
-	if a.parentAgent == nil {
-		return a
-	}
-	return a.parentAgent.RootAgent()
+	return rootAgent(a)
 }
 
 // FindAgent finds an agent by name in the agent tree
@@ -187,6 +455,16 @@ func (a *RemoteAgent) FindAgent(name string) BaseAgent {
 	return nil
 }
 
+// SubAgents returns nil; the sub-agents of a remote agent are not visible locally
+func (a *RemoteAgent) SubAgents() []BaseAgent {
+	return nil
+}
+
+// ParentAgent returns the parent agent
+func (a *RemoteAgent) ParentAgent() BaseAgent {
+	return a.parentAgent
+}
+
 // SetParentAgent sets the parent agent
 func (a *RemoteAgent) SetParentAgent(parent BaseAgent) {
 	a.parentAgent = parent
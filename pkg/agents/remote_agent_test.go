@@ -0,0 +1,197 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nvcnvn/adk-golang/pkg/a2a"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+// scriptedExecutor is an A2A executor that runs a function and records the
+// tasks it ran
+type scriptedExecutor struct {
+	execute func(task *a2a.Task, updater *a2a.TaskUpdater) error
+	tasks   []string
+	mu      sync.Mutex
+}
+
+func (e *scriptedExecutor) Execute(ctx context.Context, task *a2a.Task, message *a2a.Message, updater *a2a.TaskUpdater) error {
+	e.mu.Lock()
+	e.tasks = append(e.tasks, task.ID)
+	e.mu.Unlock()
+	return e.execute(task, updater)
+}
+
+// newRemoteTestAgent returns a remote agent calling an A2A server run by
+// executor
+func newRemoteTestAgent(t *testing.T, executor a2a.Executor, streaming bool) *RemoteAgent {
+	t.Helper()
+
+	card := &a2a.AgentCard{Name: "remote", Capabilities: a2a.AgentCapabilities{Streaming: streaming}}
+	server := httptest.NewServer(a2a.NewServer(card, executor))
+	t.Cleanup(server.Close)
+	return NewRemoteAgent("remote", server.URL, "")
+}
+
+// runRemote sends a message to a remote agent in a session and records the
+// complete events in the session
+func runRemote(t *testing.T, agent *RemoteAgent, session *sessions.Session, text string) []*events.Event {
+	t.Helper()
+
+	invocationContext := NewInvocationContext(uuid.New().String(), agent, nil)
+	invocationContext.Session = session
+	userEvent := events.NewEvent()
+	userEvent.InvocationID = invocationContext.InvocationID
+	userEvent.Author = "user"
+	userEvent.Content = &models.Content{Role: models.RoleUser, Parts: []*models.Part{{Text: text}}}
+	invocationContext.InvocationEvent = userEvent
+	session.AddEvent(userEvent)
+
+	eventCh, err := agent.Run(context.Background(), invocationContext)
+	result := drain(t, eventCh, err)
+	for _, event := range result {
+		session.AddEvent(event)
+	}
+	return result
+}
+
+// answerText returns the text of the complete events of a run, and fails the
+// test on error events
+func answerText(t *testing.T, result []*events.Event) string {
+	t.Helper()
+
+	text := ""
+	for _, event := range result {
+		if event.ErrorCode != "" {
+			t.Fatalf("error event: %s %s", event.ErrorCode, event.ErrorMessage)
+		}
+		if !event.Partial && event.Content != nil {
+			text += event.Content.GetText()
+		}
+	}
+	return text
+}
+
+func TestRemoteAgentInterruptedTaskRoundTrip(t *testing.T) {
+	for _, state := range []a2a.TaskState{a2a.TaskStateInputRequired, a2a.TaskStateAuthRequired} {
+		for _, streaming := range []bool{false, true} {
+			name := string(state)
+			if streaming {
+				name += " streaming"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				executor := &scriptedExecutor{execute: func(task *a2a.Task, updater *a2a.TaskUpdater) error {
+					if len(task.History) == 1 {
+						return updater.UpdateStatus(state, a2a.NewTextMessage(a2a.RoleAgent, "Which account?"))
+					}
+					return updater.AddArtifact(a2a.Artifact{Parts: []a2a.Part{a2a.TextPart("Paid.")}})
+				}}
+				agent := newRemoteTestAgent(t, executor, streaming)
+				session := sessions.NewSession("app", "user", nil, "")
+
+				if text := answerText(t, runRemote(t, agent, session, "Pay the bill")); text != "Which account?" {
+					t.Errorf("first answer = %q, want the question", text)
+				}
+				if value, _ := session.GetState(agent.stateKey("task_state")); value != string(state) {
+					t.Errorf("task state = %v, want %s", value, state)
+				}
+				if value, _ := session.GetState(agent.stateKey("task_id")); value == "" {
+					t.Errorf("the interrupted task was not kept in the session")
+				}
+
+				if text := answerText(t, runRemote(t, agent, session, "The main account")); text != "Paid." {
+					t.Errorf("second answer = %q, want Paid.", text)
+				}
+				if len(executor.tasks) != 2 || executor.tasks[0] != executor.tasks[1] {
+					t.Errorf("tasks = %v, want the answer to continue the first task", executor.tasks)
+				}
+				if value, _ := session.GetState(agent.stateKey("task_id")); value != "" {
+					t.Errorf("task_id = %v after the task completed, want empty", value)
+				}
+			})
+		}
+	}
+}
+
+func TestRemoteAgentRestartsMissingTask(t *testing.T) {
+	executor := &scriptedExecutor{execute: func(task *a2a.Task, updater *a2a.TaskUpdater) error {
+		return updater.AddArtifact(a2a.Artifact{Parts: []a2a.Part{a2a.TextPart("Paid.")}})
+	}}
+	agent := newRemoteTestAgent(t, executor, false)
+
+	// The session refers to a task the remote agent no longer knows
+	session := sessions.NewSession("app", "user", map[string]interface{}{
+		agent.stateKey("context_id"): "context",
+		agent.stateKey("task_id"):    "context.expired",
+	}, "")
+
+	if text := answerText(t, runRemote(t, agent, session, "The main account")); text != "Paid." {
+		t.Errorf("answer = %q, want Paid.", text)
+	}
+	if len(executor.tasks) != 1 || executor.tasks[0] == "context.expired" {
+		t.Errorf("tasks = %v, want one new task", executor.tasks)
+	}
+	if a2a.TaskContextID(executor.tasks[0]) != "context" {
+		t.Errorf("new task %s is not in the session's context", executor.tasks[0])
+	}
+}
+
+func TestA2AFileParts(t *testing.T) {
+	content := &models.Content{Parts: []*models.Part{
+		{Text: "Chart this."},
+		models.NewInlineDataPart([]byte("a,b\n1,2"), "text/csv"),
+		models.NewFileDataPart("gs://bucket/report.pdf", "application/pdf"),
+	}}
+
+	parts := A2AParts(content)
+	if len(parts) != 3 || parts[1].Kind != a2a.KindFile || parts[2].Kind != a2a.KindFile {
+		t.Fatalf("A2A parts = %+v, want a text part and two file parts", parts)
+	}
+	if file := parts[1].File; file.Bytes != "YSxiCjEsMg==" || file.MimeType != "text/csv" || file.URI != "" {
+		t.Errorf("inline data sent as %+v", file)
+	}
+	if file := parts[2].File; file.URI != "gs://bucket/report.pdf" || file.MimeType != "application/pdf" || file.Bytes != "" {
+		t.Errorf("file data sent as %+v", file)
+	}
+
+	// Files without valid content are dropped
+	parts = append(parts,
+		a2a.Part{Kind: a2a.KindFile, File: &a2a.FileContent{Name: "broken", Bytes: "not base64!"}},
+		a2a.Part{Kind: a2a.KindFile},
+	)
+	received := ContentFromA2A(parts, models.RoleUser)
+	if len(received.Parts) != 3 {
+		t.Fatalf("received parts = %+v, want the text and two files", received.Parts)
+	}
+	for _, part := range received.Parts {
+		if part.Role != models.RoleUser {
+			t.Errorf("part %+v has role %q, want %q", part, part.Role, models.RoleUser)
+		}
+		part.Role = ""
+	}
+	if !reflect.DeepEqual(received.Parts, content.Parts) {
+		t.Errorf("received parts = %+v, want %+v", received.Parts, content.Parts)
+	}
+}
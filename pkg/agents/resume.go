@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// continuer is implemented by agents that can finish their turn after one of
+// their sub-agents resumed a paused invocation
+type continuer interface {
+	continueAfter(ctx context.Context, invocationContext *InvocationContext, subAgent BaseAgent) <-chan *events.Event
+
+	// continuesAfterFailure reports whether to continue although the resumed
+	// sub-agent's turn ended with the given error event
+	continuesAfterFailure(failure *events.Event) bool
+}
+
+// ResumeAgent runs the agent of an invocation that resumes a paused one, such
+// as the agent whose tool call the user just confirmed. Unless the invocation
+// ends again, or the agent fails and its parent's error policy stops there,
+// the agent's parents then continue from where they ran it: a SequentialAgent
+// runs the sub-agents after it. Parents that cannot continue,
+// such as LoopAgent, ParallelAgent and LlmAgent, end their turn at the pause,
+// and so do their own parents. The callbacks of the parents are not run again.
+//
+// For the root agent, ResumeAgent is the same as running the agent.
+func ResumeAgent(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	agent := invocationContext.Agent
+	agentEvents, err := agent.Run(ctx, invocationContext)
+	if err != nil {
+		return nil, err
+	}
+
+	eventCh := make(chan *events.Event)
+
+	go func() {
+		defer close(eventCh)
+		_, failure := forwardEvents(agentEvents, eventCh)
+
+		child := agent
+		for parent := child.ParentAgent(); parent != nil; child, parent = parent, parent.ParentAgent() {
+			continuing, ok := parent.(continuer)
+			if !ok || ctx.Err() != nil || invocationContext.IsEndInvocation() {
+				return
+			}
+			if failure != nil && !continuing.continuesAfterFailure(failure) {
+				return
+			}
+			_, failure = forwardEvents(continuing.continueAfter(ctx, invocationContext, child), eventCh)
+		}
+	}()
+
+	return eventCh, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResumeAgentContinuesSequentialParents(t *testing.T) {
+	first := &stubAgent{name: "first"}
+	second := &stubAgent{name: "second"}
+	third := &stubAgent{name: "third"}
+	inner := NewSequentialAgent(SequentialAgentConfig{Name: "inner", SubAgents: []BaseAgent{first, second}})
+	NewSequentialAgent(SequentialAgentConfig{Name: "outer", SubAgents: []BaseAgent{inner, third}})
+
+	eventCh, err := ResumeAgent(context.Background(), NewInvocationContext("invocation", first, nil))
+	var authors []string
+	for _, event := range drain(t, eventCh, err) {
+		authors = append(authors, event.Author)
+	}
+
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(authors, want) {
+		t.Errorf("authors = %v, want %v", authors, want)
+	}
+}
+
+func TestResumeAgentStopsWhenInvocationEndsAgain(t *testing.T) {
+	first := &stubAgent{name: "first", end: true}
+	second := &stubAgent{name: "second"}
+	NewSequentialAgent(SequentialAgentConfig{Name: "sequential", SubAgents: []BaseAgent{first, second}})
+
+	eventCh, err := ResumeAgent(context.Background(), NewInvocationContext("invocation", first, nil))
+	drain(t, eventCh, err)
+
+	if runs := atomic.LoadInt32(&second.runs); runs != 0 {
+		t.Errorf("second sub-agent ran %d times after the invocation paused again", runs)
+	}
+}
+
+func TestResumeAgentDoesNotContinueLoopAgent(t *testing.T) {
+	first := &stubAgent{name: "first"}
+	second := &stubAgent{name: "second"}
+	NewLoopAgent(LoopAgentConfig{Name: "loop", SubAgents: []BaseAgent{first, second}, MaxIterations: 3})
+
+	eventCh, err := ResumeAgent(context.Background(), NewInvocationContext("invocation", first, nil))
+	drain(t, eventCh, err)
+
+	if runs := atomic.LoadInt32(&first.runs); runs != 1 {
+		t.Errorf("resumed sub-agent ran %d times, want 1", runs)
+	}
+	if runs := atomic.LoadInt32(&second.runs); runs != 0 {
+		t.Errorf("loop agent continued after the resumed sub-agent: second ran %d times", runs)
+	}
+}
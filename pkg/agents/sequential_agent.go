@@ -17,41 +17,68 @@ package agents
 
 import (
 	"context"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 )
 
 // SequentialAgent runs its sub-agents in sequence, passing the output of one to the next.
 type SequentialAgent struct {
 	Agent
-	subAgents []*Agent
+	subAgents   []BaseAgent
+	errorPolicy ErrorPolicy
 }
 
 // SequentialAgentConfig holds configuration for creating a SequentialAgent.
 type SequentialAgentConfig struct {
 	Name        string
 	Description string
-	SubAgents   []*Agent
+	SubAgents   []BaseAgent
+
+	// BeforeAgentCallback is called before the agent runs and may skip it
+	BeforeAgentCallback BeforeAgentCallback
+
+	// AfterAgentCallback is called after the agent has run and may add to or
+	// replace its final response
+	AfterAgentCallback AfterAgentCallback
+
+	// ErrorPolicy, if set, decides whether the next sub-agent runs after one
+	// ended its turn with an error event; only ErrorActionContinue lets it
+	// run. By default the agent stops at the first failed sub-agent.
+	ErrorPolicy ErrorPolicy
 }
 
 // NewSequentialAgent creates a new agent that processes sub-agents in sequence.
 func NewSequentialAgent(config SequentialAgentConfig) *SequentialAgent {
-	return &SequentialAgent{
+	agent := &SequentialAgent{
 		Agent: Agent{
-			name:        config.Name,
-			description: config.Description,
+			name:                config.Name,
+			description:         config.Description,
+			beforeAgentCallback: config.BeforeAgentCallback,
+			afterAgentCallback:  config.AfterAgentCallback,
 		},
-		subAgents: config.SubAgents,
+		subAgents:   config.SubAgents,
+		errorPolicy: config.ErrorPolicy,
 	}
+
+	adoptSubAgents(agent, config.SubAgents)
+	return agent
 }
 
 // Process handles a message by passing it through each sub-agent in sequence.
 func (a *SequentialAgent) Process(ctx context.Context, message string) (string, error) {
+	return processWithAgentCallbacks(ctx, a, a.beforeAgentCallback, a.afterAgentCallback, message, a.process)
+}
+
+// process passes the message through the sub-agents
+func (a *SequentialAgent) process(ctx context.Context, message string) (string, error) {
 	currentMessage := message
 	var response string
 	var err error
 
 	// Process through each sub-agent in sequence
 	for _, subAgent := range a.subAgents {
-		currentMessage, err = subAgent.Process(ctx, currentMessage)
+		currentMessage, err = ProcessMessage(ctx, subAgent, currentMessage)
 		if err != nil {
 			return "", err
 		}
@@ -61,7 +88,104 @@ func (a *SequentialAgent) Process(ctx context.Context, message string) (string,
 	return response, nil
 }
 
+// Run executes each sub-agent in turn on the shared invocation context,
+// forwarding every event they produce.
+func (a *SequentialAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.beforeAgentCallback, a.afterAgentCallback,
+		func(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+			return a.run(ctx, invocationContext, false)
+		})
+}
+
+// RunLive executes each sub-agent in turn in live mode.
+func (a *SequentialAgent) RunLive(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	return runWithAgentCallbacks(ctx, invocationContext.WithAgent(a), a.name, a.beforeAgentCallback, a.afterAgentCallback,
+		func(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+			return a.run(ctx, invocationContext, true)
+		})
+}
+
+func (a *SequentialAgent) run(ctx context.Context, invocationContext *InvocationContext, live bool) (<-chan *events.Event, error) {
+	return a.runSubAgents(ctx, invocationContext, a.subAgents, live), nil
+}
+
+// continueAfter runs the sub-agents that come after subAgent, for an
+// invocation resuming subAgent's paused turn
+func (a *SequentialAgent) continueAfter(ctx context.Context, invocationContext *InvocationContext, subAgent BaseAgent) <-chan *events.Event {
+	var rest []BaseAgent
+	for i, candidate := range a.subAgents {
+		if candidate == subAgent {
+			rest = a.subAgents[i+1:]
+			break
+		}
+	}
+	return a.runSubAgents(ctx, invocationContext, rest, false)
+}
+
+// continuesAfterFailure reports whether the sub-agents after a resumed one
+// run although its turn ended with the given error event
+func (a *SequentialAgent) continuesAfterFailure(failure *events.Event) bool {
+	return continueAfterFailure(a.errorPolicy, failure)
+}
+
+// runSubAgents runs the given sub-agents in turn and forwards their events
+func (a *SequentialAgent) runSubAgents(ctx context.Context, invocationContext *InvocationContext, subAgents []BaseAgent, live bool) <-chan *events.Event {
+	eventCh := make(chan *events.Event)
+	ctx, span := telemetry.StartSpan(ctx, "SequentialAgent.Run")
+	span.SetAttribute("agent.name", a.name)
+
+	invocationContext = invocationContext.WithAgent(a)
+
+	go func() {
+		defer close(eventCh)
+		defer span.End()
+
+		for _, subAgent := range subAgents {
+			if invocationContext.IsEndInvocation() {
+				return
+			}
+			if ctx.Err() != nil {
+				eventCh <- stopEvent(ctx, invocationContext, a.name)
+				return
+			}
+
+			var subEvents <-chan *events.Event
+			var err error
+			if live {
+				subEvents, err = subAgent.RunLive(ctx, invocationContext)
+			} else {
+				subEvents, err = subAgent.Run(ctx, invocationContext)
+			}
+			if err != nil {
+				span.SetAttribute("error", err.Error())
+				eventCh <- subAgentErrorEvent(invocationContext, a.name, subAgent, err)
+				return
+			}
+
+			// A failure caused by the context already reported why it stopped
+			if _, failure := forwardEvents(subEvents, eventCh); failure != nil {
+				if ctx.Err() != nil || !continueAfterFailure(a.errorPolicy, failure) {
+					span.SetAttribute("failed_sub_agent", subAgent.Name())
+					return
+				}
+			}
+		}
+	}()
+
+	return eventCh
+}
+
 // SubAgents returns the sub-agents of this sequential agent.
-func (a *SequentialAgent) SubAgents() []*Agent {
+func (a *SequentialAgent) SubAgents() []BaseAgent {
 	return a.subAgents
 }
+
+// RootAgent returns the root agent in the agent tree
+func (a *SequentialAgent) RootAgent() BaseAgent {
+	return rootAgent(a)
+}
+
+// FindAgent finds an agent by name in this agent and its descendants
+func (a *SequentialAgent) FindAgent(name string) BaseAgent {
+	return findAgent(a, name)
+}
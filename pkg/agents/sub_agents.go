@@ -0,0 +1,148 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// messageProcessor is implemented by agents that support the string-in/string-out API
+type messageProcessor interface {
+	Process(ctx context.Context, message string) (string, error)
+}
+
+// ProcessMessage sends a message to an agent and returns its reply.
+// Agents without a Process method are run through Run on a fresh invocation,
+// and the text of their last final response is returned.
+func ProcessMessage(ctx context.Context, agent BaseAgent, message string) (string, error) {
+	if processor, ok := agent.(messageProcessor); ok {
+		return processor.Process(ctx, message)
+	}
+
+	invocationContext := newMessageInvocationContext(agent, message)
+	eventCh, err := agent.Run(ctx, invocationContext)
+	if err != nil {
+		return "", fmt.Errorf("failed to run agent %s: %w", agent.Name(), err)
+	}
+
+	var response string
+	for event := range eventCh {
+		if event.ErrorCode != "" {
+			return "", fmt.Errorf("agent %s failed: %s", agent.Name(), event.ErrorMessage)
+		}
+		if event.IsFinalResponse() && event.Content != nil {
+			if text := event.Content.GetText(); text != "" {
+				response = text
+			}
+		}
+	}
+
+	return response, nil
+}
+
+// newMessageInvocationContext creates a fresh invocation of an agent for a user message
+func newMessageInvocationContext(agent BaseAgent, message string) *InvocationContext {
+	invocationContext := NewInvocationContext(uuid.New().String(), agent, nil)
+	invocationEvent := events.NewEvent()
+	invocationEvent.InvocationID = invocationContext.InvocationID
+	invocationEvent.Author = "user"
+	invocationEvent.Content = &models.Content{
+		Parts: []*models.Part{{Text: message, Role: "user"}},
+	}
+	invocationContext.InvocationEvent = invocationEvent
+	return invocationContext
+}
+
+// forwardEvents sends every event from a sub-agent to the parent's channel.
+// It reports whether any of the events asked to escalate, and returns the last
+// event if it is an error event, meaning that the sub-agent's turn failed.
+func forwardEvents(from <-chan *events.Event, to chan<- *events.Event) (bool, *events.Event) {
+	escalated := false
+	var failure *events.Event
+	for event := range from {
+		if event.Actions != nil && event.Actions.Escalate {
+			escalated = true
+		}
+		failure = nil
+		if event.ErrorCode != "" {
+			failure = event
+		}
+		to <- event
+	}
+	return escalated, failure
+}
+
+// continueAfterFailure reports whether a composite agent runs its next
+// sub-agent after one whose turn ended with the given error event. It only
+// does if its error policy says to continue: a failed turn cannot be retried,
+// and without a policy the agent stops.
+func continueAfterFailure(policy ErrorPolicy, failure *events.Event) bool {
+	if failure == nil {
+		return true
+	}
+	if policy == nil {
+		return false
+	}
+	err := events.NewError(failure.ErrorCode, errors.New(failure.ErrorMessage))
+	return policy(err, 0) == ErrorActionContinue
+}
+
+// stopEvent creates the error event of a composite agent whose context was
+// done before it could run its next sub-agent. Its code is
+// events.ErrorCodeCancelled or events.ErrorCodeDeadlineExceeded.
+func stopEvent(ctx context.Context, invocationContext *InvocationContext, author string) *events.Event {
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = author
+	event.Branch = invocationContext.Branch
+	cause := context.Cause(ctx)
+	event.ErrorCode = events.ContextErrorCode(cause)
+	if event.ErrorCode == "" {
+		event.ErrorCode = events.ContextErrorCode(ctx.Err())
+	}
+	event.ErrorMessage = cause.Error()
+	return event
+}
+
+// subAgentErrorEvent creates an error event reporting that a sub-agent could
+// not be run or did not finish. The error code is the code of err, see
+// events.ErrorCodeOf, or events.ErrorCodeFlow.
+func subAgentErrorEvent(invocationContext *InvocationContext, author string, subAgent BaseAgent, err error) *events.Event {
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = author
+	event.Branch = invocationContext.Branch
+	event.ErrorCode = events.ErrorCodeOf(err)
+	if event.ErrorCode == "" {
+		event.ErrorCode = events.ErrorCodeFlow
+	}
+	event.ErrorMessage = fmt.Sprintf("Error running sub-agent %s: %v", subAgent.Name(), err)
+	return event
+}
+
+// subAgentBranch returns the branch name for a sub-agent of a parallel agent
+func subAgentBranch(parentBranch, agentName, subAgentName string) string {
+	branch := agentName + "." + subAgentName
+	if parentBranch != "" {
+		branch = parentBranch + "." + branch
+	}
+	return branch
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// erroringAgent is an agent whose turn ends with an error event
+type erroringAgent struct {
+	stubAgent
+}
+
+func (a *erroringAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	atomic.AddInt32(&a.runs, 1)
+
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = a.name
+	event.ErrorCode = events.ErrorCodeTool
+	event.ErrorMessage = "the tool failed"
+
+	eventCh := make(chan *events.Event, 1)
+	eventCh <- event
+	close(eventCh)
+	return eventCh, nil
+}
+
+// cancellingAgent is an agent that cancels the invocation and then answers
+type cancellingAgent struct {
+	stubAgent
+	cancel context.CancelFunc
+}
+
+func (a *cancellingAgent) Run(ctx context.Context, invocationContext *InvocationContext) (<-chan *events.Event, error) {
+	a.cancel()
+	return a.stubAgent.Run(ctx, invocationContext)
+}
+
+// compositeAgents builds a sequential and a loop agent running sub-agents once
+var compositeAgents = map[string]func(subAgents []BaseAgent, policy ErrorPolicy) BaseAgent{
+	"sequential": func(subAgents []BaseAgent, policy ErrorPolicy) BaseAgent {
+		return NewSequentialAgent(SequentialAgentConfig{Name: "composite", SubAgents: subAgents, ErrorPolicy: policy})
+	},
+	"loop": func(subAgents []BaseAgent, policy ErrorPolicy) BaseAgent {
+		return NewLoopAgent(LoopAgentConfig{Name: "composite", SubAgents: subAgents, MaxIterations: 1, ErrorPolicy: policy})
+	},
+}
+
+func TestCompositeAgentStopsWhenContextIsDone(t *testing.T) {
+	for kind, build := range compositeAgents {
+		t.Run(kind+"/cancelled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			next := &stubAgent{name: "next"}
+			agent := build([]BaseAgent{&cancellingAgent{stubAgent{name: "first"}, cancel}, next}, nil)
+			eventCh, err := agent.Run(ctx, NewInvocationContext("invocation", agent, nil))
+			result := drain(t, eventCh, err)
+
+			if runs := atomic.LoadInt32(&next.runs); runs != 0 {
+				t.Errorf("next sub-agent ran %d times after the cancellation", runs)
+			}
+			last := result[len(result)-1]
+			if last.Author != "composite" || last.ErrorCode != events.ErrorCodeCancelled {
+				t.Errorf("last event = %s %s by %s, want %s by composite", last.ErrorCode, last.ErrorMessage, last.Author, events.ErrorCodeCancelled)
+			}
+		})
+
+		t.Run(kind+"/deadline exceeded", func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			next := &stubAgent{name: "next"}
+			agent := build([]BaseAgent{&blockingAgent{stubAgent{name: "slow"}}, next}, nil)
+			eventCh, err := agent.Run(ctx, NewInvocationContext("invocation", agent, nil))
+			result := drain(t, eventCh, err)
+
+			if runs := atomic.LoadInt32(&next.runs); runs != 0 {
+				t.Errorf("next sub-agent ran %d times after the deadline", runs)
+			}
+			if len(result) != 1 || result[0].ErrorCode != events.ErrorCodeDeadlineExceeded {
+				t.Fatalf("events = %+v, want one %s event", result, events.ErrorCodeDeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestCompositeAgentErrorPolicy(t *testing.T) {
+	policies := []struct {
+		name     string
+		policy   ErrorPolicy
+		wantRuns int32
+	}{
+		{name: "no policy stops", wantRuns: 0},
+		{
+			name: "abort stops",
+			policy: func(err *events.Error, attempt int) ErrorAction {
+				return ErrorActionAbort
+			},
+			wantRuns: 0,
+		},
+		{
+			name: "continue runs the next sub-agent",
+			policy: func(err *events.Error, attempt int) ErrorAction {
+				if err.Code != events.ErrorCodeTool {
+					t.Errorf("policy got code %s, want %s", err.Code, events.ErrorCodeTool)
+				}
+				return ErrorActionContinue
+			},
+			wantRuns: 1,
+		},
+	}
+
+	for kind, build := range compositeAgents {
+		for _, test := range policies {
+			t.Run(kind+"/"+test.name, func(t *testing.T) {
+				next := &stubAgent{name: "next"}
+				agent := build([]BaseAgent{&erroringAgent{stubAgent{name: "failing"}}, next}, test.policy)
+				eventCh, err := agent.Run(context.Background(), NewInvocationContext("invocation", agent, nil))
+				drain(t, eventCh, err)
+
+				if runs := atomic.LoadInt32(&next.runs); runs != test.wantRuns {
+					t.Errorf("next sub-agent ran %d times, want %d", runs, test.wantRuns)
+				}
+			})
+		}
+	}
+}
+
+func TestResumeAgentStopsAfterFailure(t *testing.T) {
+	failing := &erroringAgent{stubAgent{name: "failing"}}
+	next := &stubAgent{name: "next"}
+	NewSequentialAgent(SequentialAgentConfig{Name: "sequential", SubAgents: []BaseAgent{failing, next}})
+
+	eventCh, err := ResumeAgent(context.Background(), NewInvocationContext("invocation", failing, nil))
+	drain(t, eventCh, err)
+
+	if runs := atomic.LoadInt32(&next.runs); runs != 0 {
+		t.Errorf("next sub-agent ran %d times after the resumed one failed", runs)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agents
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// UsageStateKey is the session state key holding the total usage of the
+// session's model calls. Each model response updates it.
+const UsageStateKey = "adk:usage"
+
+// SessionUsage returns the total usage of a session's model calls recorded
+// in its state
+func SessionUsage(session *sessions.Session) types.Usage {
+	if session == nil || session.State == nil {
+		return types.Usage{}
+	}
+	value, ok := session.GetState(UsageStateKey)
+	if !ok {
+		return types.Usage{}
+	}
+	return usageFromState(value)
+}
+
+// usageFromState decodes a usage stored in the state. Values read back from
+// a persistent session service are plain JSON objects.
+func usageFromState(value interface{}) types.Usage {
+	if usage, ok := value.(types.Usage); ok {
+		return usage
+	}
+
+	var usage types.Usage
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(data, &usage)
+	}
+	if err != nil {
+		log.Printf("Invalid usage in session state: %v", err)
+	}
+	return usage
+}
@@ -17,6 +17,9 @@ package artifacts
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
 )
 
 // Part represents an artifact part, similar to google.genai.types.Part in Python.
@@ -49,6 +52,29 @@ func FromBytes(data []byte, mimeType string) Part {
 	}
 }
 
+// FromModelPart creates a Part from a text or inline data part of model
+// content. File references cannot be stored as artifacts.
+func FromModelPart(part *models.Part) (Part, error) {
+	switch {
+	case part == nil:
+		return Part{}, fmt.Errorf("part is nil")
+	case part.InlineData != nil:
+		return FromBytes(part.InlineData.Data, part.InlineData.MimeType), nil
+	case part.Text != "":
+		return FromText(part.Text, "text/plain"), nil
+	}
+	return Part{}, fmt.Errorf("part has no text or inline data")
+}
+
+// ToModelPart converts the artifact into a part of model content. Binary
+// artifacts become inline data.
+func (p Part) ToModelPart() *models.Part {
+	if p.Data != nil {
+		return models.NewInlineDataPart(p.Data, p.MimeType)
+	}
+	return &models.Part{Text: p.Text}
+}
+
 // ArtifactService defines the interface for artifact services.
 type ArtifactService interface {
 	// SaveArtifact saves an artifact to the artifact service storage.
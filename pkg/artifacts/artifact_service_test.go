@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+func TestModelPartConversion(t *testing.T) {
+	tests := []struct {
+		name     string
+		part     *models.Part
+		want     Part
+		wantBack *models.Part
+		wantErr  string
+	}{
+		{
+			name:     "text",
+			part:     &models.Part{Text: "Notes"},
+			want:     Part{Text: "Notes", MimeType: "text/plain"},
+			wantBack: &models.Part{Text: "Notes"},
+		},
+		{
+			name:     "inline data",
+			part:     models.NewInlineDataPart([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+			want:     Part{Data: []byte{0x89, 'P', 'N', 'G'}, MimeType: "image/png"},
+			wantBack: models.NewInlineDataPart([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+		},
+		{
+			name:    "file reference",
+			part:    models.NewFileDataPart("gs://bucket/report.pdf", "application/pdf"),
+			wantErr: "no text or inline data",
+		},
+		{
+			name:    "nil part",
+			wantErr: "part is nil",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			artifact, err := FromModelPart(test.part)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("FromModelPart error = %v, want it to mention %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromModelPart: %v", err)
+			}
+			if !reflect.DeepEqual(artifact, test.want) {
+				t.Errorf("artifact = %+v, want %+v", artifact, test.want)
+			}
+			if back := artifact.ToModelPart(); !reflect.DeepEqual(back, test.wantBack) {
+				t.Errorf("ToModelPart = %+v, want %+v", back, test.wantBack)
+			}
+		})
+	}
+}
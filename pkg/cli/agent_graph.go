@@ -0,0 +1,366 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/tools/mcp_tool"
+	"github.com/nvcnvn/adk-golang/pkg/tools/openapi_tool/openapi_spec_parser"
+)
+
+// Graph output formats
+const (
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+	graphFormatJSON    = "json"
+)
+
+// Node kinds of an agent graph
+const (
+	nodeLlmAgent        = "llm_agent"
+	nodeSequentialAgent = "sequential_agent"
+	nodeParallelAgent   = "parallel_agent"
+	nodeLoopAgent       = "loop_agent"
+	nodeRemoteAgent     = "remote_agent"
+	nodeAgent           = "agent"
+	nodeTool            = "tool"
+	nodeToolset         = "toolset"
+)
+
+// Edge kinds of an agent graph
+const (
+	edgeSubAgent  = "sub_agent"
+	edgeTool      = "tool"
+	edgeAgentTool = "agent_tool"
+	edgeTransfer  = "transfer"
+)
+
+// graphNode is an agent, tool or toolset in an agent graph
+type graphNode struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// graphEdge connects two nodes of an agent graph
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// agentGraph is the hierarchy of an agent with its tools and transfer paths
+type agentGraph struct {
+	Root  string      `json:"root"`
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+
+	agentIDs map[agents.BaseAgent]string
+	order    []agents.BaseAgent
+}
+
+// buildAgentGraph builds the graph of an agent tree. Agents wrapped in an
+// AgentTool are added to the graph with their own sub-trees.
+func buildAgentGraph(root agents.BaseAgent) *agentGraph {
+	g := &agentGraph{agentIDs: make(map[agents.BaseAgent]string)}
+	g.Root = g.addAgent(root)
+
+	// Transfer edges are added last, once every target has a node
+	for _, agent := range g.order {
+		llmAgent, ok := agent.(*agents.LlmAgent)
+		if !ok {
+			continue
+		}
+		for _, target := range llmAgent.TransferTargets() {
+			if targetID, ok := g.agentIDs[target]; ok {
+				g.addEdge(g.agentIDs[agent], targetID, edgeTransfer)
+			}
+		}
+	}
+
+	return g
+}
+
+// addAgent adds an agent, its tools and its sub-agents, and returns its node ID
+func (g *agentGraph) addAgent(agent agents.BaseAgent) string {
+	if id, ok := g.agentIDs[agent]; ok {
+		return id
+	}
+
+	id := g.addNode(agent.Name(), agentKind(agent), agentDescription(agent), agentDetail(agent))
+	g.agentIDs[agent] = id
+	g.order = append(g.order, agent)
+
+	g.addTools(id, agents.AgentTools(agent))
+
+	for _, subAgent := range agent.SubAgents() {
+		g.addEdge(id, g.addAgent(subAgent), edgeSubAgent)
+	}
+	return id
+}
+
+// addTools adds the tools of an agent. Tools generated from an OpenAPI spec or
+// loaded from an MCP server are grouped under a toolset node.
+func (g *agentGraph) addTools(agentID string, agentTools []tools.Tool) {
+	toolsets := make(map[string]string)
+
+	for _, tool := range agentTools {
+		if agentTool, ok := tool.(*tools.AgentTool); ok {
+			if wrapped, ok := agentTool.Agent().(agents.BaseAgent); ok {
+				g.addEdge(agentID, g.addAgent(wrapped), edgeAgentTool)
+				continue
+			}
+		}
+
+		parentID := agentID
+		if toolset := toolsetName(tool); toolset != "" {
+			if _, ok := toolsets[toolset]; !ok {
+				toolsets[toolset] = g.addNode(toolset, nodeToolset, "", "")
+				g.addEdge(agentID, toolsets[toolset], edgeTool)
+			}
+			parentID = toolsets[toolset]
+		}
+
+		g.addEdge(parentID, g.addNode(tool.Name(), nodeTool, tool.Description(), ""), edgeTool)
+	}
+}
+
+// addNode adds a node and returns its ID
+func (g *agentGraph) addNode(name, kind, description, detail string) string {
+	id := fmt.Sprintf("n%d", len(g.Nodes))
+	g.Nodes = append(g.Nodes, graphNode{
+		ID:          id,
+		Name:        name,
+		Kind:        kind,
+		Description: description,
+		Detail:      detail,
+	})
+	return id
+}
+
+// addEdge adds an edge
+func (g *agentGraph) addEdge(from, to, kind string) {
+	g.Edges = append(g.Edges, graphEdge{From: from, To: to, Kind: kind})
+}
+
+// agentKind returns the node kind of an agent
+func agentKind(agent agents.BaseAgent) string {
+	switch agent.(type) {
+	case *agents.LlmAgent:
+		return nodeLlmAgent
+	case *agents.SequentialAgent:
+		return nodeSequentialAgent
+	case *agents.ParallelAgent:
+		return nodeParallelAgent
+	case *agents.LoopAgent:
+		return nodeLoopAgent
+	case *agents.RemoteAgent:
+		return nodeRemoteAgent
+	}
+	return nodeAgent
+}
+
+// agentDescription returns the description of an agent, if it has one
+func agentDescription(agent agents.BaseAgent) string {
+	if described, ok := agent.(interface{ Description() string }); ok {
+		return described.Description()
+	}
+	return ""
+}
+
+// agentDetail returns a short summary of the agent's configuration
+func agentDetail(agent agents.BaseAgent) string {
+	switch a := agent.(type) {
+	case *agents.LoopAgent:
+		return fmt.Sprintf("max %d iterations", a.MaxIterations())
+	case *agents.ParallelAgent:
+		if a.MaxConcurrency() > 0 {
+			return fmt.Sprintf("max %d concurrent", a.MaxConcurrency())
+		}
+	case *agents.RemoteAgent:
+		return a.URL()
+	case *agents.Agent:
+		return a.Model()
+	}
+	return ""
+}
+
+// toolsetName returns the name of the toolset a tool was loaded from, or ""
+// for a standalone tool
+func toolsetName(tool tools.Tool) string {
+	switch tool.(type) {
+	case *openapi_spec_parser.RestApiTool:
+		return "OpenAPI toolset"
+	case *mcp_tool.McpTool:
+		return "MCP toolset"
+	}
+	return ""
+}
+
+// checkGraphFormat checks that a graph format is supported
+func checkGraphFormat(format string) error {
+	switch strings.ToLower(format) {
+	case graphFormatDOT, graphFormatMermaid, graphFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unsupported graph format %s, use %s, %s or %s", format, graphFormatDOT, graphFormatMermaid, graphFormatJSON)
+}
+
+// writeAgentGraph renders the graph of an agent to a file, or to stdout if
+// output is empty
+func writeAgentGraph(agent agents.BaseAgent, format, output string) error {
+	graph, err := buildAgentGraph(agent).render(format)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = fmt.Print(graph)
+		return err
+	}
+	if err := os.WriteFile(output, []byte(graph), 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	return nil
+}
+
+// render renders the graph in the given format
+func (g *agentGraph) render(format string) (string, error) {
+	if err := checkGraphFormat(format); err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(format) {
+	case graphFormatMermaid:
+		return g.mermaid(), nil
+	case graphFormatJSON:
+		data, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+	return g.dot(), nil
+}
+
+// label returns the text shown for a node
+func (n graphNode) label() string {
+	label := n.Name
+	switch n.Kind {
+	case nodeTool, nodeToolset, nodeAgent:
+	default:
+		label += "\n" + strings.TrimSuffix(n.Kind, "_agent")
+	}
+	if n.Detail != "" {
+		label += "\n" + n.Detail
+	}
+	return label
+}
+
+// dotShapes maps node kinds to Graphviz shapes
+var dotShapes = map[string]string{
+	nodeLlmAgent:        "ellipse",
+	nodeSequentialAgent: "box",
+	nodeParallelAgent:   "parallelogram",
+	nodeLoopAgent:       "doubleoctagon",
+	nodeRemoteAgent:     "cylinder",
+	nodeAgent:           "ellipse",
+	nodeTool:            "note",
+	nodeToolset:         "folder",
+}
+
+// dotEdgeStyles maps edge kinds to Graphviz edge attributes
+var dotEdgeStyles = map[string]string{
+	edgeSubAgent:  "",
+	edgeTool:      ` [arrowhead=none]`,
+	edgeAgentTool: ` [style=bold, label="as tool"]`,
+	edgeTransfer:  ` [style=dashed, color=gray, label="transfer"]`,
+}
+
+// dot renders the graph in Graphviz DOT
+func (g *agentGraph) dot() string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph agents {\n")
+	sb.WriteString("  rankdir=TB;\n")
+	for _, node := range g.Nodes {
+		sb.WriteString(fmt.Sprintf("  %s [label=%s, shape=%s];\n", node.ID, dotQuote(node.label()), dotShapes[node.Kind]))
+	}
+	for _, edge := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %s -> %s%s;\n", edge.From, edge.To, dotEdgeStyles[edge.Kind]))
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// dotQuote quotes a string for DOT
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// mermaidShapes maps node kinds to the brackets of Mermaid node shapes
+var mermaidShapes = map[string][2]string{
+	nodeLlmAgent:        {"([", "])"},
+	nodeSequentialAgent: {"[", "]"},
+	nodeParallelAgent:   {"[/", "/]"},
+	nodeLoopAgent:       {"{{", "}}"},
+	nodeRemoteAgent:     {"[(", ")]"},
+	nodeAgent:           {"([", "])"},
+	nodeTool:            {"[", "]"},
+	nodeToolset:         {"[[", "]]"},
+}
+
+// mermaidArrows maps edge kinds to Mermaid links
+var mermaidArrows = map[string]string{
+	edgeSubAgent:  "-->",
+	edgeTool:      "---",
+	edgeAgentTool: "==>|as tool|",
+	edgeTransfer:  "-.->|transfer|",
+}
+
+// mermaid renders the graph as a Mermaid flowchart
+func (g *agentGraph) mermaid() string {
+	var sb strings.Builder
+
+	sb.WriteString("flowchart TD\n")
+	for _, node := range g.Nodes {
+		shape := mermaidShapes[node.Kind]
+		sb.WriteString(fmt.Sprintf("  %s%s%s%s\n", node.ID, shape[0], mermaidQuote(node.label()), shape[1]))
+	}
+	for _, edge := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %s %s %s\n", edge.From, mermaidArrows[edge.Kind], edge.To))
+	}
+
+	return sb.String()
+}
+
+// mermaidQuote quotes a node label for Mermaid
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/agents/agent_config"
+	"github.com/nvcnvn/adk-golang/pkg/runners"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+// isAgentDefinition reports whether a path is a YAML agent definition or a
+// directory holding a root_agent.yaml file
+func isAgentDefinition(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err := os.Stat(filepath.Join(path, agent_config.RootAgentFile))
+		return err == nil
+	}
+	return agent_config.IsAgentConfigFile(path)
+}
+
+// loadAgentDefinitions loads the agents served by the web and API servers.
+// agentsDir may be a YAML definition, a directory holding root_agent.yaml, or
+// a directory whose sub-directories each hold a root_agent.yaml. Agents are
+// keyed by app name: the directory name, or the agent name for a single file.
+// Definitions that fail to load are logged and skipped.
+func loadAgentDefinitions(agentsDir string) map[string]agents.BaseAgent {
+	loaded := make(map[string]agents.BaseAgent)
+
+	if isAgentDefinition(agentsDir) {
+		agent, err := agent_config.LoadFile(agentsDir)
+		if err != nil {
+			log.Printf("Failed to load agent from %s: %v", agentsDir, err)
+			return loaded
+		}
+		loaded[agent.Name()] = agent
+		return loaded
+	}
+
+	entries, err := os.ReadDir(agentsDir)
+	if err != nil {
+		log.Printf("Failed to read agents directory %s: %v", agentsDir, err)
+		return loaded
+	}
+
+	for _, entry := range entries {
+		appDir := filepath.Join(agentsDir, entry.Name())
+		if !entry.IsDir() || !isAgentDefinition(appDir) {
+			continue
+		}
+
+		agent, err := agent_config.LoadFile(appDir)
+		if err != nil {
+			log.Printf("Failed to load agent from %s: %v", appDir, err)
+			continue
+		}
+		loaded[entry.Name()] = agent
+	}
+
+	return loaded
+}
+
+// runRequest is the body of an /api/run request
+type runRequest struct {
+	AppName string `json:"app_name"`
+	Message string `json:"message"`
+}
+
+// addAgentHandlers registers the endpoints that list, run and describe the
+// loaded agents. Apps whose agent tree is invalid are logged and removed from
+// loaded.
+func addAgentHandlers(mux *http.ServeMux, loaded map[string]agents.BaseAgent) {
+	for appName, agent := range loaded {
+		if err := agents.ValidateTree(agent); err != nil {
+			log.Printf("Skipping app %s, its agent tree is invalid: %v", appName, err)
+			delete(loaded, appName)
+		}
+	}
+
+	mux.HandleFunc("/api/list-apps", func(w http.ResponseWriter, r *http.Request) {
+		names := make([]string, 0, len(loaded))
+		for name := range loaded {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(names)
+	})
+
+	mux.HandleFunc("/api/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req runRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		agent, ok := loaded[req.AppName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("app %s not found", req.AppName), http.StatusNotFound)
+			return
+		}
+
+		response, err := agents.ProcessMessage(r.Context(), agent, req.Message)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"response": response})
+	})
+
+	// The graph of an app, as JSON by default or as DOT or Mermaid with ?format=
+	mux.HandleFunc("/api/graph", func(w http.ResponseWriter, r *http.Request) {
+		appName := r.URL.Query().Get("app_name")
+		agent, ok := loaded[appName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("app %s not found", appName), http.StatusNotFound)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = graphFormatJSON
+		}
+
+		graph, err := buildAgentGraph(agent).render(format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contentType := "text/plain; charset=utf-8"
+		if strings.EqualFold(format, graphFormatJSON) {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, graph)
+	})
+}
+
+// addA2AHandlers publishes each loaded agent over the A2A protocol at
+// /a2a/<app_name>/, with its agent card at the well-known path under it. A2A
+// tasks and conversations are kept in an in-memory session service.
+func addA2AHandlers(mux *http.ServeMux, loaded map[string]agents.BaseAgent) {
+	sessionService := sessions.NewInMemorySessionService()
+
+	for appName, agent := range loaded {
+		prefix := "/a2a/" + appName
+		runner := runners.NewSessionRunner(appName, agent, sessionService)
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, runners.NewA2AServer(runner)))
+		log.Printf("Publishing agent %s over A2A at %s/", agent.Name(), prefix)
+	}
+}
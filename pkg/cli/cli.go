@@ -18,18 +18,16 @@ package cli
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/agents/agent_config"
 	"github.com/nvcnvn/adk-golang/pkg/runners"
 	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 	"github.com/nvcnvn/adk-golang/pkg/version"
@@ -46,7 +44,7 @@ var (
 	runCmd = &cobra.Command{
 		Use:   "run [agent_module]",
 		Short: "Run an agent in interactive mode",
-		Long:  "Run an interactive CLI for a specific agent.",
+		Long:  "Run an interactive CLI for a specific agent. The agent may be a Go package, or a file in it, that exports an agent, or a YAML agent definition.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			agentModule := args[0]
@@ -71,8 +69,9 @@ var (
 			traceToCloud, _ := cmd.Flags().GetBool("trace_to_cloud")
 			logLevel, _ := cmd.Flags().GetString("log_level")
 			allowOrigins, _ := cmd.Flags().GetStringSlice("allow_origins")
+			a2a, _ := cmd.Flags().GetBool("a2a")
 
-			return startWebUI(agentsDir, sessionDBURL, port, logToTmp, traceToCloud, logLevel, allowOrigins)
+			return startWebUI(agentsDir, sessionDBURL, port, logToTmp, traceToCloud, logLevel, allowOrigins, a2a)
 		},
 	}
 
@@ -92,8 +91,9 @@ var (
 			traceToCloud, _ := cmd.Flags().GetBool("trace_to_cloud")
 			logLevel, _ := cmd.Flags().GetString("log_level")
 			allowOrigins, _ := cmd.Flags().GetStringSlice("allow_origins")
+			a2a, _ := cmd.Flags().GetBool("a2a")
 
-			return startAPIServer(agentsDir, sessionDBURL, port, logToTmp, traceToCloud, logLevel, allowOrigins)
+			return startAPIServer(agentsDir, sessionDBURL, port, logToTmp, traceToCloud, logLevel, allowOrigins, a2a)
 		},
 	}
 
@@ -112,6 +112,19 @@ var (
 		},
 	}
 
+	graphCmd = &cobra.Command{
+		Use:   "graph [agent_module]",
+		Short: "Render the agent tree as a graph",
+		Long:  "Render the hierarchy of an agent, with its sub-agents, tools and transfer paths, as Graphviz DOT, Mermaid or JSON. The agent may be a Go package, or a file in it, that exports an agent, or a YAML agent definition.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			agentModule := args[0]
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+			return graphAgent(agentModule, format, output)
+		},
+	}
+
 	deployCmd = &cobra.Command{
 		Use:   "deploy",
 		Short: "Deploy agent commands",
@@ -163,6 +176,7 @@ func init() {
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(apiServerCmd)
 	rootCmd.AddCommand(evalCmd)
+	rootCmd.AddCommand(graphCmd)
 	rootCmd.AddCommand(deployCmd)
 	rootCmd.AddCommand(versionCmd)
 
@@ -179,6 +193,7 @@ func init() {
 	webCmd.Flags().StringP("log_level", "", "INFO", "Set the logging level (DEBUG, INFO, WARNING, ERROR, CRITICAL)")
 	webCmd.Flags().BoolP("log_to_tmp", "", false, "Whether to log to system temp folder instead of console")
 	webCmd.Flags().BoolP("trace_to_cloud", "", false, "Whether to enable cloud trace for telemetry")
+	webCmd.Flags().BoolP("a2a", "", false, "Whether to publish each agent over the A2A protocol at /a2a/<app_name>/")
 
 	// Add flags for api_server command (similar to web)
 	apiServerCmd.Flags().StringP("session_db_url", "", "", "Database URL to store the session")
@@ -187,11 +202,16 @@ func init() {
 	apiServerCmd.Flags().StringP("log_level", "", "INFO", "Set the logging level (DEBUG, INFO, WARNING, ERROR, CRITICAL)")
 	apiServerCmd.Flags().BoolP("log_to_tmp", "", false, "Whether to log to system temp folder instead of console")
 	apiServerCmd.Flags().BoolP("trace_to_cloud", "", false, "Whether to enable cloud trace for telemetry")
+	apiServerCmd.Flags().BoolP("a2a", "", false, "Whether to publish each agent over the A2A protocol at /a2a/<app_name>/")
 
 	// Add flags for eval command
 	evalCmd.Flags().StringP("config_file_path", "", "", "Path to config file")
 	evalCmd.Flags().BoolP("print_detailed_results", "", false, "Whether to print detailed results on console")
 
+	// Add flags for graph command
+	graphCmd.Flags().StringP("format", "f", graphFormatDOT, "Output format (dot, mermaid, json)")
+	graphCmd.Flags().StringP("output", "o", "", "File to write the graph to instead of stdout")
+
 	// Add flags for deploy cloud_run command
 	deployCloudRunCmd.Flags().StringP("project", "", "", "Google Cloud project to deploy the agent")
 	deployCloudRunCmd.Flags().StringP("region", "", "", "Google Cloud region to deploy the agent")
@@ -212,104 +232,68 @@ func init() {
 	}
 }
 
-// runAgent loads and runs the specified agent module.
+// runAgent loads and runs the specified agent module. The module is a YAML
+// agent definition, or a Go package directory or file that exports an agent.
 func runAgent(agentModule string, saveSession bool) error {
+	if isAgentDefinition(agentModule) {
+		return runAgentDefinition(agentModule, saveSession)
+	}
+
 	fmt.Printf("Loading agent from module: %s\n", agentModule)
+	return runAgentPackage(agentModule, fmt.Sprintf("%s=%t", harnessSaveSessionEnv, saveSession))
+}
 
-	// If the agent module is a file path, load the agent from the file
-	absPath, err := getAgentPath(agentModule)
-	if err != nil {
-		return err
-	}
+// runAgentDefinition loads an agent from a YAML definition and runs it.
+func runAgentDefinition(path string, saveSession bool) error {
+	fmt.Printf("Loading agent from definition: %s\n", path)
 
-	// Build and run the agent module
-	if err := buildAndRunAgentModule(absPath); err != nil {
+	agent, err := agent_config.LoadFile(path)
+	if err != nil {
 		return err
 	}
 
-	// Get the exported agent
-	agent, ok := agents.GetExportedAgent(filepath.Base(agentModule))
-	if !ok {
-		return fmt.Errorf("agent %s not exported", agentModule)
+	if described, ok := agent.(interface{ Description() string }); ok {
+		fmt.Printf("Agent description: %s\n", described.Description())
 	}
 
-	fmt.Printf("Using model: %s\n", agent.Model())
-	fmt.Printf("Agent description: %s\n", agent.Description())
-
-	// Create a runner for the agent
 	runner := runners.NewSimpleRunner()
-	ctx := context.Background()
-
-	// Configure the runner with save session option
-	if saveSession {
-		runner.SetSaveSessionEnabled(true)
-	}
+	runner.SetSaveSessionEnabled(saveSession)
 
-	// Run the agent in interactive mode
-	return runner.RunInteractive(ctx, agent, os.Stdin, os.Stdout)
+	return runner.RunInteractive(context.Background(), agent, os.Stdin, os.Stdout)
 }
 
-// getAgentPath gets the absolute path to the agent module.
-func getAgentPath(agentModule string) (string, error) {
-	// If it's an absolute path, return it directly
-	if filepath.IsAbs(agentModule) {
-		return agentModule, nil
-	}
-
-	// If it's a relative path, make it absolute
-	absPath, err := filepath.Abs(agentModule)
-	if err != nil {
-		return "", err
+// graphAgent loads the specified agent module and renders its graph. Go
+// packages are rendered by the harness, in the agent's own process.
+func graphAgent(agentModule, format, output string) error {
+	if err := checkGraphFormat(format); err != nil {
+		return err
 	}
 
-	// If it's a directory, look for main.go in that directory
-	fileInfo, err := os.Stat(absPath)
-	if err == nil && fileInfo.IsDir() {
-		mainFile := filepath.Join(absPath, "main.go")
-		if _, err := os.Stat(mainFile); err == nil {
-			return mainFile, nil
+	if isAgentDefinition(agentModule) {
+		agent, err := agent_config.LoadFile(agentModule)
+		if err != nil {
+			return err
 		}
-		return "", fmt.Errorf("no main.go found in %s", absPath)
+		return writeAgentGraph(agent, format, output)
 	}
 
-	// Otherwise, return the absolute path
-	return absPath, nil
-}
-
-// buildAndRunAgentModule builds and runs the agent module to register the agent.
-func buildAndRunAgentModule(agentPath string) error {
-	if strings.HasSuffix(agentPath, ".go") {
-		// Compile and run the Go file
-		dir := filepath.Dir(agentPath)
-		fileName := filepath.Base(agentPath)
-
-		// Build the Go file
-		buildCmd := exec.Command("go", "build", "-o", "agent_temp", fileName)
-		buildCmd.Dir = dir
-		if err := buildCmd.Run(); err != nil {
-			return fmt.Errorf("failed to build agent module: %w", err)
-		}
-
-		// Run the compiled binary
-		runCmd := exec.Command("./agent_temp")
-		runCmd.Dir = dir
-		runCmd.Stdout = os.Stdout
-		runCmd.Stderr = os.Stderr
-		if err := runCmd.Run(); err != nil {
-			return fmt.Errorf("failed to run agent module: %w", err)
+	// The harness runs in the package directory
+	if output != "" {
+		absOutput, err := filepath.Abs(output)
+		if err != nil {
+			return err
 		}
-
-		// Clean up
-		os.Remove(filepath.Join(dir, "agent_temp"))
-	} else {
-		return errors.New("unsupported agent module format")
+		output = absOutput
 	}
 
-	return nil
+	return runAgentPackage(agentModule,
+		fmt.Sprintf("%s=%s", harnessGraphFormatEnv, format),
+		fmt.Sprintf("%s=%s", harnessGraphOutputEnv, output),
+	)
 }
 
 // startWebUI starts the web interface with UI.
-func startWebUI(agentsDir, sessionDBURL string, port int, logToTmp, traceToCloud bool, logLevel string, allowOrigins []string) error {
+func startWebUI(agentsDir, sessionDBURL string, port int, logToTmp, traceToCloud bool, logLevel string, allowOrigins []string, a2a bool) error {
 	configureLogging(logToTmp, logLevel)
 
 	successMessage := fmt.Sprintf(`
@@ -324,19 +308,19 @@ func startWebUI(agentsDir, sessionDBURL string, port int, logToTmp, traceToCloud
 
 	// Start the web server
 	addr := fmt.Sprintf("0.0.0.0:%d", port)
-	handler := getWebUIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins)
+	handler := getWebUIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins, a2a)
 
 	fmt.Println("Starting ADK web server...")
 	return http.ListenAndServe(addr, handler)
 }
 
 // startAPIServer starts the API server without UI.
-func startAPIServer(agentsDir, sessionDBURL string, port int, logToTmp, traceToCloud bool, logLevel string, allowOrigins []string) error {
+func startAPIServer(agentsDir, sessionDBURL string, port int, logToTmp, traceToCloud bool, logLevel string, allowOrigins []string, a2a bool) error {
 	configureLogging(logToTmp, logLevel)
 
 	// Start the API server
 	addr := fmt.Sprintf("0.0.0.0:%d", port)
-	handler := getAPIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins)
+	handler := getAPIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins, a2a)
 
 	fmt.Printf("Starting ADK API server on http://localhost:%d\n", port)
 	return http.ListenAndServe(addr, handler)
@@ -368,11 +352,13 @@ func configureLogging(logToTmp bool, logLevel string) {
 }
 
 // getWebUIHandler returns an HTTP handler for the web UI
-func getWebUIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOrigins []string) http.Handler {
+func getWebUIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOrigins []string, a2a bool) http.Handler {
 	mux := http.NewServeMux()
 
-	// Add API endpoints
-	mux.Handle("/api/", getAPIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins))
+	// Add API and A2A endpoints
+	apiHandler := getAPIHandler(agentsDir, sessionDBURL, traceToCloud, allowOrigins, a2a)
+	mux.Handle("/api/", apiHandler)
+	mux.Handle("/a2a/", apiHandler)
 
 	// Add UI static files and routes
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -387,8 +373,9 @@ func getWebUIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOri
 	return mux
 }
 
-// getAPIHandler returns an HTTP handler for the API server
-func getAPIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOrigins []string) http.Handler {
+// getAPIHandler returns an HTTP handler for the API server. With a2a set,
+// each agent is also published over the A2A protocol.
+func getAPIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOrigins []string, a2a bool) http.Handler {
 	mux := http.NewServeMux()
 
 	// Add API endpoints
@@ -402,7 +389,13 @@ func getAPIHandler(agentsDir, sessionDBURL string, traceToCloud bool, allowOrigi
 		json.NewEncoder(w).Encode(map[string]string{"version": version.Version})
 	})
 
-	// TODO: Add actual API handlers for agents, sessions, etc.
+	loaded := loadAgentDefinitions(agentsDir)
+	addAgentHandlers(mux, loaded)
+	if a2a {
+		addA2AHandlers(mux, loaded)
+	}
+
+	// TODO: Add actual API handlers for sessions, etc.
 
 	return addCORS(mux, allowOrigins)
 }
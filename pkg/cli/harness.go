@@ -0,0 +1,265 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/runners"
+)
+
+// `adk run` cannot load Go code into its own process, so it runs the agent
+// package with `go run` and adds a small generated harness to it through a
+// build overlay, leaving the user's files untouched. The harness hosts the
+// interactive runner, or renders the agent graph for `adk graph`, in the same
+// process as the agent:
+//
+//   - for a main package, the harness installs an export hook, and the
+//     session starts as soon as the program calls agents.Export;
+//   - for any other package, the harness is a main package that imports the
+//     agent package and runs the agent it exports.
+
+const (
+	// harnessSaveSessionEnv tells the harness to save the session on exit
+	harnessSaveSessionEnv = "ADK_RUN_SAVE_SESSION"
+
+	// harnessStatusFileEnv names a file the harness creates once it has
+	// found the agent, so the CLI can tell a session from a program that
+	// never exported an agent
+	harnessStatusFileEnv = "ADK_RUN_STATUS_FILE"
+
+	// harnessGraphFormatEnv makes the harness render the agent graph in the
+	// given format instead of running a session
+	harnessGraphFormatEnv = "ADK_GRAPH_FORMAT"
+
+	// harnessGraphOutputEnv names the file the harness writes the graph to;
+	// the graph goes to stdout if it is empty
+	harnessGraphOutputEnv = "ADK_GRAPH_OUTPUT"
+
+	// harnessName is the name of the generated harness file or package
+	harnessName = "adk_run_harness"
+)
+
+// harnessHookSource is added to main packages
+const harnessHookSource = `// Code generated by adk run. DO NOT EDIT.
+
+package main
+
+import "github.com/nvcnvn/adk-golang/pkg/cli"
+
+func init() {
+	cli.InstallRunHarness()
+}
+`
+
+// harnessMainSource is the main package generated for library packages
+const harnessMainSource = `// Code generated by adk run. DO NOT EDIT.
+
+package main
+
+import (
+	"github.com/nvcnvn/adk-golang/pkg/cli"
+
+	_ %q
+)
+
+func main() {
+	cli.RunHarness()
+}
+`
+
+// InstallRunHarness makes the program run an interactive session with, or
+// render the graph of, the first agent it exports, then exit. It is called by
+// the code `adk run` generates for main packages and is not meant to be
+// called directly.
+func InstallRunHarness() {
+	agents.SetExportHook(func(agent agents.BaseAgent) {
+		agents.SetExportHook(nil)
+		if err := runHarness(agent); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	})
+}
+
+// RunHarness runs an interactive session with, or renders the graph of, the
+// agent exported by the imported agent package. It is the entry point of the
+// code `adk run` generates for library packages and is not meant to be called
+// directly.
+func RunHarness() {
+	exported := agents.ExportedAgents()
+
+	var err error
+	switch len(exported) {
+	case 0:
+		err = fmt.Errorf("the agent package does not export an agent, call agents.Export in an init function")
+	case 1:
+		err = runHarness(exported[0])
+	default:
+		names := make([]string, 0, len(exported))
+		for _, agent := range exported {
+			names = append(names, agent.Name())
+		}
+		err = fmt.Errorf("the agent package exports several agents (%s), export only one", strings.Join(names, ", "))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runHarness does the work the CLI asked for inside the harness process
+func runHarness(agent agents.BaseAgent) error {
+	if err := agents.ValidateTree(agent); err != nil {
+		return fmt.Errorf("invalid agent tree: %w", err)
+	}
+
+	if statusFile := os.Getenv(harnessStatusFileEnv); statusFile != "" {
+		if err := os.WriteFile(statusFile, []byte(agent.Name()), 0644); err != nil {
+			return fmt.Errorf("failed to write harness status: %w", err)
+		}
+	}
+
+	if format := os.Getenv(harnessGraphFormatEnv); format != "" {
+		return writeAgentGraph(agent, format, os.Getenv(harnessGraphOutputEnv))
+	}
+	return runHarnessSession(agent)
+}
+
+// runHarnessSession runs the interactive session inside the harness process
+func runHarnessSession(agent agents.BaseAgent) error {
+	if withModel, ok := agent.(interface{ Model() string }); ok {
+		fmt.Printf("Using model: %s\n", withModel.Model())
+	}
+	fmt.Printf("Agent description: %s\n", agentDescription(agent))
+
+	runner := runners.NewSimpleRunner()
+	runner.SetSaveSessionEnabled(os.Getenv(harnessSaveSessionEnv) == "true")
+
+	return runner.RunInteractive(context.Background(), agent, os.Stdin, os.Stdout)
+}
+
+// agentPackage describes the Go package holding an agent
+type agentPackage struct {
+	Name       string
+	ImportPath string
+	Dir        string
+}
+
+// getAgentPackage resolves an agent module, given as a package directory or
+// a Go file in it, to its package
+func getAgentPackage(agentModule string) (*agentPackage, error) {
+	absPath, err := filepath.Abs(agentModule)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("agent module %s not found: %w", agentModule, err)
+	}
+
+	dir := absPath
+	if !info.IsDir() {
+		if filepath.Ext(absPath) != ".go" {
+			return nil, fmt.Errorf("unsupported agent module format: %s", agentModule)
+		}
+		dir = filepath.Dir(absPath)
+	}
+
+	listCmd := exec.Command("go", "list", "-json", ".")
+	listCmd.Dir = dir
+	listCmd.Stderr = os.Stderr
+	output, err := listCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent package in %s: %w", dir, err)
+	}
+
+	var pkg agentPackage
+	if err := json.Unmarshal(output, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to read agent package in %s: %w", dir, err)
+	}
+	return &pkg, nil
+}
+
+// runAgentPackage runs an agent package through the generated harness. env
+// holds the harness settings, as KEY=value pairs.
+func runAgentPackage(agentModule string, env ...string) error {
+	pkg, err := getAgentPackage(agentModule)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "adk-run-*")
+	if err != nil {
+		return fmt.Errorf("failed to create harness directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Main packages get the hook as an extra file; other packages are
+	// imported by a generated main package in a sub-directory
+	source := harnessHookSource
+	overlayPath := filepath.Join(pkg.Dir, harnessName+".go")
+	target := "."
+	if pkg.Name != "main" {
+		source = fmt.Sprintf(harnessMainSource, pkg.ImportPath)
+		overlayPath = filepath.Join(pkg.Dir, harnessName, "main.go")
+		target = "./" + harnessName
+	}
+
+	sourcePath := filepath.Join(tempDir, "harness.go")
+	if err := os.WriteFile(sourcePath, []byte(source), 0644); err != nil {
+		return fmt.Errorf("failed to write harness: %w", err)
+	}
+
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {overlayPath: sourcePath},
+	})
+	if err != nil {
+		return err
+	}
+	overlayFile := filepath.Join(tempDir, "overlay.json")
+	if err := os.WriteFile(overlayFile, overlay, 0644); err != nil {
+		return fmt.Errorf("failed to write build overlay: %w", err)
+	}
+
+	statusFile := filepath.Join(tempDir, "status")
+
+	runCmd := exec.Command("go", "run", "-overlay", overlayFile, target)
+	runCmd.Dir = pkg.Dir
+	runCmd.Stdin = os.Stdin
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = os.Stderr
+	runCmd.Env = append(os.Environ(), env...)
+	runCmd.Env = append(runCmd.Env, fmt.Sprintf("%s=%s", harnessStatusFileEnv, statusFile))
+
+	if err := runCmd.Run(); err != nil {
+		return fmt.Errorf("failed to run agent module: %w", err)
+	}
+
+	if _, err := os.Stat(statusFile); err != nil {
+		return fmt.Errorf("agent module %s did not export an agent", agentModule)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAgentPackage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the agent packages with go run")
+	}
+
+	tests := []struct {
+		name       string
+		module     string
+		wantAgents []string
+		wantErr    string
+	}{
+		{
+			name:       "main package",
+			module:     "main_agent",
+			wantAgents: []string{"pipeline", "writer", "reviewer"},
+		},
+		{
+			name:       "main package given as a file",
+			module:     filepath.Join("main_agent", "main.go"),
+			wantAgents: []string{"pipeline", "writer", "reviewer"},
+		},
+		{
+			name:       "library package",
+			module:     "library_agent",
+			wantAgents: []string{"coordinator", "helper"},
+		},
+		{
+			name:    "package that never exports an agent",
+			module:  "silent_agent",
+			wantErr: "did not export an agent",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := filepath.Join("testdata", test.module)
+			dir := module
+			if filepath.Ext(module) == ".go" {
+				dir = filepath.Dir(module)
+			}
+			output := filepath.Join(t.TempDir(), "graph.json")
+
+			err := runAgentPackage(module,
+				fmt.Sprintf("%s=%s", harnessGraphFormatEnv, graphFormatJSON),
+				fmt.Sprintf("%s=%s", harnessGraphOutputEnv, output),
+			)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("runAgentPackage error = %v, want it to mention %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runAgentPackage: %v", err)
+			}
+
+			graph, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("the harness wrote no graph: %v", err)
+			}
+			for _, name := range test.wantAgents {
+				if !strings.Contains(string(graph), fmt.Sprintf("%q", name)) {
+					t.Errorf("graph does not hold agent %s:\n%s", name, graph)
+				}
+			}
+
+			// The overlay leaves the agent package untouched
+			if _, err := os.Stat(filepath.Join(dir, harnessName+".go")); !os.IsNotExist(err) {
+				t.Errorf("harness file written to the agent package")
+			}
+			if _, err := os.Stat(filepath.Join(dir, harnessName)); !os.IsNotExist(err) {
+				t.Errorf("harness package written to the agent package")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package library_agent exports an LLM agent when imported.
+package library_agent
+
+import "github.com/nvcnvn/adk-golang/pkg/agents"
+
+func init() {
+	coordinator := agents.NewLlmAgent("coordinator", nil)
+	coordinator.AddSubAgents(agents.NewLlmAgent("helper", nil))
+	agents.Export(coordinator)
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command main_agent exports an agent tree from its main function.
+package main
+
+import (
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+)
+
+func main() {
+	writer := agents.NewLlmAgent("writer", nil)
+	reviewer := agents.NewLlmAgent("reviewer", nil)
+	pipeline := agents.NewSequentialAgent(agents.SequentialAgentConfig{
+		Name:      "pipeline",
+		SubAgents: []agents.BaseAgent{writer, reviewer},
+	})
+
+	agents.Export(pipeline)
+	fmt.Println("the agent was exported but the harness did not take over")
+}
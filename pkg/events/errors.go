@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// Error codes of events reporting the failure of an agent's flow. Flows may
+// report more specific failures with codes of their own.
+const (
+	// ErrorCodeModel reports that a call to the model failed
+	ErrorCodeModel = "MODEL_ERROR"
+
+	// ErrorCodeTool reports that a tool call failed
+	ErrorCodeTool = "TOOL_ERROR"
+
+	// ErrorCodeTransfer reports that a transfer to another agent failed
+	ErrorCodeTransfer = "TRANSFER_ERROR"
+
+	// ErrorCodeAuth reports that a call was refused for lack of valid
+	// credentials
+	ErrorCodeAuth = "AUTH_ERROR"
+
+	// ErrorCodeFlow reports that a request or response processor of the flow
+	// failed
+	ErrorCodeFlow = "FLOW_ERROR"
+
+	// ErrorCodeInstruction reports that the instruction of an agent could not
+	// be built
+	ErrorCodeInstruction = "INSTRUCTION_ERROR"
+
+	// ErrorCodeOutputSchema reports that the final response of an agent does
+	// not match its output schema
+	ErrorCodeOutputSchema = "OUTPUT_SCHEMA_ERROR"
+
+	// ErrorCodeBudgetExceeded reports that an invocation used up its model
+	// calls, tokens or estimated cost
+	ErrorCodeBudgetExceeded = "BUDGET_EXCEEDED"
+
+	// ErrorCodeToolTimeout reports that a tool call ran out of time. The call
+	// may still be running, so it must not be retried.
+	ErrorCodeToolTimeout = "TOOL_TIMEOUT"
+
+	// ErrorCodeDeadlineExceeded reports that an invocation, a model call or a
+	// branch of a parallel agent ran out of time
+	ErrorCodeDeadlineExceeded = "DEADLINE_EXCEEDED"
+
+	// ErrorCodeCancelled reports that an invocation was cancelled
+	ErrorCodeCancelled = "CANCELLED"
+)
+
+// Error is a failure of an agent's flow with the code of the event reporting
+// it. Tools can return an Error to report a more specific failure than
+// ErrorCodeTool, such as ErrorCodeAuth.
+type Error struct {
+	// Code is the error code of the event reporting the failure
+	Code string
+
+	// Err is the cause of the failure
+	Err error
+}
+
+// NewError creates an Error
+func NewError(code string, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// Error returns the message of the cause
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the error code reporting an error: the code of the
+// first Error in its chain, or the code of an error caused by a done context.
+// It returns an empty string for other errors.
+func ErrorCodeOf(err error) string {
+	var flowErr *Error
+	if errors.As(err, &flowErr) {
+		return flowErr.Code
+	}
+	return ContextErrorCode(err)
+}
+
+// ContextErrorCode returns the error code reporting an error caused by a done
+// context, such as the cause of an invocation that timed out. It returns an
+// empty string for other errors.
+func ContextErrorCode(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCancelled
+	}
+	return ""
+}
@@ -42,14 +42,20 @@ type EventActions struct {
 	// could correspond to multiple function calls.
 	// Map value is the required auth config.
 	RequestedAuthConfigs map[string]*auth.AuthConfig `json:"requested_auth_configs,omitempty"`
+
+	// RequestedToolConfirmations will only be set by a tool response indicating
+	// the tool call needs the user's approval before it runs.
+	// Map key is the function call ID, map value is what the user is asked to confirm.
+	RequestedToolConfirmations map[string]*ToolConfirmation `json:"requested_tool_confirmations,omitempty"`
 }
 
 // NewEventActions creates a new EventActions with default values.
 func NewEventActions() *EventActions {
 	return &EventActions{
-		StateDelta:           make(map[string]interface{}),
-		ArtifactDelta:        make(map[string]int),
-		RequestedAuthConfigs: make(map[string]*auth.AuthConfig),
+		StateDelta:                 make(map[string]interface{}),
+		ArtifactDelta:              make(map[string]int),
+		RequestedAuthConfigs:       make(map[string]*auth.AuthConfig),
+		RequestedToolConfirmations: make(map[string]*ToolConfirmation),
 	}
 }
 
@@ -72,17 +78,34 @@ func (a *EventActions) Update(other *EventActions) {
 	}
 
 	// Merge state deltas
+	if len(other.StateDelta) > 0 && a.StateDelta == nil {
+		a.StateDelta = make(map[string]interface{})
+	}
 	for k, v := range other.StateDelta {
 		a.StateDelta[k] = v
 	}
 
 	// Merge artifact deltas
+	if len(other.ArtifactDelta) > 0 && a.ArtifactDelta == nil {
+		a.ArtifactDelta = make(map[string]int)
+	}
 	for k, v := range other.ArtifactDelta {
 		a.ArtifactDelta[k] = v
 	}
 
 	// Merge requested auth configs
+	if len(other.RequestedAuthConfigs) > 0 && a.RequestedAuthConfigs == nil {
+		a.RequestedAuthConfigs = make(map[string]*auth.AuthConfig)
+	}
 	for k, v := range other.RequestedAuthConfigs {
 		a.RequestedAuthConfigs[k] = v
 	}
+
+	// Merge requested tool confirmations
+	if len(other.RequestedToolConfirmations) > 0 && a.RequestedToolConfirmations == nil {
+		a.RequestedToolConfirmations = make(map[string]*ToolConfirmation)
+	}
+	for k, v := range other.RequestedToolConfirmations {
+		a.RequestedToolConfirmations[k] = v
+	}
 }
@@ -57,6 +57,10 @@ type Event struct {
 	// LongRunningToolIDs contains IDs of long-running tools
 	LongRunningToolIDs []string `json:"longRunningToolIds,omitempty"`
 
+	// UsageMetadata holds the tokens used by the model call that produced
+	// this event, if the model reports them
+	UsageMetadata *models.UsageMetadata `json:"usageMetadata,omitempty"`
+
 	// Actions contains actions associated with this event
 	Actions *EventActions `json:"actions,omitempty"`
 }
@@ -76,6 +80,16 @@ func (e *Event) IsFinalResponse() bool {
 		return true
 	}
 
+	// Long-running tools and skipped summarization end the turn as well
+	if len(e.LongRunningToolIDs) > 0 || (e.Actions != nil && e.Actions.SkipSummarization) {
+		return true
+	}
+
+	// Function calls and responses need another round trip to the model
+	if len(e.GetFunctionCalls()) > 0 || len(e.GetFunctionResponses()) > 0 {
+		return false
+	}
+
 	// Final response if there's content and it's not partial
 	if e.Content != nil && !e.Partial {
 		return true
@@ -101,6 +115,23 @@ func (e *Event) GetFunctionCalls() []*models.FunctionCall {
 	return functionCalls
 }
 
+// GetFunctionResponses extracts function responses from the event content
+func (e *Event) GetFunctionResponses() []*models.FunctionResponse {
+	functionResponses := make([]*models.FunctionResponse, 0)
+
+	if e.Content == nil {
+		return functionResponses
+	}
+
+	for _, part := range e.Content.Parts {
+		if part.FunctionResponse != nil {
+			functionResponses = append(functionResponses, part.FunctionResponse)
+		}
+	}
+
+	return functionResponses
+}
+
 // GenerateID generates a random ID for events.
 func GenerateID() string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// RequestConfirmationFunctionName is the name of the function call an agent
+// emits to ask the user to approve or reject a tool call. The user answers
+// with a function response of the same name and ID.
+const RequestConfirmationFunctionName = "adk_request_confirmation"
+
+// ToolConfirmation is a request for the user's approval of a tool call, and
+// the user's answer to it
+type ToolConfirmation struct {
+	// Hint tells the user what they are asked to confirm
+	Hint string `json:"hint,omitempty"`
+
+	// Confirmed is the user's decision
+	Confirmed bool `json:"confirmed"`
+
+	// Payload is extra data the tool asks the user for, or the user's
+	// answer to it
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// ToolConfirmationRequest is the argument of a confirmation request function
+// call: the tool call waiting for approval and what the user is asked
+type ToolConfirmationRequest struct {
+	// OriginalFunctionCall is the tool call waiting for approval
+	OriginalFunctionCall *models.FunctionCall `json:"originalFunctionCall"`
+
+	// ToolConfirmation is what the user is asked to confirm
+	ToolConfirmation *ToolConfirmation `json:"toolConfirmation"`
+}
+
+// GetToolConfirmationRequests returns the confirmation requests of the event,
+// keyed by the ID of the request function call
+func (e *Event) GetToolConfirmationRequests() map[string]*ToolConfirmationRequest {
+	requests := make(map[string]*ToolConfirmationRequest)
+	for _, functionCall := range e.GetFunctionCalls() {
+		if functionCall.Name != RequestConfirmationFunctionName {
+			continue
+		}
+
+		var request ToolConfirmationRequest
+		if err := json.Unmarshal([]byte(functionCall.Arguments), &request); err != nil || request.OriginalFunctionCall == nil {
+			continue
+		}
+		requests[functionCall.ID] = &request
+	}
+	return requests
+}
+
+// NewToolConfirmationResponse creates the user message answering the
+// confirmation request with the given function call ID
+func NewToolConfirmationResponse(requestID string, confirmation *ToolConfirmation) (*models.Content, error) {
+	data, err := json.Marshal(confirmation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool confirmation: %v", err)
+	}
+
+	return &models.Content{
+		Parts: []*models.Part{{
+			Role: "user",
+			FunctionResponse: &models.FunctionResponse{
+				Name:    RequestConfirmationFunctionName,
+				Content: string(data),
+				ID:      requestID,
+			},
+		}},
+	}, nil
+}
+
+// ParseToolConfirmation reads the user's answer from a confirmation function
+// response
+func ParseToolConfirmation(response *models.FunctionResponse) (*ToolConfirmation, error) {
+	var confirmation ToolConfirmation
+	if err := json.Unmarshal([]byte(response.Content), &confirmation); err != nil {
+		return nil, fmt.Errorf("invalid tool confirmation %s: %v", response.ID, err)
+	}
+	return &confirmation, nil
+}
@@ -24,15 +24,12 @@ import (
 
 // CreateBasicFlow creates a new basic LLM flow with standard processors
 func CreateBasicFlow() *llm_flows.BasicFlow {
-	flow := llm_flows.NewBasicFlow()
-
-	// Add standard request processors
-	flow.RequestProcessors = append(flow.RequestProcessors,
-		llm_flows.NewInstructionsProcessor(),
-		llm_flows.NewContentsProcessor(),
-	)
+	return llm_flows.NewBasicFlow()
+}
 
-	return flow
+// CreateAutoFlow creates a flow that also lets the model transfer to other agents
+func CreateAutoFlow() *llm_flows.AutoFlow {
+	return llm_flows.NewAutoFlow()
 }
 
 // CreateIdentityFlow creates a minimal identity flow with no processors
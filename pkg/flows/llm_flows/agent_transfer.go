@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+)
+
+// AgentTransferProcessor tells the model which agents it can transfer the
+// conversation to and declares the transfer_to_agent tool
+type AgentTransferProcessor struct{}
+
+// NewAgentTransferProcessor creates a new AgentTransferProcessor
+func NewAgentTransferProcessor() *AgentTransferProcessor {
+	return &AgentTransferProcessor{}
+}
+
+// Run adds the transfer instructions and tool declaration to the LLM request
+func (p *AgentTransferProcessor) Run(ctx context.Context, invocationContext *agents.InvocationContext, llmRequest *models.LlmRequest) (<-chan *events.Event, error) {
+	eventCh := make(chan *events.Event)
+
+	go func() {
+		defer close(eventCh)
+
+		llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+		if !ok {
+			return
+		}
+
+		targets := llmAgent.TransferTargets()
+		if len(targets) == 0 {
+			return
+		}
+
+		appendInstructions(llmRequest, buildTransferInstructions(llmAgent, targets))
+		llmRequest.AppendTools(tools.FunctionDeclaration(tools.TransferToAgentTool, false))
+	}()
+
+	return eventCh, nil
+}
+
+// validateTransferTarget checks that the current agent may transfer to the named agent
+func validateTransferTarget(invocationContext *agents.InvocationContext, targetName string) error {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok {
+		return fmt.Errorf("agent %s cannot transfer to other agents", invocationContext.GetAgentName())
+	}
+
+	targets := llmAgent.TransferTargets()
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if target.Name() == targetName {
+			return nil
+		}
+		names = append(names, target.Name())
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("agent %s cannot transfer to agent %s: no transfer targets are available", llmAgent.Name(), targetName)
+	}
+	return fmt.Errorf("agent %s cannot transfer to agent %s: valid targets are %s",
+		llmAgent.Name(), targetName, strings.Join(names, ", "))
+}
+
+// buildTransferInstructions describes the transfer targets to the model
+func buildTransferInstructions(agent *agents.LlmAgent, targets []agents.BaseAgent) string {
+	var sb strings.Builder
+
+	sb.WriteString("You have a list of other agents to transfer to:\n\n")
+	for _, target := range targets {
+		sb.WriteString(fmt.Sprintf("Agent name: %s\n", target.Name()))
+		if described, ok := target.(interface{ Description() string }); ok && described.Description() != "" {
+			sb.WriteString(fmt.Sprintf("Agent description: %s\n", described.Description()))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("If you are the best to answer the question according to your description, you can answer it.\n\n")
+	sb.WriteString(fmt.Sprintf("If another agent is better for answering the question according to its description, "+
+		"call `%s` function to transfer the question to that agent. When transferring, do not generate any text "+
+		"other than the function call.\n", tools.TransferToAgentTool.Name()))
+
+	if parent := agent.ParentAgent(); parent != nil && !agent.DisallowTransferToParent {
+		sb.WriteString(fmt.Sprintf("\nYour parent agent is %s. If neither the other agents nor you are best for "+
+			"answering the question according to the descriptions, transfer to your parent agent.\n", parent.Name()))
+	}
+
+	return sb.String()
+}
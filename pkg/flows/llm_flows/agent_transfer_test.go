@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+func TestAgentTransferProcessor(t *testing.T) {
+	tests := []struct {
+		name          string
+		configure     func(writer *agents.LlmAgent)
+		wantTargets   []string
+		wantNoTargets []string
+		wantParent    bool
+	}{
+		{
+			name:          "under a sequential parent",
+			wantTargets:   []string{"editor", "pipeline", "reviewer"},
+			wantNoTargets: []string{"writer"},
+			wantParent:    true,
+		},
+		{
+			name:          "parent disallowed",
+			configure:     func(writer *agents.LlmAgent) { writer.DisallowTransferToParent = true },
+			wantTargets:   []string{"editor", "reviewer"},
+			wantNoTargets: []string{"pipeline"},
+		},
+		{
+			name:          "peers disallowed",
+			configure:     func(writer *agents.LlmAgent) { writer.DisallowTransferToPeers = true },
+			wantTargets:   []string{"editor", "pipeline"},
+			wantNoTargets: []string{"reviewer"},
+			wantParent:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writer := agents.NewLlmAgent("writer", nil)
+			editor := agents.NewLlmAgent("editor", nil)
+			editor.SetDescription("Edits drafts.")
+			writer.AddSubAgents(editor)
+			agents.NewSequentialAgent(agents.SequentialAgentConfig{
+				Name:      "pipeline",
+				SubAgents: []agents.BaseAgent{writer, agents.NewLlmAgent("reviewer", nil)},
+			})
+			if test.configure != nil {
+				test.configure(writer)
+			}
+
+			llmRequest := &models.LlmRequest{}
+			invocationContext := agents.NewInvocationContext("invocation", writer, &types.RunConfig{})
+			eventCh, err := NewAgentTransferProcessor().Run(context.Background(), invocationContext, llmRequest)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			for range eventCh {
+			}
+
+			instructions := llmRequest.SystemInstructions
+			for _, name := range test.wantTargets {
+				if !strings.Contains(instructions, "Agent name: "+name+"\n") {
+					t.Errorf("instructions do not list %s:\n%s", name, instructions)
+				}
+			}
+			for _, name := range test.wantNoTargets {
+				if strings.Contains(instructions, "Agent name: "+name+"\n") {
+					t.Errorf("instructions list %s:\n%s", name, instructions)
+				}
+			}
+			if !strings.Contains(instructions, "Agent description: Edits drafts.") {
+				t.Errorf("instructions do not describe editor:\n%s", instructions)
+			}
+			if got := strings.Contains(instructions, "Your parent agent is pipeline."); got != test.wantParent {
+				t.Errorf("parent mentioned = %t, want %t", got, test.wantParent)
+			}
+			if llmRequest.ToolsDict[tools.TransferToAgentTool.Name()] == nil {
+				t.Errorf("%s is not declared", tools.TransferToAgentTool.Name())
+			}
+
+			for _, name := range test.wantTargets {
+				if err := validateTransferTarget(invocationContext, name); err != nil {
+					t.Errorf("transfer to %s refused: %v", name, err)
+				}
+			}
+			for _, name := range test.wantNoTargets {
+				if err := validateTransferTarget(invocationContext, name); err == nil {
+					t.Errorf("transfer to %s allowed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestAgentTransferProcessorWithoutTargets(t *testing.T) {
+	agent := agents.NewLlmAgent("alone", nil)
+	llmRequest := &models.LlmRequest{}
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	eventCh, err := NewAgentTransferProcessor().Run(context.Background(), invocationContext, llmRequest)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for range eventCh {
+	}
+
+	if llmRequest.SystemInstructions != "" || len(llmRequest.Tools) != 0 {
+		t.Errorf("request = %+v, want no transfer instructions or tool", llmRequest)
+	}
+	err = validateTransferTarget(invocationContext, "anyone")
+	if err == nil || !strings.Contains(err.Error(), "no transfer targets") {
+		t.Errorf("validateTransferTarget error = %v, want no targets reported", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// AutoFlow is a BasicFlow that also lets the model transfer the conversation
+// to other agents in the agent tree
+type AutoFlow struct {
+	*BasicFlow
+}
+
+// NewAutoFlow creates a new AutoFlow instance
+func NewAutoFlow() *AutoFlow {
+	flow := &AutoFlow{
+		BasicFlow: NewBasicFlow(),
+	}
+
+	flow.RequestProcessors = append(flow.RequestProcessors, NewAgentTransferProcessor())
+
+	return flow
+}
+
+// Run executes the auto flow with the given invocation context
+func (f *AutoFlow) Run(ctx context.Context, invocationContext *agents.InvocationContext) (<-chan *events.Event, error) {
+	return f.BasicFlow.Run(ctx, invocationContext)
+}
+
+// NewFlowForAgent returns the default flow for an LLM agent
+func NewFlowForAgent(agent *agents.LlmAgent) agents.LlmFlow {
+	return NewAutoFlow()
+}
+
+func init() {
+	// Register as the default flow so that LlmAgent can run without
+	// importing this package directly
+	agents.SetDefaultLlmFlowFactory(NewFlowForAgent)
+}
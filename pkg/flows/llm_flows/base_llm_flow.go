@@ -17,11 +17,14 @@ package llm_flows
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"strings"
 
 	"github.com/nvcnvn/adk-golang/pkg/agents"
 	"github.com/nvcnvn/adk-golang/pkg/events"
 	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
 	"github.com/nvcnvn/adk-golang/pkg/tools"
 	"github.com/nvcnvn/adk-golang/pkg/types"
 )
@@ -57,10 +60,21 @@ func (f *BaseLlmFlow) Run(ctx context.Context, invocationContext *agents.Invocat
 	go func() {
 		defer close(eventCh)
 
+		// Calls the user approved or rejected run before the model is called
+		if !f.resumeToolCalls(ctx, invocationContext, eventCh) {
+			return
+		}
+
 		for {
+			// Stop once the invocation ran out of time or was cancelled
+			if ctx.Err() != nil {
+				emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, stopError(ctx)))
+				return
+			}
+
 			responseCh, err := f.runOneStep(ctx, invocationContext)
 			if err != nil {
-				log.Printf("Error running flow step: %v", err)
+				emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, fmt.Errorf("failed to run flow step: %w", err)))
 				return
 			}
 
@@ -70,7 +84,7 @@ func (f *BaseLlmFlow) Run(ctx context.Context, invocationContext *agents.Invocat
 				eventCh <- event
 			}
 
-			if lastEvent == nil || lastEvent.IsFinalResponse() {
+			if lastEvent == nil || lastEvent.IsFinalResponse() || invocationContext.IsEndInvocation() {
 				break
 			}
 		}
@@ -79,6 +93,127 @@ func (f *BaseLlmFlow) Run(ctx context.Context, invocationContext *agents.Invocat
 	return eventCh, nil
 }
 
+// RunLive executes the flow over a live connection to the model. Requests
+// sent to the invocation's LiveRequestQueue are forwarded to the model and
+// every response received is postprocessed like in Run.
+func (f *BaseLlmFlow) RunLive(ctx context.Context, invocationContext *agents.InvocationContext) (<-chan *events.Event, error) {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok {
+		return nil, fmt.Errorf("agent %s is not an LLM agent", invocationContext.Agent.Name())
+	}
+
+	eventCh := make(chan *events.Event)
+
+	go func() {
+		defer close(eventCh)
+
+		llmRequest := &models.LlmRequest{}
+
+		preprocessCh, err := f.preprocess(ctx, invocationContext, llmRequest)
+		if err != nil {
+			emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, fmt.Errorf("failed to preprocess request: %w", err)))
+			return
+		}
+
+		preprocessFailed := false
+		for event := range preprocessCh {
+			emitEvent(invocationContext, eventCh, event)
+			if event.ErrorCode != "" {
+				preprocessFailed = true
+			}
+		}
+
+		if preprocessFailed || invocationContext.IsEndInvocation() {
+			return
+		}
+
+		connection, err := llmAgent.CanonicalModel.Connect(ctx, llmRequest)
+		if err != nil {
+			emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, modelError(ctx, err)))
+			return
+		}
+		defer connection.Close()
+
+		if invocationContext.LiveRequestQueue != nil {
+			go f.sendToModel(ctx, connection, invocationContext.LiveRequestQueue)
+		}
+
+		for {
+			llmResponse, err := connection.Receive(ctx)
+			if err != nil {
+				if err != io.EOF {
+					emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, modelError(ctx, err)))
+				}
+				return
+			}
+
+			modelResponseEvent := f.newModelResponseEvent(invocationContext)
+
+			postprocessCh, err := f.postprocess(ctx, invocationContext, llmRequest, llmResponse, modelResponseEvent)
+			if err != nil {
+				emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, fmt.Errorf("failed to postprocess response: %w", err)))
+				return
+			}
+
+			transferToAgent := ""
+			for event := range postprocessCh {
+				emitEvent(invocationContext, eventCh, event)
+				if event.Actions != nil && event.Actions.TransferToAgent != "" {
+					transferToAgent = event.Actions.TransferToAgent
+				}
+			}
+
+			if transferToAgent != "" {
+				agentToRun, err := f.getAgentToRun(invocationContext, transferToAgent)
+				if err != nil {
+					emitEvent(invocationContext, eventCh, newErrorEvent(invocationContext, events.ErrorCodeTransfer, err))
+					return
+				}
+
+				transferCh, err := agentToRun.RunLive(ctx, invocationContext)
+				if err != nil {
+					emitEvent(invocationContext, eventCh, newErrorEvent(invocationContext, events.ErrorCodeTransfer,
+						fmt.Errorf("failed to run agent %s: %w", agentToRun.Name(), err)))
+					return
+				}
+
+				for event := range transferCh {
+					eventCh <- event
+				}
+				return
+			}
+
+			if invocationContext.IsEndInvocation() {
+				return
+			}
+		}
+	}()
+
+	return eventCh, nil
+}
+
+// sendToModel forwards live requests from the queue to the model connection
+func (f *BaseLlmFlow) sendToModel(ctx context.Context, connection models.LlmConnection, queue *agents.LiveRequestQueue) {
+	for {
+		request, err := queue.Get()
+		if err != nil {
+			return
+		}
+
+		if request.Close {
+			connection.Close()
+			return
+		}
+
+		if request.Content != nil {
+			if err := connection.Send(ctx, *request.Content); err != nil {
+				log.Printf("Error sending to model: %v", err)
+				return
+			}
+		}
+	}
+}
+
 // runOneStep executes one step of the flow (one LLM call)
 func (f *BaseLlmFlow) runOneStep(ctx context.Context, invocationContext *agents.InvocationContext) (<-chan *events.Event, error) {
 	eventCh := make(chan *events.Event)
@@ -92,39 +227,95 @@ func (f *BaseLlmFlow) runOneStep(ctx context.Context, invocationContext *agents.
 		// Preprocess the request
 		preprocessCh, err := f.preprocess(ctx, invocationContext, llmRequest)
 		if err != nil {
-			log.Printf("Error preprocessing request: %v", err)
+			emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, fmt.Errorf("failed to preprocess request: %w", err)))
 			return
 		}
 
+		preprocessFailed := false
 		for event := range preprocessCh {
-			eventCh <- event
+			emitEvent(invocationContext, eventCh, event)
+			if event.ErrorCode != "" {
+				preprocessFailed = true
+			}
 		}
 
-		if invocationContext.EndInvocation {
+		if preprocessFailed || invocationContext.IsEndInvocation() {
 			return
 		}
 
-		// Create a new event for the model response
-		modelResponseEvent := events.NewEvent()
-		modelResponseEvent.InvocationID = invocationContext.InvocationID
-		modelResponseEvent.Author = invocationContext.Agent.Name()
+		transferToAgent := ""
+		for attempt := 0; ; attempt++ {
+			// Create a new event for the model response
+			modelResponseEvent := f.newModelResponseEvent(invocationContext)
 
-		// Call the LLM
-		responseCh, err := f.callLLM(ctx, invocationContext, llmRequest, modelResponseEvent)
-		if err != nil {
-			log.Printf("Error calling LLM: %v", err)
-			return
+			// Call the LLM
+			responseCh, err := f.callLLM(ctx, invocationContext, llmRequest, modelResponseEvent)
+			if err != nil {
+				emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, modelError(ctx, err)))
+				return
+			}
+
+			var rejected *models.LlmResponse
+			var violations []string
+			for llmResponse := range responseCh {
+				if rejected != nil {
+					continue
+				}
+
+				// Responses that do not match the output schema are not emitted
+				if violations = validateOutput(invocationContext, llmResponse); len(violations) > 0 {
+					rejected = llmResponse
+					continue
+				}
+
+				// Postprocess the response
+				postprocessCh, err := f.postprocess(ctx, invocationContext, llmRequest, llmResponse, modelResponseEvent)
+				if err != nil {
+					emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, fmt.Errorf("failed to postprocess response: %w", err)))
+					return
+				}
+
+				for event := range postprocessCh {
+					emitEvent(invocationContext, eventCh, event)
+					if event.Actions != nil && event.Actions.TransferToAgent != "" {
+						transferToAgent = event.Actions.TransferToAgent
+					}
+				}
+			}
+
+			if rejected == nil {
+				break
+			}
+
+			// Ask the model to repair its response, or give up
+			if attempt >= outputSchemaRetries(invocationContext) {
+				err := fmt.Errorf("response does not match the output schema after %d attempts: %s",
+					attempt+1, strings.Join(violations, "; "))
+				emitEvent(invocationContext, eventCh, newErrorEvent(invocationContext, events.ErrorCodeOutputSchema, err))
+				return
+			}
+			log.Printf("Response of agent %s does not match the output schema, retrying: %s",
+				invocationContext.GetAgentName(), strings.Join(violations, "; "))
+			appendRepairRequest(llmRequest, rejected, violations)
 		}
 
-		for llmResponse := range responseCh {
-			// Postprocess the response
-			postprocessCh, err := f.postprocess(ctx, invocationContext, llmRequest, llmResponse, modelResponseEvent)
+		// Hand the invocation over to another agent if requested
+		if transferToAgent != "" {
+			agentToRun, err := f.getAgentToRun(invocationContext, transferToAgent)
 			if err != nil {
-				log.Printf("Error postprocessing response: %v", err)
+				emitEvent(invocationContext, eventCh, newErrorEvent(invocationContext, events.ErrorCodeTransfer, err))
 				return
 			}
 
-			for event := range postprocessCh {
+			transferCh, err := agentToRun.Run(ctx, invocationContext)
+			if err != nil {
+				emitEvent(invocationContext, eventCh, newErrorEvent(invocationContext, events.ErrorCodeTransfer,
+					fmt.Errorf("failed to run agent %s: %w", agentToRun.Name(), err)))
+				return
+			}
+
+			// The transferred agent records its own events
+			for event := range transferCh {
 				eventCh <- event
 			}
 		}
@@ -133,6 +324,90 @@ func (f *BaseLlmFlow) runOneStep(ctx context.Context, invocationContext *agents.
 	return eventCh, nil
 }
 
+// emitEvent records a complete event in the invocation history and sends it.
+// Events are recorded before they are sent so that the next step of the flow
+// always sees them, regardless of how fast the caller consumes the channel.
+func emitEvent(invocationContext *agents.InvocationContext, eventCh chan<- *events.Event, event *events.Event) {
+	if !event.Partial {
+		invocationContext.AppendEvent(event)
+	}
+	eventCh <- event
+}
+
+// newModelResponseEvent creates the event that will carry the model response
+func (f *BaseLlmFlow) newModelResponseEvent(invocationContext *agents.InvocationContext) *events.Event {
+	modelResponseEvent := events.NewEvent()
+	modelResponseEvent.InvocationID = invocationContext.InvocationID
+	modelResponseEvent.Author = invocationContext.Agent.Name()
+	modelResponseEvent.Branch = invocationContext.Branch
+	return modelResponseEvent
+}
+
+// newErrorEvent creates an event reporting an error that ends the agent's turn
+func newErrorEvent(invocationContext *agents.InvocationContext, errorCode string, err error) *events.Event {
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = invocationContext.Agent.Name()
+	event.Branch = invocationContext.Branch
+	event.ErrorCode = errorCode
+	event.ErrorMessage = err.Error()
+	return event
+}
+
+// newFailureEvent creates an error event reporting a failure of the flow. The
+// error code is the code of the failure, see events.ErrorCodeOf, or
+// events.ErrorCodeFlow.
+func newFailureEvent(invocationContext *agents.InvocationContext, err error) *events.Event {
+	code := events.ErrorCodeOf(err)
+	if code == "" {
+		code = events.ErrorCodeFlow
+	}
+	return newErrorEvent(invocationContext, code, err)
+}
+
+// stopError returns why a context stopped the flow. The error wraps the
+// context's error, so events.ContextErrorCode gives its error code.
+func stopError(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if events.ContextErrorCode(cause) != "" {
+		return cause
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), cause)
+}
+
+// llmCallContext returns the context of a model call, limited by the run's
+// model call timeout
+func llmCallContext(ctx context.Context, invocationContext *agents.InvocationContext) (context.Context, context.CancelFunc) {
+	runConfig := invocationContext.RunConfig
+	if runConfig == nil || runConfig.LlmCallTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, runConfig.LlmCallTimeout,
+		fmt.Errorf("model call timed out after %s: %w", runConfig.LlmCallTimeout, context.DeadlineExceeded))
+}
+
+// generateContent calls the model until it responds or the context is done.
+// A model that ignores its context keeps running in the background, but its
+// response is discarded.
+func generateContent(ctx context.Context, llm models.LLM, llmRequest *models.LlmRequest) (*models.LlmResponse, error) {
+	type result struct {
+		response *models.LlmResponse
+		err      error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		response, err := llm.GenerateContent(ctx, llmRequest)
+		resultCh <- result{response, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.response, r.err
+	case <-ctx.Done():
+		return nil, stopError(ctx)
+	}
+}
+
 // preprocess runs request processors before calling the LLM
 func (f *BaseLlmFlow) preprocess(ctx context.Context, invocationContext *agents.InvocationContext, llmRequest *models.LlmRequest) (<-chan *events.Event, error) {
 	eventCh := make(chan *events.Event)
@@ -149,7 +424,7 @@ func (f *BaseLlmFlow) preprocess(ctx context.Context, invocationContext *agents.
 		for _, processor := range f.RequestProcessors {
 			processorCh, err := processor.Run(ctx, invocationContext, llmRequest)
 			if err != nil {
-				log.Printf("Error running request processor: %v", err)
+				eventCh <- newErrorEvent(invocationContext, events.ErrorCodeFlow, fmt.Errorf("request processor failed: %w", err))
 				return
 			}
 
@@ -158,24 +433,27 @@ func (f *BaseLlmFlow) preprocess(ctx context.Context, invocationContext *agents.
 			}
 		}
 
-		// Process tools using LlmToolAdaptor
+		// Add the agent's tools to the request
+		toolCtx := &tools.ToolContext{
+			InvocationContext: invocationContext,
+		}
 		for _, tool := range llmAgent.CanonicalTools {
-			if adaptor, ok := tool.(*tools.LlmToolAdaptor); ok {
-				toolCtx := &tools.ToolContext{
-					InvocationContext: invocationContext,
-				}
-				if err := adaptor.ProcessLlmRequest(ctx, toolCtx, llmRequest); err != nil {
-					log.Printf("Error processing tool request: %v", err)
-					continue
-				}
+			if err := tools.AddToLlmRequest(ctx, toolCtx, tool, llmRequest); err != nil {
+				eventCh <- newErrorEvent(invocationContext, events.ErrorCodeTool,
+					fmt.Errorf("failed to add tool %s to the request: %w", tool.Name(), err))
+				return
 			}
 		}
+		if llmAgent.ToolConfig != nil {
+			llmRequest.ToolConfig = llmAgent.ToolConfig
+		}
 	}()
 
 	return eventCh, nil
 }
 
-// callLLM calls the LLM with the given request
+// callLLM calls the LLM with the given request. A failed call is retried or
+// ends with an error response, as the agent's error policy decides.
 func (f *BaseLlmFlow) callLLM(ctx context.Context, invocationContext *agents.InvocationContext, llmRequest *models.LlmRequest, modelResponseEvent *events.Event) (<-chan *models.LlmResponse, error) {
 	responseCh := make(chan *models.LlmResponse)
 
@@ -201,70 +479,136 @@ func (f *BaseLlmFlow) callLLM(ctx context.Context, invocationContext *agents.Inv
 			}
 		}
 
-		// Increment LLM call count
-		invocationContext.IncrementLlmCallCount()
+		for attempt := 0; ; attempt++ {
+			err := f.generate(ctx, invocationContext, llmAgent, llmRequest, modelResponseEvent, responseCh)
+			if err == nil {
+				return
+			}
 
-		// Get the canonical model
-		llm := llmAgent.CanonicalModel
+			if errorAction(ctx, invocationContext, err, attempt) == agents.ErrorActionRetry {
+				log.Printf("Retrying model call of agent %s: %v", llmAgent.Name(), err)
+				continue
+			}
+			responseCh <- &models.LlmResponse{
+				ErrorCode:    err.Code,
+				ErrorMessage: err.Error(),
+			}
+			return
+		}
+	}()
 
-		// Determine if streaming is requested
-		streaming := invocationContext.RunConfig.StreamingMode == types.StreamingModeSSE
+	return responseCh, nil
+}
 
-		var err error
-		if streaming {
-			// If streaming, use the streaming generate function
-			var llmResponseCh <-chan *models.LlmResponse
-			llmResponseCh, err = llm.GenerateContentStream(ctx, llmRequest)
-			if err != nil {
-				log.Printf("Error generating streaming content: %v", err)
-				return
-			}
+// generate makes one call to the model and sends its responses, with the
+// after model callback applied. It returns the failure of the call, if any.
+func (f *BaseLlmFlow) generate(ctx context.Context, invocationContext *agents.InvocationContext, llmAgent *agents.LlmAgent, llmRequest *models.LlmRequest, modelResponseEvent *events.Event, responseCh chan<- *models.LlmResponse) *events.Error {
+	// Stop before the model is called beyond the limits of the run
+	if err := checkBudget(invocationContext); err != nil {
+		return events.NewError(events.ErrorCodeBudgetExceeded, err)
+	}
+	if err := invocationContext.IncrementLlmCallCount(); err != nil {
+		return events.NewError(events.ErrorCodeBudgetExceeded, err)
+	}
 
-			// Forward each streamed response through our channel
-			for llmResponse := range llmResponseCh {
-				// Handle after model callback if exists
-				if llmAgent.AfterModelCallback != nil {
-					callbackCtx := &agents.CallbackContext{
-						InvocationContext: invocationContext,
-						EventActions:      modelResponseEvent.Actions,
-					}
+	// Get the canonical model
+	llm := llmAgent.CanonicalModel
 
-					alteredResponse := llmAgent.AfterModelCallback(callbackCtx, llmResponse)
-					if alteredResponse != nil {
-						llmResponse = alteredResponse
-					}
-				}
+	ctx, span := telemetry.StartSpan(ctx, "BaseLlmFlow.CallLlm")
+	defer span.End()
+	span.SetAttribute("agent.name", llmAgent.Name())
+	span.SetAttribute("invocation.id", invocationContext.InvocationID)
+
+	// A call that runs out of time fails
+	llmCtx, cancel := llmCallContext(ctx, invocationContext)
+	defer cancel()
+
+	fail := func(err error) *events.Error {
+		modelErr := modelError(llmCtx, err)
+		span.SetAttribute("error", modelErr.Error())
+		return modelErr
+	}
+
+	// Determine if streaming is requested
+	if invocationContext.RunConfig.StreamingMode != types.StreamingModeSSE {
+		llmResponse, err := generateContent(llmCtx, llm, llmRequest)
+		if err != nil {
+			return fail(err)
+		}
+		telemetry.SetLlmResponseAttributes(span, llmResponse)
+		recordUsage(invocationContext, llmRequest, llmResponse, modelResponseEvent.Actions)
+		responseCh <- f.afterModel(invocationContext, llmAgent, llmResponse, modelResponseEvent)
+		return nil
+	}
+
+	llmResponseCh, err := llm.GenerateContentStream(llmCtx, llmRequest)
+	if err != nil {
+		return fail(err)
+	}
 
-				responseCh <- llmResponse
+	// Forward each streamed response through our channel
+	for {
+		select {
+		case llmResponse, ok := <-llmResponseCh:
+			if !ok {
+				return nil
 			}
-		} else {
-			// If not streaming, use the non-streaming generate function
-			var llmResponse *models.LlmResponse
-			llmResponse, err = llm.GenerateContent(ctx, llmRequest)
-			if err != nil {
-				log.Printf("Error generating content: %v", err)
-				return
+			if !llmResponse.Partial {
+				telemetry.SetLlmResponseAttributes(span, llmResponse)
 			}
-
-			// Handle after model callback if exists
-			if llmAgent.AfterModelCallback != nil {
-				callbackCtx := &agents.CallbackContext{
-					InvocationContext: invocationContext,
-					EventActions:      modelResponseEvent.Actions,
+			recordUsage(invocationContext, llmRequest, llmResponse, modelResponseEvent.Actions)
+			responseCh <- f.afterModel(invocationContext, llmAgent, llmResponse, modelResponseEvent)
+		case <-llmCtx.Done():
+			// Let the model finish sending in the background
+			go func() {
+				for range llmResponseCh {
 				}
+			}()
+			return fail(llmCtx.Err())
+		}
+	}
+}
 
-				alteredResponse := llmAgent.AfterModelCallback(callbackCtx, llmResponse)
-				if alteredResponse != nil {
-					llmResponse = alteredResponse
-				}
-			}
+// afterModel applies the after model callback to a response, if the agent
+// has one
+func (f *BaseLlmFlow) afterModel(invocationContext *agents.InvocationContext, llmAgent *agents.LlmAgent, llmResponse *models.LlmResponse, modelResponseEvent *events.Event) *models.LlmResponse {
+	if llmAgent.AfterModelCallback == nil {
+		return llmResponse
+	}
 
-			// Send the single response through our channel
-			responseCh <- llmResponse
-		}
-	}()
+	callbackCtx := &agents.CallbackContext{
+		InvocationContext: invocationContext,
+		EventActions:      modelResponseEvent.Actions,
+	}
+	if alteredResponse := llmAgent.AfterModelCallback(callbackCtx, llmResponse); alteredResponse != nil {
+		return alteredResponse
+	}
+	return llmResponse
+}
 
-	return responseCh, nil
+// modelError returns the failure of a model call. A call stopped by its
+// context fails with the context's error code.
+func modelError(ctx context.Context, err error) *events.Error {
+	if ctx.Err() != nil {
+		err := stopError(ctx)
+		return events.NewError(events.ContextErrorCode(err), err)
+	}
+	return events.NewError(events.ErrorCodeModel, fmt.Errorf("model call failed: %w", err))
+}
+
+// errorAction returns what the flow does about a failed call, as decided by
+// the agent's error policy. Failures that end the whole invocation always
+// abort.
+func errorAction(ctx context.Context, invocationContext *agents.InvocationContext, err *events.Error, attempt int) agents.ErrorAction {
+	if ctx.Err() != nil || err.Code == events.ErrorCodeBudgetExceeded {
+		return agents.ErrorActionAbort
+	}
+
+	policy := agents.DefaultErrorPolicy
+	if llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent); ok && llmAgent.ErrorPolicy != nil {
+		policy = llmAgent.ErrorPolicy
+	}
+	return policy(err, attempt)
 }
 
 // postprocess runs response processors after calling the LLM
@@ -278,8 +622,8 @@ func (f *BaseLlmFlow) postprocess(ctx context.Context, invocationContext *agents
 		for _, processor := range f.ResponseProcessors {
 			processorCh, err := processor.Run(ctx, invocationContext, llmResponse)
 			if err != nil {
-				log.Printf("Error running response processor: %v", err)
-				continue
+				eventCh <- newErrorEvent(invocationContext, events.ErrorCodeFlow, fmt.Errorf("response processor failed: %w", err))
+				return
 			}
 
 			for event := range processorCh {
@@ -294,38 +638,27 @@ func (f *BaseLlmFlow) postprocess(ctx context.Context, invocationContext *agents
 
 		// Finalize the model response event
 		finalEvent := f.finalizeModelResponseEvent(llmRequest, llmResponse, modelResponseEvent)
+		saveOutputToState(invocationContext, finalEvent)
 		eventCh <- finalEvent
 
+		// Partial responses are only forwarded, their function calls are
+		// handled once the complete response arrives
+		if finalEvent.Partial {
+			return
+		}
+
 		// Handle function calls if any
 		functionCalls := finalEvent.GetFunctionCalls()
 		if len(functionCalls) > 0 {
-			// Process function calls (implementation would be in the functions package)
 			functionResponseEvent, err := HandleFunctionCalls(ctx, invocationContext, finalEvent, llmRequest.ToolsDict)
 			if err != nil {
-				log.Printf("Error handling function calls: %v", err)
+				eventCh <- newFailureEvent(invocationContext, err)
 				return
 			}
 
 			if functionResponseEvent != nil {
-				eventCh <- functionResponseEvent
-
-				// Handle agent transfer if needed
-				if functionResponseEvent.Actions.TransferToAgent != "" {
-					agentToRun, err := f.getAgentToRun(invocationContext, functionResponseEvent.Actions.TransferToAgent)
-					if err != nil {
-						log.Printf("Error finding agent to transfer to: %v", err)
-						return
-					}
-
-					transferCh, err := agentToRun.Run(ctx, invocationContext)
-					if err != nil {
-						log.Printf("Error running transferred agent: %v", err)
-						return
-					}
-
-					for event := range transferCh {
-						eventCh <- event
-					}
+				for _, event := range functionResponseEvents(invocationContext, functionCalls, functionResponseEvent) {
+					eventCh <- event
 				}
 			}
 		}
@@ -334,20 +667,29 @@ func (f *BaseLlmFlow) postprocess(ctx context.Context, invocationContext *agents
 	return eventCh, nil
 }
 
-// finalizeModelResponseEvent combines the model response with the event
+// finalizeModelResponseEvent builds the event for one model response. Each
+// response gets its own event; streamed chunks of the same response share the
+// ID and actions of modelResponseEvent.
 func (f *BaseLlmFlow) finalizeModelResponseEvent(llmRequest *models.LlmRequest, llmResponse *models.LlmResponse, modelResponseEvent *events.Event) *events.Event {
-	// Copy properties from LLM response to the event
-	if llmResponse.Content != nil {
-		modelResponseEvent.Content = llmResponse.Content
+	event := &events.Event{
+		ID:           modelResponseEvent.ID,
+		InvocationID: modelResponseEvent.InvocationID,
+		Author:       modelResponseEvent.Author,
+		Branch:       modelResponseEvent.Branch,
+		Actions:      modelResponseEvent.Actions,
 	}
-	modelResponseEvent.Partial = llmResponse.Partial
-	modelResponseEvent.ErrorCode = llmResponse.ErrorCode
-	modelResponseEvent.ErrorMessage = llmResponse.ErrorMessage
-	modelResponseEvent.Interrupted = llmResponse.Interrupted
+
+	// Copy properties from LLM response to the event
+	event.Content = llmResponse.Content
+	event.Partial = llmResponse.Partial
+	event.ErrorCode = llmResponse.ErrorCode
+	event.ErrorMessage = llmResponse.ErrorMessage
+	event.Interrupted = llmResponse.Interrupted
+	event.UsageMetadata = llmResponse.UsageMetadata
 
 	// Process function calls if present
-	if modelResponseEvent.Content != nil && len(modelResponseEvent.GetFunctionCalls()) > 0 {
-		PopulateClientFunctionCallID(modelResponseEvent)
+	if event.Content != nil && len(event.GetFunctionCalls()) > 0 {
+		PopulateClientFunctionCallID(event)
 
 		// Convert toolsDict from models.Tool to tools.LlmToolAdaptor
 		toolAdaptors := make(map[string]*tools.LlmToolAdaptor)
@@ -357,14 +699,18 @@ func (f *BaseLlmFlow) finalizeModelResponseEvent(llmRequest *models.LlmRequest,
 			toolAdaptors[name] = tools.NewLlmToolAdaptor(baseTool, tool.IsLongRunning)
 		}
 
-		modelResponseEvent.LongRunningToolIDs = GetLongRunningFunctionCalls(modelResponseEvent.GetFunctionCalls(), toolAdaptors)
+		event.LongRunningToolIDs = GetLongRunningFunctionCalls(event.GetFunctionCalls(), toolAdaptors)
 	}
 
-	return modelResponseEvent
+	return event
 }
 
 // getAgentToRun finds the agent to transfer to
 func (f *BaseLlmFlow) getAgentToRun(invocationContext *agents.InvocationContext, transferToAgent string) (agents.BaseAgent, error) {
+	if err := validateTransferTarget(invocationContext, transferToAgent); err != nil {
+		return nil, err
+	}
+
 	rootAgent := invocationContext.Agent.RootAgent()
 	agentToRun := rootAgent.FindAgent(transferToAgent)
 	if agentToRun == nil {
@@ -0,0 +1,256 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// sequenceLlm is a model that answers with its responses in turn, repeating
+// the last one, and records the requests it got
+type sequenceLlm struct {
+	responses []*models.LlmResponse
+	requests  []*models.LlmRequest
+	mu        sync.Mutex
+}
+
+func (m *sequenceLlm) SupportedModels() []string { return nil }
+
+func (m *sequenceLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := *request
+	snapshot.Contents = append([]*models.Content(nil), request.Contents...)
+	m.requests = append(m.requests, &snapshot)
+
+	i := len(m.requests) - 1
+	if i >= len(m.responses) {
+		i = len(m.responses) - 1
+	}
+	return m.responses[i], nil
+}
+
+func (m *sequenceLlm) GenerateContentStream(ctx context.Context, request *models.LlmRequest) (<-chan *models.LlmResponse, error) {
+	response, err := m.GenerateContent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	responseCh := make(chan *models.LlmResponse, 1)
+	responseCh <- response
+	close(responseCh)
+	return responseCh, nil
+}
+
+func (m *sequenceLlm) Connect(ctx context.Context, request *models.LlmRequest) (models.LlmConnection, error) {
+	return nil, fmt.Errorf("live connections are not supported")
+}
+
+// calls returns the number of requests the model got
+func (m *sequenceLlm) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.requests)
+}
+
+// textResponse returns a model response holding text
+func textResponse(text string) *models.LlmResponse {
+	return &models.LlmResponse{Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{{Text: text}}}}
+}
+
+// callResponse returns a model response calling a function
+func callResponse(name, arguments string) *models.LlmResponse {
+	return &models.LlmResponse{Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{
+		{FunctionCall: &models.FunctionCall{Name: name, ID: "call-" + name, Arguments: arguments}},
+	}}}
+}
+
+// runAgent runs an agent in a new invocation and returns its events
+func runAgent(t *testing.T, agent agents.BaseAgent) []*events.Event {
+	t.Helper()
+
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+	eventCh, err := agent.Run(context.Background(), invocationContext)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var result []*events.Event
+	for event := range eventCh {
+		if event.ErrorCode != "" {
+			t.Errorf("unexpected error event: %s %s", event.ErrorCode, event.ErrorMessage)
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+// newTool returns a function tool, failing the test if it cannot be created
+func newTool(t *testing.T, name string, fn interface{}) tools.Tool {
+	t.Helper()
+
+	tool, err := tools.NewFunctionTool(fn, tools.FunctionToolConfig{Name: name})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+	return tool
+}
+
+func TestFlowFeedsToolResponsesBackToTheModel(t *testing.T) {
+	model := &sequenceLlm{responses: []*models.LlmResponse{
+		callResponse("get_weather", "{}"),
+		textResponse("It is sunny."),
+	}}
+	agent := agents.NewLlmAgent("agent", model)
+	agent.CanonicalTools = []tools.Tool{newTool(t, "get_weather", func() string { return "sunny" })}
+
+	result := runAgent(t, agent)
+
+	if model.calls() != 2 {
+		t.Fatalf("model was called %d times, want 2", model.calls())
+	}
+	var response *models.FunctionResponse
+	for _, content := range model.requests[1].Contents {
+		for _, part := range content.Parts {
+			if part.FunctionResponse != nil {
+				response = part.FunctionResponse
+			}
+		}
+	}
+	if response == nil || response.ID != "call-get_weather" || !strings.Contains(response.Content, "sunny") {
+		t.Errorf("second request has function response %+v, want the tool's result", response)
+	}
+
+	if len(result) != 3 {
+		t.Fatalf("got %d events, want the call, the response and the answer", len(result))
+	}
+	if len(result[0].GetFunctionCalls()) != 1 || len(result[1].GetFunctionResponses()) != 1 {
+		t.Errorf("first events are %+v and %+v, want the call and its response", result[0].Content, result[1].Content)
+	}
+	if last := result[2]; !last.IsFinalResponse() || last.Content.GetText() != "It is sunny." {
+		t.Errorf("last event = %+v, want the final answer", last.Content)
+	}
+}
+
+func TestFlowStops(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []*models.LlmResponse
+		tool      func(toolContext *tools.ToolContext) string
+		wantCalls int
+	}{
+		{
+			name:      "at a final response",
+			responses: []*models.LlmResponse{textResponse("Hello.")},
+			wantCalls: 1,
+		},
+		{
+			name:      "when a tool ends the invocation",
+			responses: []*models.LlmResponse{callResponse("finish", "{}"), textResponse("unreachable")},
+			tool: func(toolContext *tools.ToolContext) string {
+				toolContext.InvocationContext.SetEndInvocation(true)
+				return "done"
+			},
+			wantCalls: 1,
+		},
+		{
+			name:      "when a tool skips summarization",
+			responses: []*models.LlmResponse{callResponse("finish", "{}"), textResponse("unreachable")},
+			tool: func(toolContext *tools.ToolContext) string {
+				toolContext.EventActions.SkipSummarization = true
+				return "done"
+			},
+			wantCalls: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			model := &sequenceLlm{responses: test.responses}
+			agent := agents.NewLlmAgent("agent", model)
+			if test.tool != nil {
+				agent.CanonicalTools = []tools.Tool{newTool(t, "finish", test.tool)}
+			}
+
+			runAgent(t, agent)
+			if model.calls() != test.wantCalls {
+				t.Errorf("model was called %d times, want %d", model.calls(), test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFlowTransfersToAnotherAgent(t *testing.T) {
+	rootModel := &sequenceLlm{responses: []*models.LlmResponse{
+		callResponse(tools.TransferToAgentTool.Name(), `{"agent_name": "helper"}`),
+		textResponse("unreachable"),
+	}}
+	helperModel := &sequenceLlm{responses: []*models.LlmResponse{textResponse("Helper here.")}}
+
+	helper := agents.NewLlmAgent("helper", helperModel)
+	root := agents.NewLlmAgent("root", rootModel)
+	root.AddSubAgents(helper)
+
+	result := runAgent(t, root)
+
+	if rootModel.calls() != 1 || helperModel.calls() != 1 {
+		t.Errorf("root and helper models were called %d and %d times, want once each", rootModel.calls(), helperModel.calls())
+	}
+	if len(result) == 0 {
+		t.Fatalf("no events")
+	}
+	last := result[len(result)-1]
+	if last.Author != "helper" || last.Content.GetText() != "Helper here." {
+		t.Errorf("last event by %s: %+v, want the helper's answer", last.Author, last.Content)
+	}
+
+	transferred := false
+	for _, event := range result {
+		if event.Author == "root" && event.Actions != nil && event.Actions.TransferToAgent == "helper" {
+			transferred = true
+		}
+	}
+	if !transferred {
+		t.Errorf("no event of root records the transfer to helper")
+	}
+}
+
+func TestHandleFunctionCallsAnswersCallsOfNonLlmAgents(t *testing.T) {
+	agent := agents.NewSequentialAgent(agents.SequentialAgentConfig{Name: "sequential"})
+	invocationContext := agents.NewInvocationContext("invocation", agent, nil)
+
+	callEvent := events.NewEvent()
+	callEvent.Content = callResponse("get_weather", "{}").Content
+	responseEvent, err := HandleFunctionCalls(context.Background(), invocationContext, callEvent,
+		map[string]*models.Tool{"get_weather": {Name: "get_weather"}})
+	if err != nil {
+		t.Fatalf("HandleFunctionCalls: %v", err)
+	}
+
+	responses := responseEvent.GetFunctionResponses()
+	if len(responses) != 1 || responses[0].ID != "call-get_weather" || !strings.HasPrefix(responses[0].Content, "Error") {
+		t.Errorf("responses = %+v, want one error response to the call", responses)
+	}
+}
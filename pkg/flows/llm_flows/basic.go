@@ -21,16 +21,25 @@ import (
 	"github.com/nvcnvn/adk-golang/pkg/events"
 )
 
-// BasicFlow is a simple implementation of BaseLlmFlow
+// BasicFlow is a simple implementation of BaseLlmFlow that sends the agent's
+// instructions and the conversation history to the model
 type BasicFlow struct {
 	*BaseLlmFlow
 }
 
-// NewBasicFlow creates a new BasicFlow instance
+// NewBasicFlow creates a new BasicFlow instance with the standard request processors
 func NewBasicFlow() *BasicFlow {
-	return &BasicFlow{
+	flow := &BasicFlow{
 		BaseLlmFlow: NewBaseLlmFlow(),
 	}
+
+	flow.RequestProcessors = append(flow.RequestProcessors,
+		NewInstructionsProcessor(),
+		NewOutputSchemaProcessor(),
+		NewContentsProcessor(),
+	)
+
+	return flow
 }
 
 // Run executes the basic flow with the given invocation context
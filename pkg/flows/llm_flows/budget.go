@@ -0,0 +1,129 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// checkBudget returns an error if the invocation has used up the budgets of
+// its run configuration and may not call the model again
+func checkBudget(invocationContext *agents.InvocationContext) error {
+	runConfig := invocationContext.RunConfig
+	if runConfig == nil {
+		return nil
+	}
+
+	usage := invocationContext.GetUsage()
+	if err := runConfig.InvocationBudget.Check(usage); err != nil {
+		return fmt.Errorf("invocation budget exceeded: %v", err)
+	}
+	if err := runConfig.SessionBudget.Check(sessionUsage(invocationContext)); err != nil {
+		return fmt.Errorf("session budget exceeded: %v", err)
+	}
+	return nil
+}
+
+// sessionUsage returns the total usage of the session's model calls. It is
+// read from the session state, which concurrent invocations of the session
+// add to as well. Without a session, the invocation's usage is the total.
+func sessionUsage(invocationContext *agents.InvocationContext) types.Usage {
+	if invocationContext.Session == nil {
+		return invocationContext.GetUsage()
+	}
+	return agents.SessionUsage(invocationContext.Session)
+}
+
+// recordUsage adds the usage of a model response to the invocation and
+// records the new total of the session in the state. The total is the one
+// stored in the session when the response arrives plus the response's usage,
+// so that concurrent invocations do not overwrite each other's usage. Usage
+// the model does not report is estimated from the length of the request and
+// the response. Streamed chunks are not counted; the complete response is.
+func recordUsage(invocationContext *agents.InvocationContext, llmRequest *models.LlmRequest, llmResponse *models.LlmResponse, actions *events.EventActions) {
+	metadata := llmResponse.UsageMetadata
+	if metadata == nil {
+		if llmResponse.Partial || llmResponse.ErrorCode != "" || llmResponse.Content == nil {
+			return
+		}
+		metadata = &models.UsageMetadata{
+			PromptTokenCount:     estimateRequestTokens(llmRequest),
+			CandidatesTokenCount: estimateContentTokens(llmResponse.Content),
+		}
+	} else if llmResponse.Partial {
+		return
+	}
+
+	usage := types.Usage{
+		InputTokens:       metadata.PromptTokenCount,
+		OutputTokens:      metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount,
+		CachedInputTokens: metadata.CachedContentTokenCount,
+		ThoughtTokens:     metadata.ThoughtsTokenCount,
+	}
+	if pricing, ok := models.LookupPricing(llmResponse.ModelVersion); ok {
+		usage.Cost = pricing.Cost(usage.InputTokens, usage.OutputTokens)
+	}
+
+	total := invocationContext.AddUsage(usage)
+	if invocationContext.Session != nil {
+		total = agents.SessionUsage(invocationContext.Session).Add(usage)
+	}
+	if actions.StateDelta == nil {
+		actions.StateDelta = make(map[string]interface{})
+	}
+	actions.StateDelta[agents.UsageStateKey] = total
+}
+
+// estimateRequestTokens estimates the number of tokens of a request
+func estimateRequestTokens(llmRequest *models.LlmRequest) int {
+	tokens := estimateTokens(llmRequest.SystemInstructions)
+	for _, content := range llmRequest.Contents {
+		tokens += estimateContentTokens(content)
+	}
+	for _, tool := range llmRequest.Tools {
+		tokens += estimateTokens(tool.Name) + estimateTokens(tool.Description)
+	}
+	return tokens
+}
+
+// estimateContentTokens estimates the number of tokens of a content
+func estimateContentTokens(content *models.Content) int {
+	if content == nil {
+		return 0
+	}
+
+	tokens := 0
+	for _, part := range content.Parts {
+		tokens += estimateTokens(part.Text)
+		if part.FunctionCall != nil {
+			tokens += estimateTokens(part.FunctionCall.Name) + estimateTokens(part.FunctionCall.Arguments)
+		}
+		if part.FunctionResponse != nil {
+			tokens += estimateTokens(part.FunctionResponse.Name) + estimateTokens(part.FunctionResponse.Content)
+		}
+	}
+	return tokens
+}
+
+// estimateTokens estimates the number of tokens of a text, counting about
+// four characters per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
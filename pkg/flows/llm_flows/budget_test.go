@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+func TestRecordUsageAddsToStoredSessionUsage(t *testing.T) {
+	session := sessions.NewSession("app", "user", map[string]interface{}{
+		agents.UsageStateKey: types.Usage{InputTokens: 100},
+	}, "")
+	invocationContext := agents.NewInvocationContext("invocation", agents.NewLlmAgent("agent", nil), nil)
+	invocationContext.Session = session
+
+	record := func() types.Usage {
+		actions := &events.EventActions{}
+		recordUsage(invocationContext, &models.LlmRequest{}, &models.LlmResponse{
+			UsageMetadata: &models.UsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 1},
+		}, actions)
+		total := actions.StateDelta[agents.UsageStateKey].(types.Usage)
+		session.SetState(agents.UsageStateKey, total)
+		return total
+	}
+
+	if total := record(); total.InputTokens != 110 {
+		t.Errorf("first total = %d input tokens, want 110", total.InputTokens)
+	}
+
+	// Another invocation of the session records its usage in between
+	usage := agents.SessionUsage(session)
+	usage.InputTokens += 50
+	session.SetState(agents.UsageStateKey, usage)
+
+	if total := record(); total.InputTokens != 170 || total.OutputTokens != 2 {
+		t.Errorf("second total = %+v, want 170 input and 2 output tokens", total)
+	}
+	if usage := invocationContext.GetUsage(); usage.InputTokens != 20 {
+		t.Errorf("invocation usage = %d input tokens, want 20", usage.InputTokens)
+	}
+}
+
+func TestModelUsageIsRecorded(t *testing.T) {
+	tracer := telemetry.NewSimpleTracer()
+	previous := telemetry.GetDefaultTracer()
+	telemetry.SetDefaultTracer(tracer)
+	t.Cleanup(func() { telemetry.SetDefaultTracer(previous) })
+
+	metadata := &models.UsageMetadata{
+		PromptTokenCount:        100,
+		CandidatesTokenCount:    20,
+		CachedContentTokenCount: 60,
+		ThoughtsTokenCount:      30,
+		TotalTokenCount:         150,
+	}
+	response := textResponse("Hello.")
+	response.UsageMetadata = metadata
+	response.ModelVersion = "test-model"
+
+	agent := agents.NewLlmAgent("agent", &sequenceLlm{responses: []*models.LlmResponse{response}})
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+	eventCh, err := agent.Run(context.Background(), invocationContext)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var result []*events.Event
+	for event := range eventCh {
+		result = append(result, event)
+	}
+
+	if len(result) != 1 || result[0].UsageMetadata != metadata {
+		t.Fatalf("events = %+v, want the answer carrying the model's usage", result)
+	}
+
+	// Thoughts count as output
+	want := types.Usage{InputTokens: 100, OutputTokens: 50, CachedInputTokens: 60, ThoughtTokens: 30}
+	if usage := invocationContext.GetUsage(); usage != want {
+		t.Errorf("invocation usage = %+v, want %+v", usage, want)
+	}
+
+	var call *telemetry.SimpleSpan
+	for _, span := range tracer.GetSpans() {
+		if span.Name == "BaseLlmFlow.CallLlm" {
+			call = span
+		}
+	}
+	if call == nil {
+		t.Fatalf("no span for the model call")
+	}
+	for key, value := range map[string]string{
+		telemetry.AttributeResponseModel: "test-model",
+		telemetry.AttributeInputTokens:   "100",
+		telemetry.AttributeCachedTokens:  "60",
+		telemetry.AttributeThoughtTokens: "30",
+		"agent.name":                     "agent",
+	} {
+		if got := call.Attributes[key]; got != value {
+			t.Errorf("span attribute %s = %q, want %q", key, got, value)
+		}
+	}
+}
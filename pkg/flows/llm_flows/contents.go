@@ -16,6 +16,8 @@ package llm_flows
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/nvcnvn/adk-golang/pkg/agents"
 	"github.com/nvcnvn/adk-golang/pkg/events"
@@ -37,54 +39,99 @@ func (p *ContentsProcessor) Run(ctx context.Context, invocationContext *agents.I
 	go func() {
 		defer close(eventCh)
 
-		// Initialize content if not present
-		if llmRequest.Contents == nil {
-			llmRequest.Contents = &models.Content{
-				Parts: make([]*models.Part, 0),
-			}
-		}
+		// Add the history of the events recorded for this invocation
+		appendHistory(
+			llmRequest,
+			branchEvents(invocationEvents(invocationContext), invocationContext.Branch),
+			invocationContext.GetAgentName(),
+		)
+	}()
 
-		// Add invocation event to contents if present
-		if invocationContext.InvocationEvent != nil && invocationContext.InvocationEvent.Content != nil {
-			llmRequest.Contents.Parts = append(llmRequest.Contents.Parts, &models.Part{
-				Text: invocationContext.InvocationEvent.Content.GetText(),
-				Role: "user",
-			})
-		}
+	return eventCh, nil
+}
 
-		// Iterate through events and build history
-		history := buildHistoryFromEvents(invocationContext.Events)
+// invocationEvents returns the events that make up the conversation so far.
+// If the invocation event has not been recorded in the history, it is placed
+// right before the first event of the current invocation.
+func invocationEvents(invocationContext *agents.InvocationContext) []*events.Event {
+	recorded := invocationContext.GetEvents()
 
-		// Add history to the request contents
-		for _, part := range history.Parts {
-			llmRequest.Contents.Parts = append(llmRequest.Contents.Parts, part)
+	invocationEvent := invocationContext.InvocationEvent
+	if invocationEvent == nil {
+		return recorded
+	}
+	for _, event := range recorded {
+		if event.ID == invocationEvent.ID {
+			return recorded
 		}
-	}()
+	}
 
-	return eventCh, nil
+	result := make([]*events.Event, 0, len(recorded)+1)
+	inserted := false
+	for _, event := range recorded {
+		if !inserted && event.InvocationID == invocationContext.InvocationID {
+			result = append(result, invocationEvent)
+			inserted = true
+		}
+		result = append(result, event)
+	}
+	if !inserted {
+		result = append(result, invocationEvent)
+	}
+	return result
 }
 
-// buildHistoryFromEvents constructs a content history from events
-func buildHistoryFromEvents(events []*events.Event) *models.Content {
-	content := &models.Content{
-		Parts: make([]*models.Part, 0),
+// branchEvents returns the events visible from the given branch: events on
+// the branch itself or on one of its ancestors. Events on sibling branches,
+// such as other sub-agents of a parallel agent, are left out.
+func branchEvents(allEvents []*events.Event, branch string) []*events.Event {
+	if branch == "" {
+		return allEvents
 	}
 
+	result := make([]*events.Event, 0, len(allEvents))
+	for _, event := range allEvents {
+		if event.Branch == "" || event.Branch == branch || strings.HasPrefix(branch, event.Branch+".") {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// appendHistory adds the conversation turns of the events, as seen by the
+// named agent, to the request
+func appendHistory(llmRequest *models.LlmRequest, events []*events.Event, agentName string) {
 	for _, event := range events {
-		if event.Content == nil {
+		if event.Content == nil || event.Partial {
 			continue
 		}
 
+		// Replies from other agents are presented to the model as context
+		if event.Author != "user" && event.Author != agentName {
+			llmRequest.AppendContent(models.RoleUser, otherAgentParts(event)...)
+			continue
+		}
+
+		// Determine the role based on the event author
+		role := models.RoleAssistant
+		if event.Author == "user" {
+			role = models.RoleUser
+		}
+
 		for _, part := range event.Content.Parts {
-			// Determine the role based on the event author
-			role := "assistant"
-			if event.Author == "user" {
-				role = "user"
+			if isConfirmationPart(part) {
+				continue
+			}
+
+			// Function responses are sent back to the model by the user
+			partRole := role
+			if part.FunctionResponse != nil {
+				partRole = models.RoleUser
 			}
 
 			// Create a new part with the appropriate role
 			newPart := &models.Part{
-				Role: role,
+				Role: partRole,
 			}
 
 			// Copy content based on type
@@ -94,11 +141,52 @@ func buildHistoryFromEvents(events []*events.Event) *models.Content {
 				newPart.FunctionCall = part.FunctionCall
 			} else if part.FunctionResponse != nil {
 				newPart.FunctionResponse = part.FunctionResponse
+			} else if part.InlineData != nil {
+				newPart.InlineData = part.InlineData
+			} else if part.FileData != nil {
+				newPart.FileData = part.FileData
 			}
 
-			content.Parts = append(content.Parts, newPart)
+			llmRequest.AppendContent(partRole, newPart)
 		}
 	}
+}
+
+// otherAgentParts converts an event authored by another agent into user
+// parts, so the model does not mistake another agent's replies for its own
+func otherAgentParts(event *events.Event) []*models.Part {
+	parts := make([]*models.Part, 0, len(event.Content.Parts))
+
+	for _, part := range event.Content.Parts {
+		var text string
+		switch {
+		case part.Thought, isConfirmationPart(part):
+			continue
+		case part.Text != "":
+			text = fmt.Sprintf("[%s] said: %s", event.Author, part.Text)
+		case part.FunctionCall != nil:
+			text = fmt.Sprintf("[%s] called tool `%s` with parameters: %s",
+				event.Author, part.FunctionCall.Name, part.FunctionCall.Arguments)
+		case part.FunctionResponse != nil:
+			text = fmt.Sprintf("[%s] `%s` tool returned result: %s",
+				event.Author, part.FunctionResponse.Name, part.FunctionResponse.Content)
+		case part.InlineData != nil, part.FileData != nil:
+			// Files are passed on as they are, after saying who sent them
+			parts = append(parts, &models.Part{
+				Role: models.RoleUser,
+				Text: fmt.Sprintf("For context: [%s] sent the following file:", event.Author),
+			}, &models.Part{
+				Role:       models.RoleUser,
+				InlineData: part.InlineData,
+				FileData:   part.FileData,
+			})
+			continue
+		default:
+			continue
+		}
+
+		parts = append(parts, &models.Part{Role: models.RoleUser, Text: "For context: " + text})
+	}
 
-	return content
+	return parts
 }
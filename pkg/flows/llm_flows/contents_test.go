@@ -0,0 +1,131 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// turnSummary describes the turns of a request as "role: part, part"
+func turnSummary(contents []*models.Content) []string {
+	var turns []string
+	for _, content := range contents {
+		turn := content.Role + ":"
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				turn += " call " + part.FunctionCall.Name
+			case part.FunctionResponse != nil:
+				turn += " response " + part.FunctionResponse.Name
+			case part.InlineData != nil:
+				turn += " inline " + part.InlineData.MimeType
+			case part.FileData != nil:
+				turn += " file " + part.FileData.FileURI
+			default:
+				turn += " " + part.Text
+			}
+		}
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func TestContentsProcessorBuildsTurns(t *testing.T) {
+	agent := agents.NewLlmAgent("agent", nil)
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	history := []struct {
+		author string
+		parts  []*models.Part
+	}{
+		{"user", []*models.Part{{Text: "Weather in Paris?"}, {Text: "And in Rome?"}}},
+		{"agent", []*models.Part{{FunctionCall: &models.FunctionCall{Name: "get_weather", ID: "call-1", Arguments: "{}"}}}},
+		{"agent", []*models.Part{{FunctionResponse: &models.FunctionResponse{Name: "get_weather", ID: "call-1", Content: "sunny"}}}},
+		{"agent", []*models.Part{{Text: "Sunny in both."}}},
+		{"helper", []*models.Part{{Text: "I agree."}}},
+		{"user", []*models.Part{{Text: "Thanks."}}},
+	}
+	for _, entry := range history {
+		event := events.NewEvent()
+		event.InvocationID = "invocation"
+		event.Author = entry.author
+		event.Content = &models.Content{Parts: entry.parts}
+		invocationContext.AppendEvent(event)
+	}
+
+	llmRequest := &models.LlmRequest{}
+	eventCh, err := NewContentsProcessor().Run(context.Background(), invocationContext, llmRequest)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for range eventCh {
+	}
+
+	want := []string{
+		"user: Weather in Paris? And in Rome?",
+		"assistant: call get_weather",
+		"user: response get_weather",
+		"assistant: Sunny in both.",
+		"user: For context: [helper] said: I agree. Thanks.",
+	}
+	if got := turnSummary(llmRequest.Contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("turns = %q, want %q", got, want)
+	}
+}
+
+func TestContentsProcessorKeepsFiles(t *testing.T) {
+	agent := agents.NewLlmAgent("agent", nil)
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	history := []struct {
+		author string
+		parts  []*models.Part
+	}{
+		{"user", []*models.Part{{Text: "Describe these."}, models.NewInlineDataPart([]byte{0x89, 'P', 'N', 'G'}, "image/png")}},
+		{"helper", []*models.Part{models.NewFileDataPart("gs://bucket/report.pdf", "application/pdf")}},
+	}
+	for _, entry := range history {
+		event := events.NewEvent()
+		event.InvocationID = "invocation"
+		event.Author = entry.author
+		event.Content = &models.Content{Parts: entry.parts}
+		invocationContext.AppendEvent(event)
+	}
+
+	llmRequest := &models.LlmRequest{}
+	eventCh, err := NewContentsProcessor().Run(context.Background(), invocationContext, llmRequest)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for range eventCh {
+	}
+
+	want := []string{
+		"user: Describe these. inline image/png For context: [helper] sent the following file: file gs://bucket/report.pdf",
+	}
+	if got := turnSummary(llmRequest.Contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("turns = %q, want %q", got, want)
+	}
+	if data := llmRequest.Contents[0].Parts[1].InlineData.Data; string(data) != "\x89PNG" {
+		t.Errorf("inline data = %q, want the image bytes", data)
+	}
+}
@@ -16,8 +16,11 @@ package llm_flows
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nvcnvn/adk-golang/pkg/agents"
@@ -26,6 +29,9 @@ import (
 	"github.com/nvcnvn/adk-golang/pkg/tools"
 )
 
+// flowTools are tools declared by the flow itself rather than by the agent
+var flowTools = []tools.Tool{tools.TransferToAgentTool}
+
 // HandleFunctionCalls processes function calls from the model response
 func HandleFunctionCalls(ctx context.Context, invocationContext *agents.InvocationContext, functionCallEvent *events.Event, toolsDict map[string]*models.Tool) (*events.Event, error) {
 	functionCalls := functionCallEvent.GetFunctionCalls()
@@ -37,89 +43,282 @@ func HandleFunctionCalls(ctx context.Context, invocationContext *agents.Invocati
 	functionResponseEvent := events.NewEvent()
 	functionResponseEvent.InvocationID = invocationContext.InvocationID
 	functionResponseEvent.Author = invocationContext.Agent.Name()
+	functionResponseEvent.Branch = invocationContext.Branch
 
 	// Process each function call
 	content := &models.Content{
 		Parts: make([]*models.Part, 0, len(functionCalls)),
 	}
 
+	// Only LLM agents have tools; every call still gets a response so that
+	// the history never holds a call without one
+	llmAgent, isLlmAgent := invocationContext.Agent.(*agents.LlmAgent)
+
 	for _, functionCall := range functionCalls {
 		// Check if the tool exists in the dictionary
 		_, exists := toolsDict[functionCall.Name]
 		if !exists {
 			log.Printf("Tool not found: %s", functionCall.Name)
-			part := &models.Part{
-				FunctionResponse: &models.FunctionResponse{
-					Name:    functionCall.Name,
-					Content: fmt.Sprintf("Error: Tool %s not found", functionCall.Name),
-				},
-			}
-			content.Parts = append(content.Parts, part)
+			content.Parts = append(content.Parts, functionResponsePart(functionCall, fmt.Sprintf("Error: Tool %s not found", functionCall.Name)))
 			continue
 		}
+		if !isLlmAgent {
+			log.Printf("Agent %s is not an LLM agent and cannot run tool %s", invocationContext.Agent.Name(), functionCall.Name)
+			content.Parts = append(content.Parts, functionResponsePart(functionCall, fmt.Sprintf("Error: Agent %s cannot run tools", invocationContext.Agent.Name())))
+			continue
+		}
+
+		part, err := runFunctionCall(ctx, invocationContext, llmAgent, functionCall, functionResponseEvent.Actions, nil)
+		if err != nil {
+			return nil, err
+		}
+		if part != nil {
+			content.Parts = append(content.Parts, part)
+		}
+	}
+
+	functionResponseEvent.Content = content
+	return functionResponseEvent, nil
+}
+
+// runFunctionCall executes one function call and returns its response part.
+// It returns nil if the call waits for the user's approval instead: calls
+// run without a confirmation are checked against the tool and the agent's
+// confirmation policy, and a tool may also ask for approval while it runs.
+// Failed calls are retried or fed back to the model as the agent's error
+// policy decides; an error is returned if the failure ends the agent's turn.
+func runFunctionCall(ctx context.Context, invocationContext *agents.InvocationContext, llmAgent *agents.LlmAgent, functionCall *models.FunctionCall, actions *events.EventActions, confirmation *events.ToolConfirmation) (*models.Part, error) {
+	tool := tools.FindTool(llmAgent.CanonicalTools, functionCall.Name)
+	if tool == nil {
+		tool = tools.FindTool(flowTools, functionCall.Name)
+	}
+	if tool == nil {
+		log.Printf("Tool %s not found in agent %s", functionCall.Name, llmAgent.Name())
+		return functionResponsePart(functionCall, fmt.Sprintf("Error: Tool %s not found", functionCall.Name)), nil
+	}
+
+	// Create a tool context
+	toolContext := &tools.ToolContext{
+		InvocationContext: invocationContext,
+		EventActions:      actions,
+		FunctionCallID:    functionCall.ID,
+		ToolConfirmation:  confirmation,
+	}
 
-		// Find the actual tool in the agent's canonical tools
-		llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
-		if !ok {
-			log.Printf("Agent is not an LLM agent")
+	if confirmation == nil {
+		if requested := requiredConfirmation(llmAgent, tool, functionCall, toolContext); requested != nil {
+			requestConfirmation(actions, functionCall.ID, requested)
+			return nil, nil
+		}
+	}
+
+	// Execute the tool, retrying as the error policy decides. Each attempt
+	// records its actions apart, so that an attempt still running after it
+	// timed out cannot change the actions of the event.
+	var response string
+	for attempt := 0; ; attempt++ {
+		attemptContext := *toolContext
+		attemptContext.EventActions = events.NewEventActions()
+
+		var err *events.Error
+		var running bool
+		response, running, err = callToolOnce(ctx, invocationContext, llmAgent, tool, functionCall, &attemptContext)
+		if err == nil {
+			actions.Update(attemptContext.EventActions)
+			break
+		}
+
+		log.Printf("Error executing tool %s: %v", functionCall.Name, err)
+		action := errorAction(ctx, invocationContext, err, attempt)
+		if action == agents.ErrorActionRetry && running {
+			// Retrying could run the tool's side effects twice at the same time
+			log.Printf("Not retrying tool %s: its call may still be running", functionCall.Name)
+			action = agents.ErrorActionContinue
+		}
+		switch action {
+		case agents.ErrorActionRetry:
 			continue
+		case agents.ErrorActionContinue:
+			return functionResponsePart(functionCall, fmt.Sprintf("Error executing tool: %v", err)), nil
 		}
+		return nil, events.NewError(err.Code, fmt.Errorf("tool %s failed: %w", functionCall.Name, err.Err))
+	}
+
+	// The tool asked for approval; its response is discarded
+	if _, requested := actions.RequestedToolConfirmations[functionCall.ID]; requested {
+		return nil, nil
+	}
 
-		var toolAdaptor *tools.LlmToolAdaptor
-		for _, t := range llmAgent.CanonicalTools {
-			if adaptor, ok := t.(*tools.LlmToolAdaptor); ok && adaptor.Name() == functionCall.Name {
-				toolAdaptor = adaptor
-				break
+	// A long-running tool without an initial result is still running; the
+	// response is submitted later through the runner
+	if isLongRunning(tool) && (response == "" || response == "null") {
+		return nil, nil
+	}
+
+	// Refuse transfers to agents outside the allowed targets so the model can pick another
+	if target := actions.TransferToAgent; target != "" {
+		if err := validateTransferTarget(invocationContext, target); err != nil {
+			log.Printf("Rejected transfer: %v", err)
+			actions.TransferToAgent = ""
+			transferErr := events.NewError(events.ErrorCodeTransfer, err)
+			if errorAction(ctx, invocationContext, transferErr, 0) == agents.ErrorActionAbort {
+				return nil, transferErr
 			}
+			response = fmt.Sprintf("Error: %v", err)
 		}
+	}
 
-		if toolAdaptor == nil {
-			log.Printf("LlmToolAdaptor for %s not found", functionCall.Name)
-			part := &models.Part{
-				FunctionResponse: &models.FunctionResponse{
-					Name:    functionCall.Name,
-					Content: fmt.Sprintf("Error: Tool adaptor for %s not found", functionCall.Name),
-				},
-			}
-			content.Parts = append(content.Parts, part)
-			continue
+	return functionResponsePart(functionCall, response), nil
+}
+
+// toolCallContext returns the context of a tool call, limited by the tool's
+// own timeout or else by the run's tool timeout
+func toolCallContext(ctx context.Context, invocationContext *agents.InvocationContext, tool tools.Tool) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	if runConfig := invocationContext.RunConfig; runConfig != nil {
+		timeout = runConfig.ToolTimeout
+	}
+	if timeoutTool, ok := tool.(tools.TimeoutTool); ok && timeoutTool.Timeout() > 0 {
+		timeout = timeoutTool.Timeout()
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout,
+		fmt.Errorf("tool %s timed out after %s: %w", tool.Name(), timeout, context.DeadlineExceeded))
+}
+
+// callToolOnce calls a tool within its time limit and returns the failure of
+// the call, if any. A tool can report a specific failure by returning an
+// events.Error; other errors are tool errors. running reports that the call
+// was abandoned before the tool returned, so it may still be running.
+func callToolOnce(ctx context.Context, invocationContext *agents.InvocationContext, llmAgent *agents.LlmAgent, tool tools.Tool, functionCall *models.FunctionCall, toolContext *tools.ToolContext) (response string, running bool, toolErr *events.Error) {
+	callCtx, cancel := toolCallContext(ctx, invocationContext, tool)
+	defer cancel()
+
+	response, running, err := runTool(callCtx, llmAgent, tool, functionCall, toolContext)
+	if callCtx.Err() != nil {
+		err := stopError(callCtx)
+		if running {
+			err = fmt.Errorf("%w; the call may still be running", err)
 		}
 
-		// Create a tool context
-		toolContext := &tools.ToolContext{
-			InvocationContext: invocationContext,
-			EventActions:      functionResponseEvent.Actions,
+		// The invocation ending is reported as such; otherwise the tool's own
+		// time limit ran out
+		code := events.ErrorCodeToolTimeout
+		if ctx.Err() != nil {
+			code = events.ContextErrorCode(err)
 		}
+		return "", running, events.NewError(code, err)
+	}
+	if err != nil {
+		if errors.As(err, &toolErr) {
+			return "", false, toolErr
+		}
+		return "", false, events.NewError(events.ErrorCodeTool, err)
+	}
+	return response, false, nil
+}
 
-		// Execute the tool
-		response, err := toolAdaptor.ExecuteFunctionCall(ctx, toolContext, functionCall)
-		if err != nil {
-			log.Printf("Error executing tool %s: %v", functionCall.Name, err)
-			part := &models.Part{
-				FunctionResponse: &models.FunctionResponse{
-					Name:    functionCall.Name,
-					Content: fmt.Sprintf("Error executing tool: %v", err),
-				},
-			}
-			content.Parts = append(content.Parts, part)
-			continue
+// runTool calls a tool until it returns or its context is done. A tool that
+// ignores its context keeps running in the background, but its response is
+// discarded; running reports this case.
+func runTool(ctx context.Context, llmAgent *agents.LlmAgent, tool tools.Tool, functionCall *models.FunctionCall, toolContext *tools.ToolContext) (response string, running bool, err error) {
+	type result struct {
+		response string
+		err      error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		response, err := callTool(ctx, llmAgent, tool, functionCall, toolContext)
+		resultCh <- result{response, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.response, false, r.err
+	case <-ctx.Done():
+		return "", true, stopError(ctx)
+	}
+}
+
+// isLongRunning reports whether a tool's calls complete after the invocation
+func isLongRunning(tool tools.Tool) bool {
+	longRunning, ok := tool.(interface{ IsLongRunning() bool })
+	return ok && longRunning.IsLongRunning()
+}
+
+// functionResponsePart creates the response part of a function call
+func functionResponsePart(functionCall *models.FunctionCall, response string) *models.Part {
+	return &models.Part{
+		FunctionResponse: &models.FunctionResponse{
+			Name:    functionCall.Name,
+			Content: response,
+			ID:      functionCall.ID,
+		},
+	}
+}
+
+// callTool executes a function call with the agent's tool callbacks applied
+func callTool(ctx context.Context, llmAgent *agents.LlmAgent, tool tools.Tool, functionCall *models.FunctionCall, toolContext *tools.ToolContext) (string, error) {
+	executor := tools.AsFunctionCallExecutor(tool)
+	if llmAgent.BeforeToolCallback == nil && llmAgent.AfterToolCallback == nil {
+		return executor.ExecuteFunctionCall(ctx, toolContext, functionCall)
+	}
+
+	args := make(map[string]interface{})
+	if functionCall.Arguments != "" {
+		if err := json.Unmarshal([]byte(functionCall.Arguments), &args); err != nil {
+			return "", fmt.Errorf("failed to parse function arguments: %v", err)
 		}
+	}
 
-		// Add the response to the content
-		part := &models.Part{
-			FunctionResponse: &models.FunctionResponse{
-				Name:    functionCall.Name,
-				Content: response,
-			},
+	if llmAgent.BeforeToolCallback != nil {
+		if result := llmAgent.BeforeToolCallback(tool, args, toolContext); result != nil {
+			return marshalToolResult(result)
 		}
-		if functionCall.ID != "" {
-			part.FunctionResponse.ID = functionCall.ID
+
+		// The callback may have rewritten the arguments; the recorded call is left as is
+		arguments, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal function arguments: %v", err)
 		}
-		content.Parts = append(content.Parts, part)
+		rewritten := *functionCall
+		rewritten.Arguments = string(arguments)
+		functionCall = &rewritten
 	}
 
-	functionResponseEvent.Content = content
-	return functionResponseEvent, nil
+	response, err := executor.ExecuteFunctionCall(ctx, toolContext, functionCall)
+	if err != nil || llmAgent.AfterToolCallback == nil {
+		return response, err
+	}
+
+	if result := llmAgent.AfterToolCallback(tool, args, toolContext, toolResultMap(response)); result != nil {
+		return marshalToolResult(result)
+	}
+	return response, nil
+}
+
+// toolResultMap decodes a tool response for the after-tool callback.
+// Responses that are not JSON objects are wrapped as {"result": response}.
+func toolResultMap(response string) map[string]interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(response), &value); err != nil {
+		return map[string]interface{}{"result": response}
+	}
+	if result, ok := value.(map[string]interface{}); ok {
+		return result
+	}
+	return map[string]interface{}{"result": value}
+}
+
+// marshalToolResult encodes a result returned by a tool callback
+func marshalToolResult(result map[string]interface{}) (string, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tool result: %v", err)
+	}
+	return string(data), nil
 }
 
 // PopulateClientFunctionCallID generates client-side IDs for function calls
@@ -0,0 +1,256 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// slowTool returns a tool that ignores its context, sleeps past its timeout
+// and then writes to its event actions. done is closed once it returned.
+func slowTool(t *testing.T, calls *int32, done chan struct{}) tools.Tool {
+	t.Helper()
+
+	tool, err := tools.NewFunctionTool(func(toolContext *tools.ToolContext) string {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		toolContext.EventActions.StateDelta["late"] = true
+		toolContext.EventActions.TransferToAgent = "other"
+		close(done)
+		return "paid"
+	}, tools.FunctionToolConfig{Name: "pay", Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+	return tool
+}
+
+func TestRunFunctionCallTimeoutKeepsActionsApart(t *testing.T) {
+	var calls int32
+	done := make(chan struct{})
+
+	agent := agents.NewLlmAgent("agent", nil)
+	agent.CanonicalTools = []tools.Tool{slowTool(t, &calls, done)}
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	actions := events.NewEventActions()
+	functionCall := &models.FunctionCall{Name: "pay", ID: "call", Arguments: "{}"}
+	part, err := runFunctionCall(context.Background(), invocationContext, agent, functionCall, actions, nil)
+
+	// The flow keeps using the actions while the abandoned call finishes
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			actions.StateDelta["flow"] = true
+			delete(actions.StateDelta, "flow")
+		}
+	}
+
+	if _, ok := actions.StateDelta["late"]; ok {
+		t.Errorf("state delta of the timed out call was recorded")
+	}
+	if actions.TransferToAgent != "" {
+		t.Errorf("TransferToAgent = %q, want empty", actions.TransferToAgent)
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	} else if part != nil && part.FunctionResponse != nil {
+		message = part.FunctionResponse.Content
+	}
+	if !strings.Contains(message, "may still be running") {
+		t.Errorf("failure %q does not say the call may still be running", message)
+	}
+}
+
+func TestRunFunctionCallMergesActionsOnSuccess(t *testing.T) {
+	tool, err := tools.NewFunctionTool(func(toolContext *tools.ToolContext) string {
+		toolContext.EventActions.StateDelta["paid"] = true
+		return "paid"
+	}, tools.FunctionToolConfig{Name: "pay"})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+
+	agent := agents.NewLlmAgent("agent", nil)
+	agent.CanonicalTools = []tools.Tool{tool}
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	actions := events.NewEventActions()
+	functionCall := &models.FunctionCall{Name: "pay", ID: "call", Arguments: "{}"}
+	part, err := runFunctionCall(context.Background(), invocationContext, agent, functionCall, actions, nil)
+	if err != nil {
+		t.Fatalf("runFunctionCall: %v", err)
+	}
+	if part == nil || part.FunctionResponse == nil {
+		t.Fatalf("no function response")
+	}
+	if actions.StateDelta["paid"] != true {
+		t.Errorf("state delta of the call was not recorded: %v", actions.StateDelta)
+	}
+}
+
+func TestRunFunctionCallDoesNotRetryRunningCall(t *testing.T) {
+	policies := map[string]agents.ErrorPolicy{
+		"retry model errors": agents.RetryModelErrors(3),
+		"always retry": func(err *events.Error, attempt int) agents.ErrorAction {
+			return agents.ErrorActionRetry
+		},
+	}
+
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			done := make(chan struct{})
+
+			agent := agents.NewLlmAgent("agent", nil)
+			agent.CanonicalTools = []tools.Tool{slowTool(t, &calls, done)}
+			agent.ErrorPolicy = policy
+			invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+			functionCall := &models.FunctionCall{Name: "pay", ID: "call", Arguments: "{}"}
+			part, err := runFunctionCall(context.Background(), invocationContext, agent, functionCall, events.NewEventActions(), nil)
+			<-done
+
+			if err != nil {
+				t.Fatalf("runFunctionCall: %v", err)
+			}
+			if part == nil || !strings.Contains(part.FunctionResponse.Content, "timed out") {
+				t.Errorf("response = %+v, want the timeout fed back to the model", part)
+			}
+			if got := atomic.LoadInt32(&calls); got != 1 {
+				t.Errorf("tool ran %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestToolTimeoutErrorCode(t *testing.T) {
+	var calls int32
+	done := make(chan struct{})
+
+	agent := agents.NewLlmAgent("agent", nil)
+	agent.CanonicalTools = []tools.Tool{slowTool(t, &calls, done)}
+	agent.ErrorPolicy = func(err *events.Error, attempt int) agents.ErrorAction {
+		return agents.ErrorActionAbort
+	}
+	invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+	functionCall := &models.FunctionCall{Name: "pay", ID: "call", Arguments: "{}"}
+	_, err := runFunctionCall(context.Background(), invocationContext, agent, functionCall, events.NewEventActions(), nil)
+	<-done
+
+	if code := events.ErrorCodeOf(err); code != events.ErrorCodeToolTimeout {
+		t.Errorf("error code = %q, want %q", code, events.ErrorCodeToolTimeout)
+	}
+}
+
+func TestToolCallbacks(t *testing.T) {
+	tests := []struct {
+		name         string
+		before       func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext) map[string]interface{}
+		after        func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext, result map[string]interface{}) map[string]interface{}
+		wantCity     string
+		wantResponse string
+		wantState    map[string]interface{}
+	}{
+		{
+			name:         "no callbacks",
+			wantCity:     "Paris",
+			wantResponse: `{"forecast":"sunny in Paris"}`,
+		},
+		{
+			name: "before short-circuits",
+			before: func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext) map[string]interface{} {
+				toolContext.EventActions.StateDelta["cached"] = true
+				return map[string]interface{}{"forecast": "cached"}
+			},
+			wantResponse: `{"forecast":"cached"}`,
+			wantState:    map[string]interface{}{"cached": true},
+		},
+		{
+			name: "before rewrites the arguments",
+			before: func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext) map[string]interface{} {
+				args["city"] = "Rome"
+				return nil
+			},
+			wantCity:     "Rome",
+			wantResponse: `{"forecast":"sunny in Rome"}`,
+		},
+		{
+			name: "after rewrites the result",
+			after: func(tool tools.Tool, args map[string]interface{}, toolContext *tools.ToolContext, result map[string]interface{}) map[string]interface{} {
+				toolContext.EventActions.StateDelta["last_forecast"] = result["forecast"]
+				return map[string]interface{}{"forecast": "rewritten"}
+			},
+			wantCity:     "Paris",
+			wantResponse: `{"forecast":"rewritten"}`,
+			wantState:    map[string]interface{}{"last_forecast": "sunny in Paris"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			city := ""
+			forecast := tools.NewTool("forecast", "Forecasts the weather", tools.ToolSchema{},
+				func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+					city, _ = input["city"].(string)
+					return map[string]interface{}{"forecast": "sunny in " + city}, nil
+				})
+
+			agent := agents.NewLlmAgent("agent", nil)
+			agent.CanonicalTools = []tools.Tool{forecast}
+			agent.BeforeToolCallback = test.before
+			agent.AfterToolCallback = test.after
+			invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+
+			callEvent := events.NewEvent()
+			callEvent.Content = &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{
+				{FunctionCall: &models.FunctionCall{Name: "forecast", ID: "call", Arguments: `{"city":"Paris"}`}},
+			}}
+			responseEvent, err := HandleFunctionCalls(context.Background(), invocationContext, callEvent,
+				map[string]*models.Tool{"forecast": {Name: "forecast"}})
+			if err != nil {
+				t.Fatalf("HandleFunctionCalls: %v", err)
+			}
+
+			if city != test.wantCity {
+				t.Errorf("tool ran for city %q, want %q", city, test.wantCity)
+			}
+			responses := responseEvent.GetFunctionResponses()
+			if len(responses) != 1 || responses[0].Content != test.wantResponse {
+				t.Fatalf("responses = %+v, want %s", responses, test.wantResponse)
+			}
+			for key, want := range test.wantState {
+				if value := responseEvent.Actions.StateDelta[key]; value != want {
+					t.Errorf("state delta %s = %v, want %v", key, value, want)
+				}
+			}
+		})
+	}
+}
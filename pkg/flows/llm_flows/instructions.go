@@ -16,6 +16,7 @@ package llm_flows
 
 import (
 	"context"
+	"log"
 
 	"github.com/nvcnvn/adk-golang/pkg/agents"
 	"github.com/nvcnvn/adk-golang/pkg/events"
@@ -43,29 +44,40 @@ func (p *InstructionsProcessor) Run(ctx context.Context, invocationContext *agen
 		}
 
 		// Get system instructions from the agent
-		instructions := llmAgent.SystemInstructions
-		if instructions == "" {
+		instructions, err := p.instructions(ctx, invocationContext, llmAgent)
+		if err != nil {
+			log.Printf("Error building instructions for agent %s: %v", llmAgent.Name(), err)
+			eventCh <- newErrorEvent(invocationContext, events.ErrorCodeInstruction, err)
 			return
 		}
-
-		// Initialize content if not present
-		if llmRequest.Contents == nil {
-			llmRequest.Contents = &models.Content{
-				Parts: make([]*models.Part, 0),
-			}
-		}
-
-		// Create a system part with the instructions
-		systemPart := &models.Part{
-			Text: instructions,
-			Role: "system",
+		if instructions == "" {
+			return
 		}
 
-		// Add the system part at the beginning of the content parts
-		newParts := []*models.Part{systemPart}
-		newParts = append(newParts, llmRequest.Contents.Parts...)
-		llmRequest.Contents.Parts = newParts
+		appendInstructions(llmRequest, instructions)
 	}()
 
 	return eventCh, nil
 }
+
+// instructions returns the agent's instructions for this invocation, either
+// from its InstructionProvider or by resolving the placeholders in its
+// SystemInstructions
+func (p *InstructionsProcessor) instructions(ctx context.Context, invocationContext *agents.InvocationContext, llmAgent *agents.LlmAgent) (string, error) {
+	if llmAgent.InstructionProvider != nil {
+		return llmAgent.InstructionProvider(ctx, invocationContext)
+	}
+
+	if llmAgent.SystemInstructions == "" {
+		return "", nil
+	}
+	return agents.InjectSessionState(ctx, llmAgent.SystemInstructions, invocationContext)
+}
+
+// appendInstructions adds instructions to the system instructions of the request
+func appendInstructions(llmRequest *models.LlmRequest, instructions string) {
+	if llmRequest.SystemInstructions != "" {
+		llmRequest.SystemInstructions += "\n\n"
+	}
+	llmRequest.SystemInstructions += instructions
+}
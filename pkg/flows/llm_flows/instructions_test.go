@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+func TestInstructionsProcessor(t *testing.T) {
+	tests := []struct {
+		name             string
+		instructions     string
+		provider         agents.InstructionProvider
+		wantInstructions string
+		wantErrorCode    string
+	}{
+		{
+			name:             "template",
+			instructions:     "Answer in {user:language}{suffix?}.",
+			wantInstructions: "Existing.\n\nAnswer in French.",
+		},
+		{
+			name:          "template with a missing value",
+			instructions:  "Answer in {language}.",
+			wantErrorCode: events.ErrorCodeInstruction,
+		},
+		{
+			name:         "provider",
+			instructions: "ignored",
+			provider: func(ctx context.Context, invocationContext *agents.InvocationContext) (string, error) {
+				language, _ := invocationContext.GetState("user:language")
+				return "Answer in " + language.(string) + ", keep {braces}.", nil
+			},
+			wantInstructions: "Existing.\n\nAnswer in French, keep {braces}.",
+		},
+		{
+			name:             "no instructions",
+			wantInstructions: "Existing.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := agents.NewLlmAgent("agent", nil)
+			agent.SystemInstructions = test.instructions
+			agent.InstructionProvider = test.provider
+
+			invocationContext := agents.NewInvocationContext("invocation", agent, &types.RunConfig{})
+			invocationContext.Session = sessions.NewSession("app", "user", map[string]interface{}{"user:language": "French"}, "session")
+			llmRequest := &models.LlmRequest{SystemInstructions: "Existing."}
+
+			eventCh, err := NewInstructionsProcessor().Run(context.Background(), invocationContext, llmRequest)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			var result []*events.Event
+			for event := range eventCh {
+				result = append(result, event)
+			}
+
+			if test.wantErrorCode != "" {
+				if len(result) != 1 || result[0].ErrorCode != test.wantErrorCode {
+					t.Fatalf("events = %+v, want one %s error", result, test.wantErrorCode)
+				}
+				return
+			}
+			if len(result) != 0 {
+				t.Errorf("unexpected events: %+v", result)
+			}
+			if llmRequest.SystemInstructions != test.wantInstructions {
+				t.Errorf("instructions = %q, want %q", llmRequest.SystemInstructions, test.wantInstructions)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+)
+
+// OutputSchemaProcessor asks the model for JSON output matching the agent's OutputSchema
+type OutputSchemaProcessor struct{}
+
+// NewOutputSchemaProcessor creates a new OutputSchemaProcessor
+func NewOutputSchemaProcessor() *OutputSchemaProcessor {
+	return &OutputSchemaProcessor{}
+}
+
+// Run sets the response MIME type and schema on the LLM request
+func (p *OutputSchemaProcessor) Run(ctx context.Context, invocationContext *agents.InvocationContext, llmRequest *models.LlmRequest) (<-chan *events.Event, error) {
+	eventCh := make(chan *events.Event)
+
+	go func() {
+		defer close(eventCh)
+
+		llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+		if !ok || llmAgent.OutputSchema == nil {
+			return
+		}
+
+		llmRequest.SetOutputSchema(llmAgent.OutputSchema)
+	}()
+
+	return eventCh, nil
+}
+
+// validateOutput checks a final text response against the agent's
+// OutputSchema and returns the violations found. Other responses, and
+// responses of agents without an OutputSchema, are not checked.
+func validateOutput(invocationContext *agents.InvocationContext, llmResponse *models.LlmResponse) []string {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok || llmAgent.OutputSchema == nil {
+		return nil
+	}
+
+	if llmResponse.Partial || llmResponse.ErrorCode != "" {
+		return nil
+	}
+
+	text, ok := responseText(&events.Event{Content: llmResponse.Content})
+	if !ok {
+		return nil
+	}
+
+	value, err := models.ParseJSONResponse(text)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return models.ValidateSchema(llmAgent.OutputSchema, value)
+}
+
+// outputSchemaRetries returns how many times the model may repair a response
+// that does not match the output schema
+func outputSchemaRetries(invocationContext *agents.InvocationContext) int {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok || llmAgent.OutputSchemaRetries < 0 {
+		return 0
+	}
+	return llmAgent.OutputSchemaRetries
+}
+
+// appendRepairRequest adds the rejected response and the validation errors
+// to the request so that the model can correct its output
+func appendRepairRequest(llmRequest *models.LlmRequest, rejected *models.LlmResponse, violations []string) {
+	if rejected.Content != nil {
+		for _, part := range rejected.Content.Parts {
+			if part.Text == "" || part.Thought {
+				continue
+			}
+			llmRequest.AppendContent(models.RoleAssistant, &models.Part{
+				Role: models.RoleAssistant,
+				Text: part.Text,
+			})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your previous response does not match the required output schema:\n")
+	for _, violation := range violations {
+		sb.WriteString(fmt.Sprintf("- %s\n", violation))
+	}
+	sb.WriteString("Respond again with only a JSON value that conforms to the schema.")
+
+	llmRequest.AppendContent(models.RoleUser, &models.Part{
+		Role: models.RoleUser,
+		Text: sb.String(),
+	})
+}
+
+// saveOutputToState writes the agent's final response into the event's state
+// delta under the agent's OutputKey, so that it is persisted with the event
+// and visible to later agents. With an OutputSchema the decoded JSON value is
+// stored instead of the text.
+func saveOutputToState(invocationContext *agents.InvocationContext, event *events.Event) {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok || llmAgent.OutputKey == "" {
+		return
+	}
+
+	if event.Partial || event.ErrorCode != "" || event.Author != llmAgent.Name() || !event.IsFinalResponse() {
+		return
+	}
+
+	text, ok := responseText(event)
+	if !ok {
+		return
+	}
+
+	// Structured output is stored as the decoded JSON value
+	var output interface{} = text
+	if llmAgent.OutputSchema != nil {
+		value, err := models.ParseJSONResponse(text)
+		if err != nil {
+			log.Printf("Error parsing output of agent %s: %v", llmAgent.Name(), err)
+			return
+		}
+		output = value
+	}
+
+	// Partial events of the same response share the actions, so the delta is
+	// written to a copy
+	actions := events.NewEventActions()
+	if event.Actions != nil {
+		copied := *event.Actions
+		actions = &copied
+		actions.StateDelta = make(map[string]interface{}, len(event.Actions.StateDelta)+1)
+		for key, value := range event.Actions.StateDelta {
+			actions.StateDelta[key] = value
+		}
+	}
+	actions.StateDelta[llmAgent.OutputKey] = output
+	event.Actions = actions
+}
+
+// responseText joins the text parts of an event, leaving out thoughts. It
+// reports false if the event has no text.
+func responseText(event *events.Event) (string, bool) {
+	if event.Content == nil || len(event.GetFunctionCalls()) > 0 || len(event.GetFunctionResponses()) > 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	found := false
+	for _, part := range event.Content.Parts {
+		if part.Text == "" || part.Thought {
+			continue
+		}
+		sb.WriteString(part.Text)
+		found = true
+	}
+	return sb.String(), found
+}
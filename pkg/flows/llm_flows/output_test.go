@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+)
+
+func TestOutputKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []*models.LlmResponse
+		schema    map[string]interface{}
+		want      interface{}
+	}{
+		{
+			name: "text without thoughts",
+			responses: []*models.LlmResponse{{Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{
+				{Text: "Let me think.", Thought: true},
+				{Text: "It is "},
+				{Text: "sunny."},
+			}}}},
+			want: "It is sunny.",
+		},
+		{
+			name: "after a tool call",
+			responses: []*models.LlmResponse{
+				callResponse("get_weather", "{}"),
+				textResponse("It is sunny."),
+			},
+			want: "It is sunny.",
+		},
+		{
+			name:      "structured output",
+			responses: []*models.LlmResponse{textResponse(`{"sky": "sunny"}`)},
+			schema:    map[string]interface{}{"type": "object"},
+			want:      map[string]interface{}{"sky": "sunny"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			agent := agents.NewLlmAgent("agent", &sequenceLlm{responses: test.responses})
+			agent.OutputKey = "weather"
+			agent.OutputSchema = test.schema
+			if test.schema == nil {
+				agent.CanonicalTools = []tools.Tool{newTool(t, "get_weather", func() string { return "sunny" })}
+			}
+
+			result := runAgent(t, agent)
+			if len(result) == 0 {
+				t.Fatalf("no events")
+			}
+
+			// Only the final response stores the output
+			for _, event := range result[:len(result)-1] {
+				if _, ok := event.Actions.StateDelta["weather"]; ok {
+					t.Errorf("event %+v stores the output before the final response", event.Content)
+				}
+			}
+			got := result[len(result)-1].Actions.StateDelta["weather"]
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("stored output = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOutputKeyIsVisibleToLaterAgents(t *testing.T) {
+	writer := agents.NewLlmAgent("writer", &sequenceLlm{responses: []*models.LlmResponse{textResponse("A short draft.")}})
+	writer.OutputKey = "draft"
+	reviewerModel := &sequenceLlm{responses: []*models.LlmResponse{textResponse("Looks good.")}}
+	reviewer := agents.NewLlmAgent("reviewer", reviewerModel)
+	reviewer.SystemInstructions = "Review this draft: {draft}"
+
+	runAgent(t, agents.NewSequentialAgent(agents.SequentialAgentConfig{
+		Name:      "pipeline",
+		SubAgents: []agents.BaseAgent{writer, reviewer},
+	}))
+
+	if reviewerModel.calls() != 1 {
+		t.Fatalf("reviewer model was called %d times, want once", reviewerModel.calls())
+	}
+	if instructions := reviewerModel.requests[0].SystemInstructions; !strings.Contains(instructions, "Review this draft: A short draft.") {
+		t.Errorf("reviewer instructions = %q, want the writer's draft", instructions)
+	}
+}
+
+func TestSaveOutputToStateLeavesSharedActionsAlone(t *testing.T) {
+	agent := agents.NewLlmAgent("agent", nil)
+	agent.OutputKey = "answer"
+	invocationContext := agents.NewInvocationContext("invocation", agent, nil)
+
+	newEvent := func(author string, partial bool, shared *events.EventActions) *events.Event {
+		event := events.NewEvent()
+		event.Author = author
+		event.Partial = partial
+		event.Actions = shared
+		event.Content = textResponse("Hello.").Content
+		return event
+	}
+
+	shared := events.NewEventActions()
+	shared.StateDelta["other"] = true
+
+	partial := newEvent("agent", true, shared)
+	saveOutputToState(invocationContext, partial)
+	if _, ok := partial.Actions.StateDelta["answer"]; ok {
+		t.Errorf("partial event stores the output")
+	}
+
+	otherAuthor := newEvent("someone", false, shared)
+	saveOutputToState(invocationContext, otherAuthor)
+	if _, ok := otherAuthor.Actions.StateDelta["answer"]; ok {
+		t.Errorf("event of another author stores the output")
+	}
+
+	final := newEvent("agent", false, shared)
+	saveOutputToState(invocationContext, final)
+	if final.Actions.StateDelta["answer"] != "Hello." || final.Actions.StateDelta["other"] != true {
+		t.Errorf("final state delta = %v, want the output and the existing delta", final.Actions.StateDelta)
+	}
+	if _, ok := shared.StateDelta["answer"]; ok {
+		t.Errorf("the shared actions were modified")
+	}
+}
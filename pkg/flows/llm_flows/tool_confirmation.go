@@ -0,0 +1,250 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+)
+
+// defaultConfirmationHint is shown to the user when a tool call requires
+// confirmation without saying why
+func defaultConfirmationHint(toolName string) string {
+	return fmt.Sprintf("Please approve or reject the call to %s.", toolName)
+}
+
+// requiredConfirmation returns what the user must confirm before a call runs,
+// or nil if the call can run right away
+func requiredConfirmation(llmAgent *agents.LlmAgent, tool tools.Tool, functionCall *models.FunctionCall, toolContext *tools.ToolContext) *events.ToolConfirmation {
+	if requirer, ok := tool.(tools.ConfirmationRequirer); ok && requirer.RequiresConfirmation() {
+		return &events.ToolConfirmation{Hint: defaultConfirmationHint(tool.Name())}
+	}
+
+	if llmAgent.ToolConfirmationPolicy == nil {
+		return nil
+	}
+
+	// Calls with invalid arguments are left to the tool to reject
+	args := make(map[string]interface{})
+	if functionCall.Arguments != "" {
+		if err := json.Unmarshal([]byte(functionCall.Arguments), &args); err != nil {
+			return nil
+		}
+	}
+	return llmAgent.ToolConfirmationPolicy(tool, args, toolContext)
+}
+
+// requestConfirmation records that a call waits for the user's approval
+func requestConfirmation(actions *events.EventActions, functionCallID string, confirmation *events.ToolConfirmation) {
+	if actions.RequestedToolConfirmations == nil {
+		actions.RequestedToolConfirmations = make(map[string]*events.ToolConfirmation)
+	}
+	actions.RequestedToolConfirmations[functionCallID] = confirmation
+}
+
+// GenerateConfirmationEvent creates the event asking the user to approve the
+// calls that requested confirmation, if any. Each request is a long-running
+// function call, so the event ends the invocation.
+func GenerateConfirmationEvent(invocationContext *agents.InvocationContext, functionCalls []*models.FunctionCall, functionResponseEvent *events.Event) *events.Event {
+	requested := functionResponseEvent.Actions.RequestedToolConfirmations
+	if len(requested) == 0 {
+		return nil
+	}
+
+	confirmationEvent := events.NewEvent()
+	confirmationEvent.InvocationID = invocationContext.InvocationID
+	confirmationEvent.Author = invocationContext.Agent.Name()
+	confirmationEvent.Branch = invocationContext.Branch
+
+	content := &models.Content{Parts: make([]*models.Part, 0, len(requested))}
+	for _, functionCall := range functionCalls {
+		confirmation, ok := requested[functionCall.ID]
+		if !ok {
+			continue
+		}
+		if confirmation.Hint == "" {
+			confirmation.Hint = defaultConfirmationHint(functionCall.Name)
+		}
+
+		arguments, err := json.Marshal(&events.ToolConfirmationRequest{
+			OriginalFunctionCall: functionCall,
+			ToolConfirmation:     confirmation,
+		})
+		if err != nil {
+			log.Printf("Error creating confirmation request for %s: %v", functionCall.Name, err)
+			continue
+		}
+
+		requestCall := &models.FunctionCall{
+			Name:      events.RequestConfirmationFunctionName,
+			Arguments: string(arguments),
+			ID:        uuid.New().String(),
+		}
+		content.Parts = append(content.Parts, &models.Part{FunctionCall: requestCall})
+		confirmationEvent.LongRunningToolIDs = append(confirmationEvent.LongRunningToolIDs, requestCall.ID)
+	}
+
+	if len(content.Parts) == 0 {
+		return nil
+	}
+	confirmationEvent.Content = content
+	return confirmationEvent
+}
+
+// functionResponseEvents returns the events to emit for a function response
+// event: the responses, followed by a confirmation request for the calls
+// waiting for approval. When every call waits, the request alone is emitted
+// and carries the actions of the calls. When every call is a long-running
+// call still in progress, nothing is emitted and the invocation ends on the
+// function call event. Either pause ends the whole invocation, so that the
+// agents after this one only run once the user answered.
+func functionResponseEvents(invocationContext *agents.InvocationContext, functionCalls []*models.FunctionCall, functionResponseEvent *events.Event) []*events.Event {
+	hasResponses := functionResponseEvent.Content != nil && len(functionResponseEvent.Content.Parts) > 0
+
+	confirmationEvent := GenerateConfirmationEvent(invocationContext, functionCalls, functionResponseEvent)
+	if confirmationEvent != nil || !hasResponses {
+		invocationContext.SetEndInvocation(true)
+	}
+
+	switch {
+	case confirmationEvent == nil && !hasResponses:
+		return nil
+	case confirmationEvent == nil:
+		return []*events.Event{functionResponseEvent}
+	case !hasResponses:
+		confirmationEvent.Actions = functionResponseEvent.Actions
+		return []*events.Event{confirmationEvent}
+	}
+	return []*events.Event{functionResponseEvent, confirmationEvent}
+}
+
+// resumeToolCalls answers the confirmation requests the user responded to in
+// the invocation's message: approved calls run, rejected calls get an error
+// response for the model. It returns false if the invocation ends here, for
+// instance because a resumed call asked for approval again or failed.
+func (f *BaseLlmFlow) resumeToolCalls(ctx context.Context, invocationContext *agents.InvocationContext, eventCh chan<- *events.Event) bool {
+	llmAgent, ok := invocationContext.Agent.(*agents.LlmAgent)
+	if !ok || invocationContext.InvocationEvent == nil {
+		return true
+	}
+
+	var answers []*models.FunctionResponse
+	for _, response := range invocationContext.InvocationEvent.GetFunctionResponses() {
+		if response.Name == events.RequestConfirmationFunctionName {
+			answers = append(answers, response)
+		}
+	}
+	if len(answers) == 0 {
+		return true
+	}
+
+	requests, answered := confirmationRequests(invocationContext, llmAgent.Name())
+
+	functionResponseEvent := events.NewEvent()
+	functionResponseEvent.InvocationID = invocationContext.InvocationID
+	functionResponseEvent.Author = llmAgent.Name()
+	functionResponseEvent.Branch = invocationContext.Branch
+	content := &models.Content{Parts: make([]*models.Part, 0, len(answers))}
+
+	var functionCalls []*models.FunctionCall
+	for _, answer := range answers {
+		// Answers to other agents' requests are theirs to handle; agents that
+		// continue after the resumed one see the same message
+		request, ok := requests[answer.ID]
+		if !ok {
+			continue
+		}
+
+		functionCall := request.OriginalFunctionCall
+		if answered[functionCall.ID] {
+			log.Printf("Tool call %s was already confirmed or rejected", functionCall.ID)
+			continue
+		}
+
+		confirmation, err := events.ParseToolConfirmation(answer)
+		if err != nil {
+			log.Printf("Error reading tool confirmation: %v", err)
+			continue
+		}
+		functionCalls = append(functionCalls, functionCall)
+
+		if !confirmation.Confirmed {
+			content.Parts = append(content.Parts, functionResponsePart(functionCall,
+				fmt.Sprintf("Error: the user rejected the call to %s", functionCall.Name)))
+			continue
+		}
+
+		part, err := runFunctionCall(ctx, invocationContext, llmAgent, functionCall, functionResponseEvent.Actions, confirmation)
+		if err != nil {
+			emitEvent(invocationContext, eventCh, newFailureEvent(invocationContext, err))
+			return false
+		}
+		if part != nil {
+			content.Parts = append(content.Parts, part)
+		}
+	}
+
+	if len(functionCalls) == 0 {
+		return true
+	}
+	functionResponseEvent.Content = content
+
+	responseEvents := functionResponseEvents(invocationContext, functionCalls, functionResponseEvent)
+	for _, event := range responseEvents {
+		emitEvent(invocationContext, eventCh, event)
+	}
+	if len(responseEvents) == 0 {
+		return true
+	}
+	return !responseEvents[len(responseEvents)-1].IsFinalResponse() && !invocationContext.IsEndInvocation()
+}
+
+// confirmationRequests returns the confirmation requests the agent made in the
+// history, keyed by request ID, and the IDs of the calls that already have a
+// response
+func confirmationRequests(invocationContext *agents.InvocationContext, agentName string) (map[string]*events.ToolConfirmationRequest, map[string]bool) {
+	requests := make(map[string]*events.ToolConfirmationRequest)
+	answered := make(map[string]bool)
+
+	for _, event := range invocationContext.GetEvents() {
+		if event.Content == nil {
+			continue
+		}
+		if event.Author == agentName {
+			for id, request := range event.GetToolConfirmationRequests() {
+				requests[id] = request
+			}
+		}
+		for _, response := range event.GetFunctionResponses() {
+			answered[response.ID] = true
+		}
+	}
+	return requests, answered
+}
+
+// isConfirmationPart reports whether a part is a confirmation request or the
+// user's answer to one. These are exchanged with the user, not the model.
+func isConfirmationPart(part *models.Part) bool {
+	return (part.FunctionCall != nil && part.FunctionCall.Name == events.RequestConfirmationFunctionName) ||
+		(part.FunctionResponse != nil && part.FunctionResponse.Name == events.RequestConfirmationFunctionName)
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llm_flows
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// scriptedLlm is a model that always answers with the same response and
+// counts its calls
+type scriptedLlm struct {
+	response *models.LlmResponse
+	calls    int32
+}
+
+func (m *scriptedLlm) SupportedModels() []string { return nil }
+
+func (m *scriptedLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	atomic.AddInt32(&m.calls, 1)
+	return m.response, nil
+}
+
+func (m *scriptedLlm) GenerateContentStream(ctx context.Context, request *models.LlmRequest) (<-chan *models.LlmResponse, error) {
+	response, err := m.GenerateContent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	responseCh := make(chan *models.LlmResponse, 1)
+	responseCh <- response
+	close(responseCh)
+	return responseCh, nil
+}
+
+func (m *scriptedLlm) Connect(ctx context.Context, request *models.LlmRequest) (models.LlmConnection, error) {
+	return nil, fmt.Errorf("live connections are not supported")
+}
+
+func TestConfirmationEndsSequentialAgent(t *testing.T) {
+	payCalls := int32(0)
+	pay, err := tools.NewFunctionTool(func() string {
+		atomic.AddInt32(&payCalls, 1)
+		return "paid"
+	}, tools.FunctionToolConfig{Name: "pay", RequireConfirmation: true})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+
+	payer := agents.NewLlmAgent("payer", &scriptedLlm{response: &models.LlmResponse{
+		Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{
+			{FunctionCall: &models.FunctionCall{Name: "pay", ID: "call", Arguments: "{}"}},
+		}},
+	}})
+	payer.CanonicalTools = []tools.Tool{pay}
+
+	nextModel := &scriptedLlm{response: &models.LlmResponse{
+		Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{{Text: "done"}}},
+	}}
+	next := agents.NewLlmAgent("next", nextModel)
+
+	sequential := agents.NewSequentialAgent(agents.SequentialAgentConfig{
+		Name:      "sequential",
+		SubAgents: []agents.BaseAgent{payer, next},
+	})
+
+	invocationContext := agents.NewInvocationContext("invocation", sequential, &types.RunConfig{})
+	eventCh, err := sequential.Run(context.Background(), invocationContext)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	confirmations := 0
+	for event := range eventCh {
+		if event.ErrorCode != "" {
+			t.Errorf("unexpected error event: %s %s", event.ErrorCode, event.ErrorMessage)
+		}
+		confirmations += len(event.GetToolConfirmationRequests())
+	}
+
+	if confirmations != 1 {
+		t.Errorf("got %d confirmation requests, want 1", confirmations)
+	}
+	if calls := atomic.LoadInt32(&payCalls); calls != 0 {
+		t.Errorf("tool ran %d times before it was confirmed", calls)
+	}
+	if calls := atomic.LoadInt32(&nextModel.calls); calls != 0 {
+		t.Errorf("agent after the paused one ran %d model calls", calls)
+	}
+}
@@ -15,6 +15,7 @@
 package models
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -41,27 +42,54 @@ type GeminiLLM struct {
 // geminiRequest represents a request to the Gemini API
 type geminiRequest struct {
 	Contents          []geminiContent        `json:"contents"`
-	SystemInstruction string                 `json:"systemInstruction,omitempty"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
 	Tools             []geminiTool           `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig      `json:"toolConfig,omitempty"`
 	GenerationConfig  geminiGenerationConfig `json:"generationConfig,omitempty"`
 }
 
 // geminiContent represents a message with role and parts in the Gemini API format
 type geminiContent struct {
-	Role  string       `json:"role"`
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
 // geminiPart represents a part of content in the Gemini API format
 type geminiPart struct {
-	Text       string      `json:"text,omitempty"`
-	InlineData *inlineData `json:"inlineData,omitempty"`
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *inlineData             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
 }
 
-// inlineData represents inline binary data with MIME type
+// geminiFunctionCall represents a function call in the Gemini API format
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// geminiFunctionResponse represents a function response in the Gemini API
+// format. The response must be a JSON object.
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+// inlineData represents inline binary data with MIME type. Data is encoded
+// as base64 in JSON, as the API expects.
 type inlineData struct {
 	MimeType string `json:"mimeType"`
-	Data     string `json:"data"`
+	Data     []byte `json:"data"`
+}
+
+// geminiFileData represents a file reference in the Gemini API format
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
 }
 
 // geminiTool represents a tool in the Gemini API format
@@ -76,6 +104,18 @@ type geminiFunctionDeclaration struct {
 	Parameters  interface{} `json:"parameters,omitempty"`
 }
 
+// geminiToolConfig represents the tool config in Gemini format
+type geminiToolConfig struct {
+	FunctionCallingConfig *geminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// geminiFunctionCallingConfig represents the function calling config in
+// Gemini format
+type geminiFunctionCallingConfig struct {
+	Mode                 FunctionCallingMode `json:"mode,omitempty"`
+	AllowedFunctionNames []string            `json:"allowedFunctionNames,omitempty"`
+}
+
 // geminiGenerationConfig represents generation config for Gemini requests
 type geminiGenerationConfig struct {
 	Temperature     float64  `json:"temperature,omitempty"`
@@ -83,12 +123,17 @@ type geminiGenerationConfig struct {
 	TopK            int      `json:"topK,omitempty"`
 	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
 	StopSequences   []string `json:"stopSequences,omitempty"`
+
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
 }
 
 // geminiResponse represents a response from the Gemini API
 type geminiResponse struct {
 	Candidates     []geminiCandidate `json:"candidates"`
 	PromptFeedback *promptFeedback   `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata    `json:"usageMetadata,omitempty"`
+	ModelVersion   string            `json:"modelVersion,omitempty"`
 }
 
 // geminiCandidate represents a candidate in a Gemini response
@@ -192,7 +237,7 @@ func (g *GeminiLLM) GenerateContentStream(ctx context.Context, request *LlmReque
 		return nil, err
 	}
 
-	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s",
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s",
 		g.endpoint, g.ModelName, g.apiKey)
 
 	reqBody, err := json.Marshal(geminiReq)
@@ -225,61 +270,114 @@ func (g *GeminiLLM) GenerateContentStream(ctx context.Context, request *LlmReque
 		defer resp.Body.Close()
 		defer close(responseChan)
 
-		decoder := json.NewDecoder(resp.Body)
-		fullText := ""
+		fullThought, fullText := "", ""
 
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				var chunk geminiResponse
-				if err := decoder.Decode(&chunk); err != nil {
-					if err != io.EOF {
-						// Send error only if it's not EOF
-						errResp := &LlmResponse{
-							ErrorMessage: fmt.Sprintf("Error: %v", err),
-						}
-						responseChan <- errResp
-					}
-					return
-				}
+		err := readServerSentEvents(resp.Body, func(data []byte) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
-				// Convert the chunk to an LlmResponse
-				llmResp := g.createResponse(&chunk)
-
-				// Handle streaming text aggregation
-				if text := g.extractTextFromResponse(llmResp); text != "" {
-					fullText += text
-
-					// Mark as partial unless it's the final chunk
-					if len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "" {
-						// Send the accumulated text as the final non-partial message
-						finalResp := &LlmResponse{
-							Content: &Content{
-								Parts: []*Part{{
-									Text: fullText,
-									Role: "assistant",
-								}},
-							},
-							Partial: false,
-						}
-						responseChan <- finalResp
-					} else {
-						// This is a partial response
-						llmResp.Partial = true
-						responseChan <- llmResp
-					}
-				} else {
-					responseChan <- llmResp
-				}
+			var chunk geminiResponse
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+			}
+
+			// Convert the chunk to an LlmResponse
+			llmResp := g.createResponse(&chunk)
+
+			// Thoughts and text are streamed as partial responses and sent
+			// again in full with the final chunk
+			thought, text := streamedText(llmResp)
+			fullThought += thought
+			fullText += text
+
+			switch {
+			case len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "":
+				responseChan <- streamFinalResponse(fullThought, fullText, llmResp)
+			case thought != "" || text != "":
+				llmResp.Partial = true
+				responseChan <- llmResp
+			default:
+				responseChan <- llmResp
 			}
+			return nil
+		})
+		if err != nil && ctx.Err() == nil {
+			responseChan <- &LlmResponse{ErrorMessage: fmt.Sprintf("Error: %v", err)}
 		}
 	}()
 
 	return responseChan, nil
 }
 
+// readServerSentEvents reads a stream of server-sent events, as sent by the
+// API for alt=sse, and calls handle with the data of each event. It stops at
+// the first error handle returns.
+func readServerSentEvents(r io.Reader, handle func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			// A blank line ends the event
+			if len(data) > 0 {
+				if err := handle(data); err != nil {
+					return err
+				}
+				data = nil
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(line[len("data:"):], []byte(" "))...)
+		}
+		// Other fields, such as event types and comments, are not used
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(data) > 0 {
+		return handle(data)
+	}
+	return nil
+}
+
+// streamFinalResponse builds the final, non-partial response of a stream: the
+// thoughts and text of all chunks, followed by the other parts of the final
+// chunk, such as function calls
+func streamFinalResponse(fullThought, fullText string, finalChunk *LlmResponse) *LlmResponse {
+	var parts []*Part
+	if fullThought != "" {
+		parts = append(parts, &Part{Text: fullThought, Thought: true, Role: "assistant"})
+	}
+	if fullText != "" {
+		parts = append(parts, &Part{Text: fullText, Role: "assistant"})
+	}
+	if finalChunk.Content != nil {
+		for _, part := range finalChunk.Content.Parts {
+			if part.Text == "" {
+				part.Role = "assistant"
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	response := &LlmResponse{
+		ErrorMessage:  finalChunk.ErrorMessage,
+		UsageMetadata: finalChunk.UsageMetadata,
+		ModelVersion:  finalChunk.ModelVersion,
+	}
+	if len(parts) > 0 {
+		response.Content = &Content{Role: RoleAssistant, Parts: parts}
+	}
+	return response
+}
+
 // Connect establishes a real-time connection with the model.
 func (g *GeminiLLM) Connect(ctx context.Context, request *LlmRequest) (LlmConnection, error) {
 	// This would be implemented with the Gemini API's bidirectional streaming
@@ -289,35 +387,26 @@ func (g *GeminiLLM) Connect(ctx context.Context, request *LlmRequest) (LlmConnec
 
 // createGeminiRequest converts LlmRequest to geminiRequest
 func (g *GeminiLLM) createGeminiRequest(request *LlmRequest) (*geminiRequest, error) {
-	// Create geminiContent array from existing message structure
+	// Convert the turns, merging consecutive parts of the same role
 	var contents []geminiContent
-
-	// Handle system instructions if specified
-	if request.SystemInstructions != "" {
-		// Add system instructions as a user message
-		contents = append(contents, geminiContent{
-			Role: "user",
-			Parts: []geminiPart{{
-				Text: request.SystemInstructions,
-			}},
-		})
-	}
-
-	// Convert contents from message parts
-	if request.Contents != nil && len(request.Contents.Parts) > 0 {
-		for _, part := range request.Contents.Parts {
-			var role string
-			if part.Role == "" {
-				role = "user" // Default role
-			} else {
-				role = part.Role
+	for _, content := range request.Contents {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			converted, err := toGeminiPart(part)
+			if err != nil {
+				return nil, err
 			}
 
-			geminiParts := []geminiPart{{Text: part.Text}}
-
+			role := geminiRole(content.Role, part)
+			if n := len(contents); n > 0 && contents[n-1].Role == role {
+				contents[n-1].Parts = append(contents[n-1].Parts, converted)
+				continue
+			}
 			contents = append(contents, geminiContent{
 				Role:  role,
-				Parts: geminiParts,
+				Parts: []geminiPart{converted},
 			})
 		}
 	}
@@ -332,61 +421,76 @@ func (g *GeminiLLM) createGeminiRequest(request *LlmRequest) (*geminiRequest, er
 		})
 	}
 
-	// Ensure the last message is from the user so Gemini responds
-	if len(contents) > 0 && contents[len(contents)-1].Role != "user" {
-		contents = append(contents, geminiContent{
-			Role: "user",
-			Parts: []geminiPart{{
-				Text: "Continue processing previous requests as instructed.",
-			}},
-		})
+	var systemInstruction *geminiContent
+	if request.SystemInstructions != "" {
+		systemInstruction = &geminiContent{
+			Parts: []geminiPart{{Text: request.SystemInstructions}},
+		}
 	}
 
-	// Convert tools if present
+	// Declare all functions in a single tool
 	var tools []geminiTool
-	if request.Tools != nil && len(request.Tools) > 0 {
+	if len(request.Tools) > 0 {
+		declarations := make([]geminiFunctionDeclaration, 0, len(request.Tools))
 		for _, tool := range request.Tools {
-			geminiTool := geminiTool{
-				FunctionDeclarations: []geminiFunctionDeclaration{
-					{
-						Name:        tool.Name,
-						Description: tool.Description,
-						Parameters:  tool.InputSchema,
-					},
-				},
+			declaration := geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+			}
+			if len(tool.InputSchema) > 0 {
+				declaration.Parameters = tool.InputSchema
 			}
-			tools = append(tools, geminiTool)
+			declarations = append(declarations, declaration)
+		}
+		tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+
+	var toolConfig *geminiToolConfig
+	if request.ToolConfig != nil {
+		toolConfig = &geminiToolConfig{
+			FunctionCallingConfig: &geminiFunctionCallingConfig{
+				Mode:                 request.ToolConfig.Mode,
+				AllowedFunctionNames: request.ToolConfig.AllowedFunctionNames,
+			},
 		}
 	}
 
 	return &geminiRequest{
 		Contents:          contents,
-		SystemInstruction: request.SystemInstructions,
+		SystemInstruction: systemInstruction,
 		Tools:             tools,
+		ToolConfig:        toolConfig,
 		GenerationConfig: geminiGenerationConfig{
 			Temperature:     request.Temperature,
 			TopP:            request.TopP,
 			TopK:            request.TopK,
 			MaxOutputTokens: request.MaxTokens,
+
+			ResponseMimeType: request.ResponseMimeType,
+			ResponseSchema:   request.ResponseSchema,
 		},
 	}, nil
 }
 
 // createResponse converts geminiResponse to LlmResponse
 func (g *GeminiLLM) createResponse(geminiResp *geminiResponse) *LlmResponse {
-	response := &LlmResponse{}
+	response := &LlmResponse{
+		UsageMetadata: geminiResp.UsageMetadata,
+		ModelVersion:  geminiResp.ModelVersion,
+	}
+	if response.ModelVersion == "" {
+		response.ModelVersion = g.ModelName
+	}
 
 	if len(geminiResp.Candidates) > 0 {
 		candidate := geminiResp.Candidates[0]
 		content := &Content{
+			Role:  RoleAssistant,
 			Parts: make([]*Part, len(candidate.Content.Parts)),
 		}
 
 		for i, part := range candidate.Content.Parts {
-			content.Parts[i] = &Part{
-				Text: part.Text,
-				Role: candidate.Content.Role,
-			}
+			content.Parts[i] = fromGeminiPart(part, candidate.Content.Role)
 		}
 
 		response.Content = content
@@ -407,16 +511,145 @@ func (g *GeminiLLM) createResponse(geminiResp *geminiResponse) *LlmResponse {
 	return response
 }
 
-// extractTextFromResponse gets text from LlmResponse
-func (g *GeminiLLM) extractTextFromResponse(resp *LlmResponse) string {
-	if resp.Content != nil && len(resp.Content.Parts) > 0 {
-		for _, part := range resp.Content.Parts {
-			if part.Text != "" {
-				return part.Text
+// toGeminiPart converts a part to the Gemini API format. Function arguments
+// and responses are sent as JSON objects, with the call IDs that pair them.
+func toGeminiPart(part *Part) (geminiPart, error) {
+	switch {
+	case part.FunctionCall != nil:
+		args := json.RawMessage("{}")
+		if part.FunctionCall.Arguments != "" {
+			args = json.RawMessage(part.FunctionCall.Arguments)
+			if !isJSONObject(args) {
+				return geminiPart{}, fmt.Errorf("arguments of function call %s are not a JSON object", part.FunctionCall.Name)
 			}
 		}
+		return geminiPart{FunctionCall: &geminiFunctionCall{
+			ID:   part.FunctionCall.ID,
+			Name: part.FunctionCall.Name,
+			Args: args,
+		}}, nil
+
+	case part.FunctionResponse != nil:
+		response, err := functionResponseObject(part.FunctionResponse.Content)
+		if err != nil {
+			return geminiPart{}, fmt.Errorf("invalid response of function %s: %w", part.FunctionResponse.Name, err)
+		}
+		return geminiPart{FunctionResponse: &geminiFunctionResponse{
+			ID:       part.FunctionResponse.ID,
+			Name:     part.FunctionResponse.Name,
+			Response: response,
+		}}, nil
+
+	case part.InlineData != nil:
+		return geminiPart{InlineData: &inlineData{
+			MimeType: part.InlineData.MimeType,
+			Data:     part.InlineData.Data,
+		}}, nil
+
+	case part.FileData != nil:
+		return geminiPart{FileData: &geminiFileData{
+			MimeType: part.FileData.MimeType,
+			FileURI:  part.FileData.FileURI,
+		}}, nil
+	}
+
+	return geminiPart{Text: part.Text, Thought: part.Thought}, nil
+}
+
+// fromGeminiPart converts a part in the Gemini API format to a part
+func fromGeminiPart(part geminiPart, role string) *Part {
+	result := &Part{Text: part.Text, Thought: part.Thought, Role: role}
+
+	if part.FunctionCall != nil {
+		arguments := "{}"
+		if len(part.FunctionCall.Args) > 0 {
+			arguments = string(part.FunctionCall.Args)
+		}
+		result.FunctionCall = &FunctionCall{
+			Name:      part.FunctionCall.Name,
+			Arguments: arguments,
+			ID:        part.FunctionCall.ID,
+		}
+	}
+
+	if part.FunctionResponse != nil {
+		result.FunctionResponse = &FunctionResponse{
+			Name:    part.FunctionResponse.Name,
+			Content: string(part.FunctionResponse.Response),
+			ID:      part.FunctionResponse.ID,
+		}
+	}
+
+	if part.InlineData != nil {
+		result.InlineData = &Blob{
+			MimeType: part.InlineData.MimeType,
+			Data:     part.InlineData.Data,
+		}
+	}
+
+	if part.FileData != nil {
+		result.FileData = &FileData{
+			FileURI:  part.FileData.FileURI,
+			MimeType: part.FileData.MimeType,
+		}
+	}
+
+	return result
+}
+
+// geminiRole returns the Gemini role of a part in a turn of the given role.
+// Function calls belong to the model and function responses to the user,
+// whatever the role of their turn.
+func geminiRole(role string, part *Part) string {
+	if role == "" {
+		role = part.Role
+	}
+
+	switch {
+	case part.FunctionCall != nil:
+		return "model"
+	case part.FunctionResponse != nil:
+		return "user"
+	case role == RoleAssistant, role == "model":
+		return "model"
 	}
-	return ""
+	return "user"
+}
+
+// functionResponseObject returns a function response as a JSON object.
+// Responses that are not JSON objects are wrapped as {"result": response}.
+func functionResponseObject(content string) (json.RawMessage, error) {
+	raw := json.RawMessage(content)
+	if isJSONObject(raw) {
+		return raw, nil
+	}
+
+	var result interface{} = content
+	if json.Valid(raw) {
+		result = raw
+	}
+	return json.Marshal(map[string]interface{}{"result": result})
+}
+
+// isJSONObject reports whether data is a JSON object
+func isJSONObject(data []byte) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal(data, &object) == nil && object != nil
+}
+
+// streamedText returns the thought and answer text of a streamed chunk
+func streamedText(resp *LlmResponse) (thought, text string) {
+	if resp.Content == nil {
+		return "", ""
+	}
+	for _, part := range resp.Content.Parts {
+		if part.Thought {
+			thought += part.Text
+		} else {
+			text += part.Text
+		}
+	}
+	return thought, text
 }
 
 // getUserAgent returns a user agent string for API tracking
@@ -448,25 +681,7 @@ type GeminiModel struct {
 
 // Generate implements the Model interface
 func (m *GeminiModel) Generate(ctx context.Context, messages []Message) (string, error) {
-	// Convert Messages to geminiRequest
-	contents := make([]geminiContent, len(messages))
-	for i, msg := range messages {
-		role := msg.Role
-		if role == "system" {
-			role = "user" // Gemini doesn't support system role directly
-		}
-
-		contents[i] = geminiContent{
-			Role: role,
-			Parts: []geminiPart{
-				{Text: msg.Content},
-			},
-		}
-	}
-
-	req := &geminiRequest{
-		Contents: contents,
-	}
+	req := geminiRequestFromMessages(messages)
 
 	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s",
 		m.endpoint, m.name, m.apiKey)
@@ -513,27 +728,9 @@ func (m *GeminiModel) Generate(ctx context.Context, messages []Message) (string,
 
 // GenerateStream implements streaming for the Model interface
 func (m *GeminiModel) GenerateStream(ctx context.Context, messages []Message) (chan StreamedResponse, error) {
-	// Convert Messages to geminiRequest
-	contents := make([]geminiContent, len(messages))
-	for i, msg := range messages {
-		role := msg.Role
-		if role == "system" {
-			role = "user" // Gemini doesn't support system role directly
-		}
-
-		contents[i] = geminiContent{
-			Role: role,
-			Parts: []geminiPart{
-				{Text: msg.Content},
-			},
-		}
-	}
-
-	req := &geminiRequest{
-		Contents: contents,
-	}
+	req := geminiRequestFromMessages(messages)
 
-	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s",
+	url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s",
 		m.endpoint, m.name, m.apiKey)
 
 	reqBody, err := json.Marshal(req)
@@ -566,44 +763,40 @@ func (m *GeminiModel) GenerateStream(ctx context.Context, messages []Message) (c
 		defer resp.Body.Close()
 		defer close(streamChan)
 
-		decoder := json.NewDecoder(resp.Body)
+		// errDone stops reading once the last chunk was sent
+		errDone := errors.New("stream done")
 
-		for {
-			select {
-			case <-ctx.Done():
-				streamChan <- StreamedResponse{
-					Error: ctx.Err(),
-					Done:  true,
-				}
-				return
-			default:
-				var chunk geminiResponse
-				if err := decoder.Decode(&chunk); err != nil {
-					if err != io.EOF {
-						// Send error only if it's not EOF
-						streamChan <- StreamedResponse{
-							Error: err,
-							Done:  true,
-						}
-					}
-					return
-				}
+		err := readServerSentEvents(resp.Body, func(data []byte) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
-				if len(chunk.Candidates) == 0 || len(chunk.Candidates[0].Content.Parts) == 0 {
-					continue
-				}
+			var chunk geminiResponse
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return err
+			}
 
-				isLast := chunk.Candidates[0].FinishReason != ""
-				text := chunk.Candidates[0].Content.Parts[0].Text
+			if len(chunk.Candidates) == 0 || len(chunk.Candidates[0].Content.Parts) == 0 {
+				return nil
+			}
 
-				streamChan <- StreamedResponse{
-					Content: text,
-					Done:    isLast,
-				}
+			isLast := chunk.Candidates[0].FinishReason != ""
+			text := chunk.Candidates[0].Content.Parts[0].Text
 
-				if isLast {
-					return
-				}
+			streamChan <- StreamedResponse{
+				Content: text,
+				Done:    isLast,
+			}
+
+			if isLast {
+				return errDone
+			}
+			return nil
+		})
+		if err != nil && err != errDone {
+			streamChan <- StreamedResponse{
+				Error: err,
+				Done:  true,
 			}
 		}
 	}()
@@ -628,5 +821,41 @@ func init() {
 			// Log error but continue
 			fmt.Printf("Error registering Gemini pattern %s: %v\n", pattern, err)
 		}
+
+		err = registry.RegisterLLMPattern(pattern, func(modelName string) (LLM, error) {
+			return NewGeminiLLM(modelName)
+		})
+		if err != nil {
+			fmt.Printf("Error registering Gemini LLM pattern %s: %v\n", pattern, err)
+		}
+	}
+}
+
+// geminiRequestFromMessages converts messages to a geminiRequest. System
+// messages become the system instruction and consecutive messages of the same
+// role are merged into one turn.
+func geminiRequestFromMessages(messages []Message) *geminiRequest {
+	req := &geminiRequest{}
+	for _, msg := range messages {
+		part := geminiPart{Text: msg.Content}
+
+		if msg.Role == "system" {
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, part)
+			continue
+		}
+
+		role := geminiRole(msg.Role, &Part{})
+		if n := len(req.Contents); n > 0 && req.Contents[n-1].Role == role {
+			req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, part)
+			continue
+		}
+		req.Contents = append(req.Contents, geminiContent{
+			Role:  role,
+			Parts: []geminiPart{part},
+		})
 	}
+	return req
 }
@@ -0,0 +1,453 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestGemini returns a Gemini client talking to a test server. The server
+// records each request and answers with the given body, as server-sent events
+// for streaming requests.
+func newTestGemini(t *testing.T, body string, requests *[]geminiRequest) *GeminiLLM {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		*requests = append(*requests, request)
+
+		contentType := "application/json"
+		if strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			if alt := r.URL.Query().Get("alt"); alt != "sse" {
+				t.Errorf("streaming request with alt=%q, want sse", alt)
+			}
+			contentType = "text/event-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GOOGLE_API_KEY", "test-key")
+	t.Setenv("GEMINI_API_ENDPOINT", server.URL)
+	gemini, err := NewGeminiLLM("gemini-test")
+	if err != nil {
+		t.Fatalf("NewGeminiLLM: %v", err)
+	}
+	return gemini
+}
+
+// geminiTurns describes the turns of a Gemini request as "role: text text"
+func geminiTurns(contents []geminiContent) []string {
+	var turns []string
+	for _, content := range contents {
+		turn := content.Role + ":"
+		for _, part := range content.Parts {
+			turn += " " + part.Text
+		}
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+func TestGeminiFunctionCallRoundTrip(t *testing.T) {
+	var requests []geminiRequest
+	gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [
+		{"functionCall": {"id": "call-2", "name": "get_weather", "args": {"city": "Paris"}}}
+	]}, "finishReason": "STOP"}]}`, &requests)
+
+	request := &LlmRequest{}
+	request.AppendContent(RoleUser, &Part{Text: "Weather in Rome and Paris?"})
+	request.AppendContent(RoleAssistant, &Part{FunctionCall: &FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`, ID: "call-1"}})
+	request.AppendContent(RoleUser, &Part{FunctionResponse: &FunctionResponse{Name: "get_weather", Content: "sunny", ID: "call-1"}})
+	request.AppendTools(&Tool{Name: "get_weather", InputSchema: map[string]interface{}{"type": "object"}})
+
+	response, err := gemini.GenerateContent(context.Background(), request)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	sent := requests[0]
+	var roles []string
+	for _, content := range sent.Contents {
+		roles = append(roles, content.Role)
+	}
+	if want := []string{"user", "model", "user"}; !reflect.DeepEqual(roles, want) {
+		t.Fatalf("roles = %v, want %v", roles, want)
+	}
+	if call := sent.Contents[1].Parts[0].FunctionCall; call == nil || call.Name != "get_weather" || string(call.Args) != `{"city":"Rome"}` || call.ID != "call-1" {
+		t.Errorf("function call sent as %+v", call)
+	}
+	if result := sent.Contents[2].Parts[0].FunctionResponse; result == nil || string(result.Response) != `{"result":"sunny"}` || result.ID != "call-1" {
+		t.Errorf("function response sent as %+v", result)
+	}
+	if len(sent.Tools) != 1 || len(sent.Tools[0].FunctionDeclarations) != 1 || sent.Tools[0].FunctionDeclarations[0].Name != "get_weather" {
+		t.Errorf("tools sent as %+v", sent.Tools)
+	}
+
+	calls := response.Content.Parts
+	if len(calls) != 1 || calls[0].FunctionCall == nil || calls[0].FunctionCall.ID != "call-2" {
+		t.Fatalf("response parts = %+v, want one function call with its ID", calls)
+	}
+	var args map[string]string
+	if err := json.Unmarshal([]byte(calls[0].FunctionCall.Arguments), &args); err != nil || args["city"] != "Paris" {
+		t.Errorf("function call arguments = %q", calls[0].FunctionCall.Arguments)
+	}
+}
+
+func TestGeminiToolConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		toolConfig *ToolConfig
+		want       *geminiToolConfig
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:       "any with allowed functions",
+			toolConfig: &ToolConfig{Mode: FunctionCallingModeAny, AllowedFunctionNames: []string{"get_weather"}},
+			want: &geminiToolConfig{FunctionCallingConfig: &geminiFunctionCallingConfig{
+				Mode:                 FunctionCallingModeAny,
+				AllowedFunctionNames: []string{"get_weather"},
+			}},
+		},
+		{
+			name:       "none",
+			toolConfig: &ToolConfig{Mode: FunctionCallingModeNone},
+			want:       &geminiToolConfig{FunctionCallingConfig: &geminiFunctionCallingConfig{Mode: FunctionCallingModeNone}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []geminiRequest
+			gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`, &requests)
+
+			request := &LlmRequest{ToolConfig: test.toolConfig}
+			request.AppendContent(RoleUser, &Part{Text: "Hello"})
+			request.AppendTools(&Tool{Name: "get_weather"})
+			if _, err := gemini.GenerateContent(context.Background(), request); err != nil {
+				t.Fatalf("GenerateContent: %v", err)
+			}
+
+			if got := requests[0].ToolConfig; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("toolConfig = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGeminiStreamFinalResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		chunks      []string
+		wantThought string
+		wantText    string
+		wantCalls   int
+	}{
+		{
+			name: "text in the finish chunk",
+			chunks: []string{
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello, "}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "world"}]}, "finishReason": "STOP"}]}`,
+			},
+			wantText: "Hello, world",
+		},
+		{
+			name: "function call only in the finish chunk",
+			chunks: []string{
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Let me check."}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "get_weather", "args": {}}}]}, "finishReason": "STOP"}]}`,
+			},
+			wantText:  "Let me check.",
+			wantCalls: 1,
+		},
+		{
+			name: "empty finish chunk",
+			chunks: []string{
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello, "}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "world"}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": []}, "finishReason": "STOP"}]}`,
+			},
+			wantText: "Hello, world",
+		},
+		{
+			name: "thoughts kept apart from the text",
+			chunks: []string{
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "The user ", "thought": true}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "says hello.", "thought": true}, {"text": "Hello"}]}}]}`,
+				`{"candidates": [{"content": {"role": "model", "parts": [{"text": "!"}]}, "finishReason": "STOP"}]}`,
+			},
+			wantThought: "The user says hello.",
+			wantText:    "Hello!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requests []geminiRequest
+			gemini := newTestGemini(t, "data: "+strings.Join(test.chunks, "\r\n\r\ndata: ")+"\r\n\r\n", &requests)
+
+			request := &LlmRequest{}
+			request.AppendContent(RoleUser, &Part{Text: "Hello"})
+			responseCh, err := gemini.GenerateContentStream(context.Background(), request)
+			if err != nil {
+				t.Fatalf("GenerateContentStream: %v", err)
+			}
+
+			var final *LlmResponse
+			for response := range responseCh {
+				if response.ErrorMessage != "" {
+					t.Fatalf("stream error: %s", response.ErrorMessage)
+				}
+				if !response.Partial {
+					final = response
+				}
+			}
+			if final == nil || final.Content == nil {
+				t.Fatalf("no final response with content")
+			}
+
+			thought, text, calls := "", "", 0
+			for _, part := range final.Content.Parts {
+				if part.Thought {
+					thought += part.Text
+				} else {
+					text += part.Text
+				}
+				if part.FunctionCall != nil {
+					calls++
+				}
+			}
+			if thought != test.wantThought {
+				t.Errorf("final thought = %q, want %q", thought, test.wantThought)
+			}
+			if text != test.wantText {
+				t.Errorf("final text = %q, want %q", text, test.wantText)
+			}
+			if calls != test.wantCalls {
+				t.Errorf("final response has %d function calls, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGeminiSystemInstructionAndTurns(t *testing.T) {
+	var requests []geminiRequest
+	gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "ok"}]}, "finishReason": "STOP"}]}`, &requests)
+
+	request := &LlmRequest{SystemInstructions: "Be brief."}
+	request.AppendContent(RoleUser, &Part{Text: "Hello"})
+	request.AppendContent(RoleAssistant, &Part{Text: "Hi"})
+	// Parts without a turn role fall back to their own role
+	request.Contents = append(request.Contents,
+		&Content{Parts: []*Part{{Text: "Still there?", Role: RoleUser}}},
+		&Content{Parts: []*Part{{Text: "Yes.", Role: RoleAssistant}}},
+		&Content{Role: RoleAssistant, Parts: []*Part{{Text: "Go on."}}},
+	)
+
+	response, err := gemini.GenerateContent(context.Background(), request)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+
+	sent := requests[0]
+	if sent.SystemInstruction == nil || len(sent.SystemInstruction.Parts) != 1 || sent.SystemInstruction.Parts[0].Text != "Be brief." {
+		t.Errorf("system instruction sent as %+v, want Be brief.", sent.SystemInstruction)
+	}
+
+	// The conversation is sent as it is: no instruction turn, consecutive
+	// parts of the same role merged and no turn added after the model's
+	turns := geminiTurns(sent.Contents)
+	want := []string{"user: Hello", "model: Hi", "user: Still there?", "model: Yes. Go on."}
+	if !reflect.DeepEqual(turns, want) {
+		t.Errorf("turns = %q, want %q", turns, want)
+	}
+
+	if response.Content.Role != RoleAssistant {
+		t.Errorf("response role = %q, want %q", response.Content.Role, RoleAssistant)
+	}
+}
+
+func TestGeminiRequestFromMessages(t *testing.T) {
+	request := geminiRequestFromMessages([]Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hello"},
+		{Role: "system", Content: "Answer in French."},
+		{Role: "user", Content: "Anyone there?"},
+		{Role: "assistant", Content: "Bonjour"},
+	})
+
+	var instructions []string
+	for _, part := range request.SystemInstruction.Parts {
+		instructions = append(instructions, part.Text)
+	}
+	if want := []string{"Be brief.", "Answer in French."}; !reflect.DeepEqual(instructions, want) {
+		t.Errorf("system instruction = %q, want %q", instructions, want)
+	}
+
+	turns := geminiTurns(request.Contents)
+	if want := []string{"user: Hello Anyone there?", "model: Bonjour"}; !reflect.DeepEqual(turns, want) {
+		t.Errorf("turns = %q, want %q", turns, want)
+	}
+}
+
+func TestGeminiThoughtParts(t *testing.T) {
+	var requests []geminiRequest
+	gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [
+		{"text": "The user wants a greeting.", "thought": true},
+		{"text": "Hello!"}
+	]}, "finishReason": "STOP"}]}`, &requests)
+
+	request := &LlmRequest{}
+	request.AppendContent(RoleUser, &Part{Text: "Hi"})
+	request.AppendContent(RoleAssistant, &Part{Text: "A greeting is due.", Thought: true}, &Part{Text: "Hi!"})
+	request.AppendContent(RoleUser, &Part{Text: "Hi again"})
+
+	response, err := gemini.GenerateContent(context.Background(), request)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+
+	sent := requests[0].Contents[1].Parts
+	if len(sent) != 2 || !sent[0].Thought || sent[1].Thought {
+		t.Errorf("model turn sent as %+v, want a thought followed by text", sent)
+	}
+
+	parts := response.Content.Parts
+	if len(parts) != 2 || !parts[0].Thought || parts[0].Text != "The user wants a greeting." || parts[1].Thought {
+		t.Errorf("response parts = %+v, want a thought followed by text", parts)
+	}
+}
+
+func TestGeminiFileParts(t *testing.T) {
+	var requests []geminiRequest
+	gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [
+		{"text": "Here is the chart."},
+		{"inlineData": {"mimeType": "image/png", "data": "iVBORw=="}},
+		{"fileData": {"mimeType": "application/pdf", "fileUri": "gs://bucket/summary.pdf"}}
+	]}, "finishReason": "STOP"}]}`, &requests)
+
+	request := &LlmRequest{}
+	request.AppendContent(RoleUser,
+		&Part{Text: "Chart this."},
+		NewInlineDataPart([]byte("a,b\n1,2"), "text/csv"),
+		NewFileDataPart("gs://bucket/report.pdf", "application/pdf"),
+	)
+
+	response, err := gemini.GenerateContent(context.Background(), request)
+	if err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+
+	sent := requests[0].Contents[0].Parts
+	if len(sent) != 3 {
+		t.Fatalf("sent parts = %+v, want text, inline data and file data", sent)
+	}
+	if inline := sent[1].InlineData; inline == nil || inline.MimeType != "text/csv" || string(inline.Data) != "a,b\n1,2" {
+		t.Errorf("inline data sent as %+v", inline)
+	}
+	if file := sent[2].FileData; file == nil || file.FileURI != "gs://bucket/report.pdf" || file.MimeType != "application/pdf" {
+		t.Errorf("file data sent as %+v", file)
+	}
+
+	parts := response.Content.Parts
+	if len(parts) != 3 {
+		t.Fatalf("response parts = %+v, want text, inline data and file data", parts)
+	}
+	if inline := parts[1].InlineData; inline == nil || inline.MimeType != "image/png" || !bytes.Equal(inline.Data, []byte{0x89, 'P', 'N', 'G'}) {
+		t.Errorf("inline data received as %+v", inline)
+	}
+	if file := parts[2].FileData; file == nil || file.FileURI != "gs://bucket/summary.pdf" || file.MimeType != "application/pdf" {
+		t.Errorf("file data received as %+v", file)
+	}
+}
+
+func TestGeminiUsageMetadata(t *testing.T) {
+	want := &UsageMetadata{
+		PromptTokenCount:        100,
+		CandidatesTokenCount:    20,
+		CachedContentTokenCount: 60,
+		ThoughtsTokenCount:      30,
+		TotalTokenCount:         150,
+	}
+	usage := `"usageMetadata": {"promptTokenCount": 100, "candidatesTokenCount": 20, "cachedContentTokenCount": 60, "thoughtsTokenCount": 30, "totalTokenCount": 150}, "modelVersion": "gemini-2.5-flash-001"`
+
+	request := &LlmRequest{}
+	request.AppendContent(RoleUser, &Part{Text: "Hello"})
+
+	t.Run("complete response", func(t *testing.T) {
+		var requests []geminiRequest
+		gemini := newTestGemini(t, `{"candidates": [{"content": {"role": "model", "parts": [{"text": "Hi"}]}, "finishReason": "STOP"}], `+usage+`}`, &requests)
+
+		response, err := gemini.GenerateContent(context.Background(), request)
+		if err != nil {
+			t.Fatalf("GenerateContent: %v", err)
+		}
+		if !reflect.DeepEqual(response.UsageMetadata, want) || response.ModelVersion != "gemini-2.5-flash-001" {
+			t.Errorf("usage = %+v of model %q, want %+v of gemini-2.5-flash-001", response.UsageMetadata, response.ModelVersion, want)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var requests []geminiRequest
+		gemini := newTestGemini(t, `data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "H"}]}}]}`+"\n\n"+
+			`data: {"candidates": [{"content": {"role": "model", "parts": [{"text": "i"}]}, "finishReason": "STOP"}], `+usage+`}`+"\n\n", &requests)
+
+		responseCh, err := gemini.GenerateContentStream(context.Background(), request)
+		if err != nil {
+			t.Fatalf("GenerateContentStream: %v", err)
+		}
+		var final *LlmResponse
+		for response := range responseCh {
+			if !response.Partial {
+				final = response
+			}
+		}
+		if final == nil || !reflect.DeepEqual(final.UsageMetadata, want) || final.ModelVersion != "gemini-2.5-flash-001" {
+			t.Errorf("final response = %+v, want the usage of the last chunk", final)
+		}
+	})
+}
+
+func TestReadServerSentEvents(t *testing.T) {
+	stream := ": keep-alive\n\nevent: message\ndata: {\"a\":\ndata: 1}\n\ndata:{\"b\":2}\n\ndata: {\"c\":3}"
+
+	var got []string
+	err := readServerSentEvents(strings.NewReader(stream), func(data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readServerSentEvents: %v", err)
+	}
+	if want := []string{"{\"a\":\n1}", `{"b":2}`, `{"c":3}`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
@@ -21,8 +21,9 @@ import (
 
 // LlmRequest represents a request to an LLM model
 type LlmRequest struct {
-	// Contents contains the conversation history to be sent to the model
-	Contents *Content `json:"contents,omitempty"`
+	// Contents contains the conversation history to be sent to the model, one
+	// content per turn in order
+	Contents []*Content `json:"contents,omitempty"`
 
 	// Tools is the list of tools available to the model
 	Tools []*Tool `json:"tools,omitempty"`
@@ -47,6 +48,78 @@ type LlmRequest struct {
 
 	// CandidateCount specifies the number of response candidates to generate
 	CandidateCount int `json:"candidateCount,omitempty"`
+
+	// ResponseMimeType is the MIME type the response should have, such as application/json
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+
+	// ResponseSchema is the JSON schema the response should conform to
+	ResponseSchema map[string]interface{} `json:"responseSchema,omitempty"`
+
+	// ToolConfig controls how the model uses the tools, if set
+	ToolConfig *ToolConfig `json:"toolConfig,omitempty"`
+}
+
+// FunctionCallingMode controls whether the model calls functions
+type FunctionCallingMode string
+
+const (
+	// FunctionCallingModeAuto lets the model choose between calling a
+	// function and answering directly
+	FunctionCallingModeAuto FunctionCallingMode = "AUTO"
+
+	// FunctionCallingModeAny makes the model always call a function
+	FunctionCallingModeAny FunctionCallingMode = "ANY"
+
+	// FunctionCallingModeNone keeps the model from calling functions
+	FunctionCallingModeNone FunctionCallingMode = "NONE"
+)
+
+// ToolConfig controls how the model uses the tools of a request
+type ToolConfig struct {
+	// Mode controls whether the model calls functions
+	Mode FunctionCallingMode `json:"mode,omitempty"`
+
+	// AllowedFunctionNames limits the functions the model may call in ANY
+	// mode. Empty allows all declared functions.
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// SetOutputSchema asks the model to respond with JSON conforming to the schema
+func (r *LlmRequest) SetOutputSchema(schema map[string]interface{}) {
+	r.ResponseMimeType = "application/json"
+	r.ResponseSchema = schema
+}
+
+// AppendContent adds parts to the conversation as a turn of the given role.
+// Parts following a turn of the same role are merged into that turn.
+func (r *LlmRequest) AppendContent(role string, parts ...*Part) {
+	if len(parts) == 0 {
+		return
+	}
+
+	if n := len(r.Contents); n > 0 && r.Contents[n-1].Role == role {
+		r.Contents[n-1].Parts = append(r.Contents[n-1].Parts, parts...)
+		return
+	}
+	r.Contents = append(r.Contents, &Content{
+		Role:  role,
+		Parts: append([]*Part(nil), parts...),
+	})
+}
+
+// AppendTools adds tools to the request, keeping Tools and ToolsDict in sync
+func (r *LlmRequest) AppendTools(tools ...*Tool) {
+	if r.ToolsDict == nil {
+		r.ToolsDict = make(map[string]*Tool)
+	}
+
+	for _, tool := range tools {
+		if _, exists := r.ToolsDict[tool.Name]; exists {
+			continue
+		}
+		r.Tools = append(r.Tools, tool)
+		r.ToolsDict[tool.Name] = tool
+	}
 }
 
 // LlmResponse represents a response from an LLM model
@@ -68,10 +141,46 @@ type LlmResponse struct {
 
 	// TurnComplete indicates if the turn is complete (used in live mode)
 	TurnComplete bool `json:"turnComplete,omitempty"`
+
+	// UsageMetadata holds the tokens used by the call, if the model reports them
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+
+	// ModelVersion is the model that generated the response, if known
+	ModelVersion string `json:"modelVersion,omitempty"`
+}
+
+// UsageMetadata holds the number of tokens used by a model call
+type UsageMetadata struct {
+	// PromptTokenCount is the number of tokens in the request, including the
+	// cached ones
+	PromptTokenCount int `json:"promptTokenCount,omitempty"`
+
+	// CandidatesTokenCount is the number of tokens in the response
+	CandidatesTokenCount int `json:"candidatesTokenCount,omitempty"`
+
+	// CachedContentTokenCount is the number of tokens of the request read from
+	// the model's context cache
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+
+	// ThoughtsTokenCount is the number of tokens the model spent thinking
+	ThoughtsTokenCount int `json:"thoughtsTokenCount,omitempty"`
+
+	// TotalTokenCount is the total number of tokens of the call
+	TotalTokenCount int `json:"totalTokenCount,omitempty"`
 }
 
+// Role names of the conversation turns. Backends map them to their own names.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
 // Content represents the content in a message, containing one or more parts
 type Content struct {
+	// Role is the role of the turn (user, assistant), if the content is a
+	// conversation turn
+	Role string `json:"role,omitempty"`
+
 	// Parts contains the individual content parts
 	Parts []*Part `json:"parts,omitempty"`
 }
@@ -108,10 +217,46 @@ type Part struct {
 	// AuthRequest represents an authentication request
 	AuthRequest *AuthRequest `json:"authRequest,omitempty"`
 
+	// InlineData holds binary content, such as an image, sent inline
+	InlineData *Blob `json:"inlineData,omitempty"`
+
+	// FileData references content stored in a file
+	FileData *FileData `json:"fileData,omitempty"`
+
 	// Thought indicates if this part should be treated as a thought/reasoning step
 	Thought bool `json:"thought,omitempty"`
 }
 
+// Blob is binary content with its MIME type. Data is encoded as base64 in
+// JSON.
+type Blob struct {
+	// MimeType is the MIME type of the data, such as image/png
+	MimeType string `json:"mimeType"`
+
+	// Data is the raw content
+	Data []byte `json:"data"`
+}
+
+// FileData references content stored in a file by its URI
+type FileData struct {
+	// FileURI is the URI of the file, such as a gs:// URI or a file uploaded
+	// to the model's file API
+	FileURI string `json:"fileUri"`
+
+	// MimeType is the MIME type of the file
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// NewInlineDataPart creates a part holding binary content
+func NewInlineDataPart(data []byte, mimeType string) *Part {
+	return &Part{InlineData: &Blob{MimeType: mimeType, Data: data}}
+}
+
+// NewFileDataPart creates a part referencing a file
+func NewFileDataPart(fileURI, mimeType string) *Part {
+	return &Part{FileData: &FileData{FileURI: fileURI, MimeType: mimeType}}
+}
+
 // FunctionCall represents a call to a function
 type FunctionCall struct {
 	// Name is the name of the function to call
@@ -0,0 +1,110 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLlmRequestAppendContent(t *testing.T) {
+	request := &LlmRequest{}
+	request.AppendContent(RoleUser, &Part{Text: "Hello"})
+	request.AppendContent(RoleUser)
+	request.AppendContent(RoleUser, &Part{Text: "Anyone there?"})
+	request.AppendContent(RoleAssistant, &Part{Text: "Hi"}, &Part{Text: "How can I help?"})
+	request.AppendContent(RoleUser, &Part{Text: "Bye"})
+
+	var got [][]string
+	for _, content := range request.Contents {
+		turn := []string{content.Role}
+		for _, part := range content.Parts {
+			turn = append(turn, part.Text)
+		}
+		got = append(got, turn)
+	}
+	want := [][]string{
+		{RoleUser, "Hello", "Anyone there?"},
+		{RoleAssistant, "Hi", "How can I help?"},
+		{RoleUser, "Bye"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("turns = %q, want %q", got, want)
+	}
+}
+
+func TestMessagesFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func() *LlmRequest
+		want    []Message
+	}{
+		{
+			name: "system instructions and turns",
+			request: func() *LlmRequest {
+				request := &LlmRequest{SystemInstructions: "Be brief."}
+				request.AppendContent(RoleUser, &Part{Text: "Hello"}, &Part{Text: "Anyone there?"})
+				request.AppendContent(RoleAssistant, &Part{FunctionCall: &FunctionCall{Name: "lookup"}})
+				request.AppendContent(RoleAssistant, &Part{Text: "Hi"})
+				request.Contents = append(request.Contents, &Content{Parts: []*Part{{Text: "No role"}}})
+				return request
+			},
+			want: []Message{
+				{Role: "system", Content: "Be brief."},
+				{Role: RoleUser, Content: "Hello\n\nAnyone there?"},
+				{Role: RoleAssistant, Content: "Hi"},
+				{Role: RoleUser, Content: "No role"},
+			},
+		},
+		{
+			name:    "empty request",
+			request: func() *LlmRequest { return &LlmRequest{} },
+			want:    []Message{{Role: RoleUser, Content: "Hello"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := messagesFromRequest(test.request()); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("messages = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPartJSONCarriesFiles(t *testing.T) {
+	content := &Content{Role: RoleUser, Parts: []*Part{
+		NewInlineDataPart([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+		NewFileDataPart("gs://bucket/report.pdf", "application/pdf"),
+	}}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"data":"iVBORw=="`) {
+		t.Errorf("inline data encoded as %s, want base64", data)
+	}
+
+	var decoded Content
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, content) {
+		t.Errorf("decoded content = %+v, want %+v", &decoded, content)
+	}
+}
@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"strings"
 )
 
 // UnifiedModelFactory provides a unified way to create models, using both the
@@ -56,8 +57,19 @@ func (f *UnifiedModelFactory) GetModel(modelName string) (Model, error) {
 }
 
 // GetLLM creates a new LLM based on the model name.
-// This is a bridge between the old Model interface and the new LLM interface.
+// Models registered by exact name in the standard registry take priority, then
+// native LLM implementations, then pattern-based models. Models are bridged to
+// the LLM interface with a ModelToLLMAdapter.
 func (f *UnifiedModelFactory) GetLLM(modelName string) (LLM, error) {
+	if model, ok := f.standardRegistry.Get(modelName); ok {
+		return &ModelToLLMAdapter{model: model}, nil
+	}
+
+	// Prefer a native LLM implementation, which supports function calling
+	if llm, err := f.enhancedRegistry.GetLLM(modelName); err == nil {
+		return llm, nil
+	}
+
 	// Try to get a Model and wrap it
 	model, err := f.GetModel(modelName)
 	if err != nil {
@@ -68,8 +80,10 @@ func (f *UnifiedModelFactory) GetLLM(modelName string) (LLM, error) {
 	return &ModelToLLMAdapter{model: model}, nil
 }
 
-// LLMTypeFactory is a function that creates a new LLM instance.
-type LLMTypeFactory func(modelName string) LLM
+// LLMTypeFactory is a function that creates a new LLM instance. It returns an
+// error if the LLM cannot be created, for instance because its API key is not
+// set.
+type LLMTypeFactory func(modelName string) (LLM, error)
 
 // ModelToLLMAdapter adapts the Model interface to the LLM interface.
 type ModelToLLMAdapter struct {
@@ -84,38 +98,7 @@ func (a *ModelToLLMAdapter) SupportedModels() []string {
 // GenerateContent generates content by using the underlying Model.
 func (a *ModelToLLMAdapter) GenerateContent(ctx context.Context, request *LlmRequest) (*LlmResponse, error) {
 	// Convert request to messages for the underlying Model
-	messages := []Message{}
-
-	// Add system instructions as a message if present
-	if request.SystemInstructions != "" {
-		messages = append(messages, Message{
-			Role:    "system",
-			Content: request.SystemInstructions,
-		})
-	}
-
-	// Add content parts as messages
-	if request.Contents != nil && len(request.Contents.Parts) > 0 {
-		for _, part := range request.Contents.Parts {
-			role := "user"
-			if part.Role != "" {
-				role = part.Role
-			}
-
-			messages = append(messages, Message{
-				Role:    role,
-				Content: part.Text,
-			})
-		}
-	}
-
-	// If no messages were added, add a default one
-	if len(messages) == 0 {
-		messages = append(messages, Message{
-			Role:    "user",
-			Content: "Hello",
-		})
-	}
+	messages := messagesFromRequest(request)
 
 	// Call the underlying Model
 	text, err := a.model.Generate(ctx, messages)
@@ -139,38 +122,7 @@ func (a *ModelToLLMAdapter) GenerateContent(ctx context.Context, request *LlmReq
 // GenerateContentStream adapts the streaming interface.
 func (a *ModelToLLMAdapter) GenerateContentStream(ctx context.Context, request *LlmRequest) (<-chan *LlmResponse, error) {
 	// Convert request to messages for the underlying Model
-	messages := []Message{}
-
-	// Add system instructions as a message if present
-	if request.SystemInstructions != "" {
-		messages = append(messages, Message{
-			Role:    "system",
-			Content: request.SystemInstructions,
-		})
-	}
-
-	// Add content parts as messages
-	if request.Contents != nil && len(request.Contents.Parts) > 0 {
-		for _, part := range request.Contents.Parts {
-			role := "user"
-			if part.Role != "" {
-				role = part.Role
-			}
-
-			messages = append(messages, Message{
-				Role:    role,
-				Content: part.Text,
-			})
-		}
-	}
-
-	// If no messages were added, add a default one
-	if len(messages) == 0 {
-		messages = append(messages, Message{
-			Role:    "user",
-			Content: "Hello",
-		})
-	}
+	messages := messagesFromRequest(request)
 
 	// Call the streaming method on the Model
 	streamChan, err := a.model.GenerateStream(ctx, messages)
@@ -217,3 +169,51 @@ func (a *ModelToLLMAdapter) GenerateContentStream(ctx context.Context, request *
 func (a *ModelToLLMAdapter) Connect(ctx context.Context, request *LlmRequest) (LlmConnection, error) {
 	return nil, fmt.Errorf("real-time connection not supported by model %s", a.model.Name())
 }
+
+// messagesFromRequest converts a request to messages for a Model: the system
+// instructions, then one message per turn with the text of its parts
+func messagesFromRequest(request *LlmRequest) []Message {
+	messages := []Message{}
+
+	// Add system instructions as a message if present
+	if request.SystemInstructions != "" {
+		messages = append(messages, Message{
+			Role:    "system",
+			Content: request.SystemInstructions,
+		})
+	}
+
+	for _, content := range request.Contents {
+		if content == nil {
+			continue
+		}
+
+		texts := make([]string, 0, len(content.Parts))
+		for _, part := range content.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+
+		role := RoleUser
+		if content.Role != "" {
+			role = content.Role
+		}
+		messages = append(messages, Message{
+			Role:    role,
+			Content: strings.Join(texts, "\n\n"),
+		})
+	}
+
+	// If no messages were added, add a default one
+	if len(messages) == 0 {
+		messages = append(messages, Message{
+			Role:    RoleUser,
+			Content: "Hello",
+		})
+	}
+	return messages
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ModelPricing is the price of a model's tokens in US dollars per million
+// tokens
+type ModelPricing struct {
+	// InputPerMillion is the price of a million tokens sent to the model
+	InputPerMillion float64 `json:"inputPerMillion"`
+
+	// OutputPerMillion is the price of a million tokens generated by the model
+	OutputPerMillion float64 `json:"outputPerMillion"`
+}
+
+// Cost returns the estimated cost of a model call in US dollars
+func (p ModelPricing) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.InputPerMillion + float64(outputTokens)*p.OutputPerMillion) / 1e6
+}
+
+// pricingEntry associates a model name pattern with its pricing
+type pricingEntry struct {
+	pattern *regexp.Regexp
+	pricing ModelPricing
+}
+
+var (
+	pricingEntries []pricingEntry
+	pricingMu      sync.RWMutex
+)
+
+// defaultPricing holds the list prices of the Gemini models at the time of
+// writing. More specific patterns come after the general ones.
+var defaultPricing = []struct {
+	pattern string
+	pricing ModelPricing
+}{
+	{`^gemini-1\.5-flash`, ModelPricing{InputPerMillion: 0.075, OutputPerMillion: 0.30}},
+	{`^gemini-1\.5-flash-8b`, ModelPricing{InputPerMillion: 0.0375, OutputPerMillion: 0.15}},
+	{`^gemini-1\.5-pro`, ModelPricing{InputPerMillion: 1.25, OutputPerMillion: 5.00}},
+	{`^gemini-2\.0-flash`, ModelPricing{InputPerMillion: 0.10, OutputPerMillion: 0.40}},
+	{`^gemini-2\.0-flash-lite`, ModelPricing{InputPerMillion: 0.075, OutputPerMillion: 0.30}},
+	{`^gemini-2\.5-flash`, ModelPricing{InputPerMillion: 0.30, OutputPerMillion: 2.50}},
+	{`^gemini-2\.5-flash-lite`, ModelPricing{InputPerMillion: 0.10, OutputPerMillion: 0.40}},
+	{`^gemini-2\.5-pro`, ModelPricing{InputPerMillion: 1.25, OutputPerMillion: 10.00}},
+}
+
+func init() {
+	for _, entry := range defaultPricing {
+		if err := RegisterPricing(entry.pattern, entry.pricing); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterPricing sets the pricing of the models whose names match a regex
+// pattern. Later registrations take precedence, so they can override the
+// default prices or price custom models.
+func RegisterPricing(pattern string, pricing ModelPricing) error {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regex pattern %s: %w", pattern, err)
+	}
+
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricingEntries = append(pricingEntries, pricingEntry{pattern: regex, pricing: pricing})
+	return nil
+}
+
+// LookupPricing returns the pricing of a model, if one is registered
+func LookupPricing(modelName string) (ModelPricing, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	for i := len(pricingEntries) - 1; i >= 0; i-- {
+		if pricingEntries[i].pattern.MatchString(modelName) {
+			return pricingEntries[i].pricing, true
+		}
+	}
+	return ModelPricing{}, false
+}
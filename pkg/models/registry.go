@@ -30,11 +30,19 @@ type RegistryEntry struct {
 	Factory EnhancedModelFactory
 }
 
+// LLMRegistryEntry combines a regex pattern with its associated LLM factory.
+type LLMRegistryEntry struct {
+	Pattern *regexp.Regexp
+	Factory LLMTypeFactory
+}
+
 // EnhancedRegistry is a registry for models that supports regex-based lookup.
 type EnhancedRegistry struct {
-	entries []RegistryEntry
-	models  map[string]Model // Cache for already created models
-	mu      sync.RWMutex
+	entries    []RegistryEntry
+	models     map[string]Model // Cache for already created models
+	llmEntries []LLMRegistryEntry
+	llms       map[string]LLM // Cache for already created LLMs
+	mu         sync.RWMutex
 }
 
 var (
@@ -46,8 +54,10 @@ var (
 func GetEnhancedRegistry() *EnhancedRegistry {
 	enhancedRegistryOnce.Do(func() {
 		enhancedRegistry = &EnhancedRegistry{
-			entries: make([]RegistryEntry, 0),
-			models:  make(map[string]Model),
+			entries:    make([]RegistryEntry, 0),
+			models:     make(map[string]Model),
+			llmEntries: make([]LLMRegistryEntry, 0),
+			llms:       make(map[string]LLM),
 		}
 	})
 	return enhancedRegistry
@@ -109,6 +119,65 @@ func (r *EnhancedRegistry) GetModel(name string) (Model, error) {
 	return nil, fmt.Errorf("no model factory found for model name: %s", name)
 }
 
+// RegisterLLMPattern registers a factory of native LLM implementations, such
+// as GeminiLLM, for the model names matching a regex pattern. Native LLMs
+// support function calling, which models bridged from the Model interface do
+// not, so UnifiedModelFactory.GetLLM prefers them.
+func (r *EnhancedRegistry) RegisterLLMPattern(pattern string, factory LLMTypeFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regex pattern %s: %w", pattern, err)
+	}
+
+	r.llmEntries = append(r.llmEntries, LLMRegistryEntry{
+		Pattern: regex,
+		Factory: factory,
+	})
+
+	log.Printf("Registered LLM pattern: %s", pattern)
+	return nil
+}
+
+// GetLLM returns or creates an LLM that matches the given name. Created LLMs
+// are cached by name; a factory error is returned and nothing is cached.
+func (r *EnhancedRegistry) GetLLM(name string) (LLM, error) {
+	// First check the cache
+	r.mu.RLock()
+	llm, exists := r.llms[name]
+	r.mu.RUnlock()
+
+	if exists {
+		return llm, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check again in case another goroutine created it
+	if llm, exists = r.llms[name]; exists {
+		return llm, nil
+	}
+
+	// Find a factory for the model name
+	for _, entry := range r.llmEntries {
+		if entry.Pattern.MatchString(name) {
+			llm, err := entry.Factory(name)
+			if err != nil {
+				return nil, err
+			}
+
+			// Cache the LLM
+			r.llms[name] = llm
+			return llm, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no LLM factory found for model name: %s", name)
+}
+
 // ListPatterns returns all registered patterns.
 func (r *EnhancedRegistry) ListPatterns() []string {
 	r.mu.RLock()
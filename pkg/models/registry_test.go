@@ -0,0 +1,124 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// nativeLlm is an LLM that only knows its name
+type nativeLlm struct {
+	name string
+}
+
+func (m *nativeLlm) SupportedModels() []string { return []string{m.name} }
+
+func (m *nativeLlm) GenerateContent(ctx context.Context, request *LlmRequest) (*LlmResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *nativeLlm) GenerateContentStream(ctx context.Context, request *LlmRequest) (<-chan *LlmResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *nativeLlm) Connect(ctx context.Context, request *LlmRequest) (LlmConnection, error) {
+	return nil, errors.New("not implemented")
+}
+
+// newTestRegistry returns an empty registry, apart from the singleton
+func newTestRegistry() *EnhancedRegistry {
+	return &EnhancedRegistry{models: make(map[string]Model), llms: make(map[string]LLM)}
+}
+
+func TestEnhancedRegistryGetLLM(t *testing.T) {
+	registry := newTestRegistry()
+	created := 0
+	failing := true
+	if err := registry.RegisterLLMPattern("native-.*", func(modelName string) (LLM, error) {
+		created++
+		if strings.HasSuffix(modelName, "-keyless") && failing {
+			return nil, errors.New("API key not set")
+		}
+		return &nativeLlm{name: modelName}, nil
+	}); err != nil {
+		t.Fatalf("RegisterLLMPattern: %v", err)
+	}
+
+	first, err := registry.GetLLM("native-1")
+	if err != nil {
+		t.Fatalf("GetLLM: %v", err)
+	}
+	second, _ := registry.GetLLM("native-1")
+	if first != second || created != 1 {
+		t.Errorf("LLM created %d times for two lookups, want it cached", created)
+	}
+
+	if _, err := registry.GetLLM("native-keyless"); err == nil || !strings.Contains(err.Error(), "API key") {
+		t.Errorf("GetLLM error = %v, want the factory error", err)
+	}
+	failing = false
+	if _, err := registry.GetLLM("native-keyless"); err != nil {
+		t.Errorf("GetLLM after the factory error: %v, want the error not cached", err)
+	}
+
+	if _, err := registry.GetLLM("other"); err == nil {
+		t.Errorf("GetLLM found an LLM for a name matching no pattern")
+	}
+	if err := registry.RegisterLLMPattern("(", nil); err == nil {
+		t.Errorf("RegisterLLMPattern accepted an invalid pattern")
+	}
+}
+
+func TestUnifiedModelFactoryGetLLM(t *testing.T) {
+	standard := &ModelRegistry{models: make(map[string]Model)}
+	standard.Register(NewMockModel("shared-exact", "registered"))
+
+	enhanced := newTestRegistry()
+	enhanced.entries = append(enhanced.entries, RegistryEntry{
+		Pattern: regexp.MustCompile(".*"),
+		Factory: func(modelName string) (Model, error) { return NewMockModel(modelName, "pattern"), nil },
+	})
+	enhanced.llmEntries = append(enhanced.llmEntries, LLMRegistryEntry{
+		Pattern: regexp.MustCompile("shared-.*"),
+		Factory: func(modelName string) (LLM, error) { return &nativeLlm{name: modelName}, nil },
+	})
+	factory := &UnifiedModelFactory{standardRegistry: standard, enhancedRegistry: enhanced}
+
+	tests := []struct {
+		name       string
+		model      string
+		wantNative bool
+	}{
+		{name: "exact name before native LLMs", model: "shared-exact"},
+		{name: "native LLM before pattern models", model: "shared-pattern", wantNative: true},
+		{name: "pattern model", model: "other"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			llm, err := factory.GetLLM(test.model)
+			if err != nil {
+				t.Fatalf("GetLLM: %v", err)
+			}
+			if _, native := llm.(*nativeLlm); native != test.wantNative {
+				t.Errorf("GetLLM(%s) = %T, want native %t", test.model, llm, test.wantNative)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ParseJSONResponse decodes JSON text produced by a model. Markdown code
+// fences around the JSON are ignored.
+func ParseJSONResponse(text string) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimPrefix(text, "json")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	return value, nil
+}
+
+// ValidateSchema checks a decoded JSON value against a JSON schema and
+// returns one message per violation. It supports the subset of JSON schema
+// used for model response schemas: type, nullable, enum, properties,
+// required, additionalProperties, items, minItems, maxItems, minLength,
+// maxLength, minimum, maximum and anyOf. Type names are case-insensitive.
+func ValidateSchema(schema map[string]interface{}, value interface{}) []string {
+	return validateSchema(schema, value, "$")
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schemaAllowsType(schema, "null") {
+			return nil
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, option := range anyOf {
+			if optionSchema, ok := option.(map[string]interface{}); ok && len(validateSchema(optionSchema, value, path)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: does not match any of the allowed schemas", path)}
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if valueHasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))}
+		}
+	}
+
+	var violations []string
+
+	if enum := schemaEnum(schema); enum != nil && !enumContains(enum, value) {
+		violations = append(violations, fmt.Sprintf("%s: value %v is not one of %v", path, value, enum))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		violations = append(violations, validateObject(schema, v, path)...)
+	case []interface{}:
+		violations = append(violations, validateArray(schema, v, path)...)
+	case string:
+		if minLength, ok := schemaNumber(schema, "minLength"); ok && float64(len([]rune(v))) < minLength {
+			violations = append(violations, fmt.Sprintf("%s: length must be at least %v", path, minLength))
+		}
+		if maxLength, ok := schemaNumber(schema, "maxLength"); ok && float64(len([]rune(v))) > maxLength {
+			violations = append(violations, fmt.Sprintf("%s: length must be at most %v", path, maxLength))
+		}
+	case float64:
+		if minimum, ok := schemaNumber(schema, "minimum"); ok && v < minimum {
+			violations = append(violations, fmt.Sprintf("%s: must be at least %v", path, minimum))
+		}
+		if maximum, ok := schemaNumber(schema, "maximum"); ok && v > maximum {
+			violations = append(violations, fmt.Sprintf("%s: must be at most %v", path, maximum))
+		}
+	}
+
+	return violations
+}
+
+// validateObject checks the properties of an object value
+func validateObject(schema map[string]interface{}, value map[string]interface{}, path string) []string {
+	var violations []string
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for _, name := range schemaRequired(schema) {
+		if _, ok := value[name]; !ok {
+			violations = append(violations, fmt.Sprintf("%s: missing required property %q", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyPath := path + "." + name
+		if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+			violations = append(violations, validateSchema(propertySchema, value[name], propertyPath)...)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				violations = append(violations, fmt.Sprintf("%s: unexpected property", propertyPath))
+			}
+		case map[string]interface{}:
+			violations = append(violations, validateSchema(additional, value[name], propertyPath)...)
+		}
+	}
+
+	return violations
+}
+
+// validateArray checks the items of an array value
+func validateArray(schema map[string]interface{}, value []interface{}, path string) []string {
+	var violations []string
+
+	if minItems, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < minItems {
+		violations = append(violations, fmt.Sprintf("%s: must have at least %v items", path, minItems))
+	}
+	if maxItems, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > maxItems {
+		violations = append(violations, fmt.Sprintf("%s: must have at most %v items", path, maxItems))
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			violations = append(violations, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return violations
+}
+
+// schemaTypes returns the lower-cased type names allowed by a schema
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{strings.ToLower(t)}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, strings.ToLower(name))
+			}
+		}
+		return types
+	case []string:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			types = append(types, strings.ToLower(name))
+		}
+		return types
+	}
+	return nil
+}
+
+// schemaAllowsType reports whether the schema lists the given type
+func schemaAllowsType(schema map[string]interface{}, typeName string) bool {
+	for _, t := range schemaTypes(schema) {
+		if t == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaRequired returns the required property names of an object schema
+func schemaRequired(schema map[string]interface{}) []string {
+	switch required := schema["required"].(type) {
+	case []string:
+		return required
+	case []interface{}:
+		names := make([]string, 0, len(required))
+		for _, item := range required {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// schemaEnum returns the allowed values of a schema, or nil if any value is allowed
+func schemaEnum(schema map[string]interface{}) []interface{} {
+	switch enum := schema["enum"].(type) {
+	case []interface{}:
+		return enum
+	case []string:
+		values := make([]interface{}, len(enum))
+		for i, value := range enum {
+			values[i] = value
+		}
+		return values
+	}
+	return nil
+}
+
+// schemaNumber reads a numeric schema keyword
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	switch n := schema[key].(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// valueHasType reports whether a decoded JSON value is of the given schema type
+func valueHasType(value interface{}, typeName string) bool {
+	switch typeName {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// jsonTypeName names the JSON type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// enumContains reports whether value is one of the enum values
+func enumContains(enum []interface{}, value interface{}) bool {
+	_, isString := value.(string)
+	for _, allowed := range enum {
+		_, allowedIsString := allowed.(string)
+		if isString == allowedIsString && fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "object", text: `{"city": "Paris"}`, want: map[string]interface{}{"city": "Paris"}},
+		{name: "array", text: ` [1, 2] `, want: []interface{}{1.0, 2.0}},
+		{name: "json code fence", text: "```json\n{\"city\": \"Paris\"}\n```", want: map[string]interface{}{"city": "Paris"}},
+		{name: "bare code fence", text: "```\n[true]\n```", want: []interface{}{true}},
+		{name: "not JSON", text: "The weather is sunny.", wantErr: true},
+		{name: "truncated", text: `{"city": `, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseJSONResponse(test.text)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseJSONResponse = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJSONResponse: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseJSONResponse = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	forecast := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"city": map[string]interface{}{"type": "string"},
+			"sky":  map[string]interface{}{"type": "STRING", "enum": []interface{}{"sunny", "cloudy"}},
+			"location": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"lat": map[string]interface{}{"type": "number"},
+					"lon": map[string]interface{}{"type": "number"},
+				},
+				"required": []interface{}{"lat", "lon"},
+			},
+			"temperatures": map[string]interface{}{
+				"type":     "array",
+				"items":    map[string]interface{}{"type": "integer"},
+				"minItems": 1,
+			},
+		},
+		"required": []string{"city"},
+	}
+
+	tests := []struct {
+		name   string
+		schema map[string]interface{}
+		value  string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			schema: forecast,
+			value:  `{"city": "Paris", "sky": "sunny", "location": {"lat": 48.9, "lon": 2.4}, "temperatures": [18, 21]}`,
+		},
+		{
+			name:   "wrong type",
+			schema: forecast,
+			value:  `["Paris"]`,
+			want:   []string{"$: expected object, got array"},
+		},
+		{
+			name:   "wrong property type",
+			schema: forecast,
+			value:  `{"city": 75}`,
+			want:   []string{"$.city: expected string, got number"},
+		},
+		{
+			name:   "missing required property",
+			schema: forecast,
+			value:  `{"sky": "sunny"}`,
+			want:   []string{`$: missing required property "city"`},
+		},
+		{
+			name:   "value not in enum",
+			schema: forecast,
+			value:  `{"city": "Paris", "sky": "rainy"}`,
+			want:   []string{"$.sky: value rainy is not one of [sunny cloudy]"},
+		},
+		{
+			name:   "nested object",
+			schema: forecast,
+			value:  `{"city": "Paris", "location": {"lat": "north"}}`,
+			want: []string{
+				`$.location: missing required property "lon"`,
+				"$.location.lat: expected number, got string",
+			},
+		},
+		{
+			name:   "array items",
+			schema: forecast,
+			value:  `{"city": "Paris", "temperatures": [18, 20.5, "hot"]}`,
+			want: []string{
+				"$.temperatures[1]: expected integer, got number",
+				"$.temperatures[2]: expected integer, got string",
+			},
+		},
+		{
+			name:   "array too short",
+			schema: forecast,
+			value:  `{"city": "Paris", "temperatures": []}`,
+			want:   []string{"$.temperatures: must have at least 1 items"},
+		},
+		{
+			name:   "nullable",
+			schema: map[string]interface{}{"type": "string", "nullable": true},
+			value:  `null`,
+		},
+		{
+			name: "no additional properties",
+			schema: map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+				"additionalProperties": false,
+			},
+			value: `{"city": "Paris", "country": "France"}`,
+			want:  []string{"$.country: unexpected property"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := ParseJSONResponse(test.value)
+			if err != nil {
+				t.Fatalf("ParseJSONResponse: %v", err)
+			}
+			if got := ValidateSchema(test.schema, value); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ValidateSchema = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nvcnvn/adk-golang/pkg/a2a"
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/version"
+)
+
+// a2aUserID is the user the sessions of A2A clients belong to. Each A2A
+// context is a session.
+const a2aUserID = "a2a"
+
+// a2aTaskStateKey is the session state key prefix A2A tasks are stored under
+const a2aTaskStateKey = "a2a:task:"
+
+// a2aHistoryStateKey follows the state key of a task, and is followed by an
+// index, in the state keys of the messages of the task's history
+const a2aHistoryStateKey = ":history:"
+
+// NewA2AServer publishes the runner's agent over the A2A protocol. Every A2A
+// context is a session of the runner, and tasks are stored in the state of
+// their session. The agent card is built from the agent's name, description
+// and tools; its URL is the URL the card is requested at.
+func NewA2AServer(runner *SessionRunner) *a2a.Server {
+	return a2a.NewServer(
+		BuildAgentCard(runner.Agent, ""),
+		&a2aExecutor{runner: runner},
+		a2a.WithTaskStore(&SessionTaskStore{Runner: runner, UserID: a2aUserID}),
+	)
+}
+
+// BuildAgentCard describes an agent for A2A clients. The agent is one skill,
+// and each of its tools another.
+func BuildAgentCard(agent agents.BaseAgent, url string) *a2a.AgentCard {
+	var description string
+	if described, ok := agent.(interface{ Description() string }); ok {
+		description = described.Description()
+	}
+
+	skills := []a2a.AgentSkill{{
+		ID:          agent.Name(),
+		Name:        agent.Name(),
+		Description: description,
+		Tags:        []string{"agent"},
+	}}
+	for _, tool := range agents.AgentTools(agent) {
+		skills = append(skills, a2a.AgentSkill{
+			ID:          agent.Name() + "-" + tool.Name(),
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Tags:        []string{"tool"},
+		})
+	}
+
+	return &a2a.AgentCard{
+		Name:               agent.Name(),
+		Description:        description,
+		URL:                url,
+		Version:            version.Version,
+		ProtocolVersion:    a2a.ProtocolVersion,
+		Capabilities:       a2a.AgentCapabilities{Streaming: true},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             skills,
+	}
+}
+
+// a2aExecutor runs A2A messages as invocations of a SessionRunner
+type a2aExecutor struct {
+	runner *SessionRunner
+}
+
+// Execute runs the agent on the message in the session of the task's context.
+// The agent's last answer becomes the task's artifact, earlier answers are
+// reported as working status messages, and an error event fails the task.
+// A task whose agent waits on long-running tools requires input.
+func (e *a2aExecutor) Execute(ctx context.Context, task *a2a.Task, message *a2a.Message, updater *a2a.TaskUpdater) error {
+	if _, err := e.runner.GetOrCreateSession(ctx, a2aUserID, task.ContextID); err != nil {
+		return err
+	}
+
+	eventCh, err := e.runner.Run(ctx, a2aUserID, task.ContextID, agents.ContentFromA2A(message.Parts, "user"), nil)
+	if err != nil {
+		return err
+	}
+
+	var answer, errorEvent *events.Event
+	for event := range eventCh {
+		switch {
+		case event.Partial:
+		case event.ErrorCode != "":
+			errorEvent = event
+		case event.IsFinalResponse() && event.Content.GetText() != "":
+			if answer != nil {
+				status := &a2a.Message{Role: a2a.RoleAgent, Parts: agents.A2AParts(answer.Content)}
+				if err := updater.UpdateStatus(a2a.TaskStateWorking, status); err != nil {
+					return err
+				}
+			}
+			answer = event
+		case len(event.LongRunningToolIDs) > 0:
+			answer = event
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if errorEvent != nil {
+		message := errorEvent.ErrorCode
+		if errorEvent.ErrorMessage != "" {
+			message = fmt.Sprintf("%s: %s", errorEvent.ErrorCode, errorEvent.ErrorMessage)
+		}
+		return updater.UpdateStatus(a2a.TaskStateFailed, a2a.NewTextMessage(a2a.RoleAgent, message))
+	}
+
+	if answer == nil {
+		return nil
+	}
+
+	if len(answer.LongRunningToolIDs) > 0 {
+		text := answer.Content.GetText()
+		if text == "" {
+			text = fmt.Sprintf("Waiting for %s to complete.", strings.Join(answer.LongRunningToolIDs, ", "))
+		}
+		return updater.UpdateStatus(a2a.TaskStateInputRequired, a2a.NewTextMessage(a2a.RoleAgent, text))
+	}
+
+	return updater.AddArtifact(a2a.Artifact{
+		Name:  "response",
+		Parts: agents.A2AParts(answer.Content),
+	})
+}
+
+// SessionTaskStore stores A2A tasks in the state of the session of their
+// context, so tasks are kept wherever the session service keeps sessions
+type SessionTaskStore struct {
+	// Runner owns the sessions
+	Runner *SessionRunner
+
+	// UserID is the user the sessions belong to
+	UserID string
+}
+
+// GetTask returns a task from the state of its session
+func (s *SessionTaskStore) GetTask(ctx context.Context, taskID string) (*a2a.Task, error) {
+	session, err := s.Runner.SessionService.GetSession(ctx, s.Runner.AppName, s.UserID, a2a.TaskContextID(taskID), nil)
+	if err != nil || session == nil {
+		return nil, a2a.ErrTaskNotFound
+	}
+
+	value, ok := session.GetState(a2aTaskStateKey + taskID)
+	if !ok {
+		return nil, a2a.ErrTaskNotFound
+	}
+
+	var task a2a.Task
+	if err := fromStateValue(value, &task); err != nil {
+		return nil, fmt.Errorf("failed to read task %s: %w", taskID, err)
+	}
+	for i := 0; ; i++ {
+		value, ok := session.GetState(a2aHistoryKey(taskID, i))
+		if !ok {
+			break
+		}
+		var message a2a.Message
+		if err := fromStateValue(value, &message); err != nil {
+			return nil, fmt.Errorf("failed to read message %d of task %s: %w", i, taskID, err)
+		}
+		task.History = append(task.History, message)
+	}
+	return &task, nil
+}
+
+// SaveTask records a task in the state of its session with a state delta
+// event. The history of a task only grows, so the event holds the task
+// without its history, and the messages added since the task was last saved,
+// each under its own key.
+func (s *SessionTaskStore) SaveTask(ctx context.Context, task *a2a.Task) error {
+	session, err := s.Runner.GetOrCreateSession(ctx, s.UserID, task.ContextID)
+	if err != nil {
+		return err
+	}
+
+	event := events.NewEvent()
+	event.Author = "a2a"
+
+	record := *task
+	record.History = nil
+	value, err := toStateValue(&record)
+	if err != nil {
+		return err
+	}
+	event.Actions.StateDelta[a2aTaskStateKey+task.ID] = value
+
+	saved := 0
+	for {
+		if _, ok := session.GetState(a2aHistoryKey(task.ID, saved)); !ok {
+			break
+		}
+		saved++
+	}
+	for i := saved; i < len(task.History); i++ {
+		value, err := toStateValue(&task.History[i])
+		if err != nil {
+			return err
+		}
+		event.Actions.StateDelta[a2aHistoryKey(task.ID, i)] = value
+	}
+
+	if _, err := s.Runner.SessionService.AppendEvent(ctx, session, event); err != nil {
+		return fmt.Errorf("failed to save task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// a2aHistoryKey returns the state key of a message of a task's history
+func a2aHistoryKey(taskID string, index int) string {
+	return fmt.Sprintf("%s%s%s%d", a2aTaskStateKey, taskID, a2aHistoryStateKey, index)
+}
+
+// toStateValue converts a value to plain JSON values, like any other session
+// state
+func toStateValue(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var stateValue map[string]interface{}
+	if err := json.Unmarshal(data, &stateValue); err != nil {
+		return nil, err
+	}
+	return stateValue, nil
+}
+
+// fromStateValue reads a value stored with toStateValue
+func fromStateValue(stateValue interface{}, value interface{}) error {
+	data, err := json.Marshal(stateValue)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runners
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/a2a"
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+)
+
+func TestSessionTaskStoreSavesHistoryOnce(t *testing.T) {
+	runner := NewSessionRunner("app", agents.NewLlmAgent("agent", nil), sessions.NewInMemorySessionService())
+	store := &SessionTaskStore{Runner: runner, UserID: a2aUserID}
+	ctx := context.Background()
+
+	task := &a2a.Task{
+		Kind:      a2a.KindTask,
+		ID:        a2a.NewTaskID("context"),
+		ContextID: "context",
+		Status:    a2a.TaskStatus{State: a2a.TaskStateSubmitted},
+		History:   []a2a.Message{*a2a.NewTextMessage(a2a.RoleUser, "Pay the bill")},
+	}
+	if err := store.SaveTask(ctx, task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+
+	for _, state := range []a2a.TaskState{a2a.TaskStateWorking, a2a.TaskStateInputRequired} {
+		task.Status.State = state
+		task.History = append(task.History, *a2a.NewTextMessage(a2a.RoleAgent, string(state)))
+		if err := store.SaveTask(ctx, task); err != nil {
+			t.Fatalf("SaveTask: %v", err)
+		}
+	}
+
+	session, err := runner.SessionService.GetSession(ctx, "app", a2aUserID, "context", nil)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	for i, event := range session.GetAllEvents() {
+		// The task itself and the one message added by the save
+		if n := len(event.Actions.StateDelta); n != 2 {
+			t.Errorf("save %d recorded %d state keys, want 2", i, n)
+		}
+	}
+
+	saved, err := store.GetTask(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if saved.Status.State != a2a.TaskStateInputRequired {
+		t.Errorf("state = %s, want %s", saved.Status.State, a2a.TaskStateInputRequired)
+	}
+	if len(saved.History) != len(task.History) {
+		t.Fatalf("history has %d messages, want %d", len(saved.History), len(task.History))
+	}
+	for i, message := range saved.History {
+		if message.MessageID != task.History[i].MessageID {
+			t.Errorf("message %d is %s, want %s", i, message.MessageID, task.History[i].MessageID)
+		}
+	}
+}
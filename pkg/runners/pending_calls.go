@@ -0,0 +1,221 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// ErrCallNotPending is returned when a function response is submitted for a
+// call that is not waiting for one
+var ErrCallNotPending = errors.New("function call is not pending")
+
+// PendingCall is a long-running function call waiting for its response, such
+// as a long-running tool still at work or a tool confirmation request
+type PendingCall struct {
+	// FunctionCall is the call waiting for a response
+	FunctionCall *models.FunctionCall
+
+	// Author is the agent that made the call and resumes when it completes
+	Author string
+
+	// InvocationID is the invocation the call was made in
+	InvocationID string
+
+	// EventID is the event carrying the call
+	EventID string
+}
+
+// ListPendingCalls returns the long-running calls of a session that are
+// waiting for a response, oldest first
+func (r *SessionRunner) ListPendingCalls(ctx context.Context, userID, sessionID string) ([]*PendingCall, error) {
+	session, err := r.SessionService.GetSession(ctx, r.AppName, userID, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	if session == nil {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	return pendingCalls(session), nil
+}
+
+// SubmitFunctionResponse completes a pending long-running call with its
+// response and resumes the agent that made the call. The response's ID must
+// be the ID of a pending call; its name defaults to the call's name.
+func (r *SessionRunner) SubmitFunctionResponse(ctx context.Context, userID, sessionID string, response *models.FunctionResponse, runConfig *types.RunConfig) (<-chan *events.Event, error) {
+	pending, err := r.ListPendingCalls(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	submitted := *response
+	for _, call := range pending {
+		if call.FunctionCall.ID == submitted.ID && submitted.Name == "" {
+			submitted.Name = call.FunctionCall.Name
+		}
+	}
+
+	newMessage := &models.Content{
+		Parts: []*models.Part{{Role: "user", FunctionResponse: &submitted}},
+	}
+	return r.Run(ctx, userID, sessionID, newMessage, runConfig)
+}
+
+// pendingCalls returns the long-running calls of a session that the user has
+// not answered. A response from the tool itself, such as an initial status,
+// leaves the call pending.
+// A confirmation request is settled once the call it is about has a response,
+// such as the rejection recorded when the user moved on without answering.
+func pendingCalls(session *sessions.Session) []*PendingCall {
+	var calls []*PendingCall
+	answered := make(map[string]bool)
+	responded := make(map[string]bool)
+	confirmedCalls := make(map[string]string)
+
+	for _, event := range session.GetAllEvents() {
+		for _, response := range event.GetFunctionResponses() {
+			responded[response.ID] = true
+		}
+		if event.Author == "user" {
+			for _, response := range event.GetFunctionResponses() {
+				answered[response.ID] = true
+			}
+			continue
+		}
+		for id, request := range event.GetToolConfirmationRequests() {
+			confirmedCalls[id] = request.OriginalFunctionCall.ID
+		}
+
+		if len(event.LongRunningToolIDs) == 0 {
+			continue
+		}
+		longRunning := make(map[string]bool, len(event.LongRunningToolIDs))
+		for _, id := range event.LongRunningToolIDs {
+			longRunning[id] = true
+		}
+		for _, functionCall := range event.GetFunctionCalls() {
+			if longRunning[functionCall.ID] {
+				calls = append(calls, &PendingCall{
+					FunctionCall: functionCall,
+					Author:       event.Author,
+					InvocationID: event.InvocationID,
+					EventID:      event.ID,
+				})
+			}
+		}
+	}
+
+	pending := make([]*PendingCall, 0, len(calls))
+	for _, call := range calls {
+		if answered[call.FunctionCall.ID] {
+			continue
+		}
+		if original, ok := confirmedCalls[call.FunctionCall.ID]; ok && responded[original] {
+			continue
+		}
+		pending = append(pending, call)
+	}
+	return pending
+}
+
+// declinedConfirmations returns the events rejecting the calls that wait for
+// the user's approval, for a message that answers none of them. The user moved
+// on, so the calls are cancelled instead of being left without a response in
+// the history the model sees. Each event is authored by the agent that asked
+// for the approval, on its branch.
+func declinedConfirmations(session *sessions.Session, newMessage *models.Content, invocationID string) []*events.Event {
+	if newMessage == nil {
+		return nil
+	}
+	for _, part := range newMessage.Parts {
+		if part.FunctionResponse != nil {
+			return nil
+		}
+	}
+
+	pending := make(map[string]bool)
+	for _, call := range pendingCalls(session) {
+		pending[call.FunctionCall.ID] = true
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var declined []*events.Event
+	for _, event := range session.GetAllEvents() {
+		requests := event.GetToolConfirmationRequests()
+		if len(requests) == 0 {
+			continue
+		}
+
+		var parts []*models.Part
+		for _, functionCall := range event.GetFunctionCalls() {
+			request, ok := requests[functionCall.ID]
+			if !ok || !pending[functionCall.ID] {
+				continue
+			}
+			original := request.OriginalFunctionCall
+			parts = append(parts, &models.Part{FunctionResponse: &models.FunctionResponse{
+				Name:    original.Name,
+				Content: fmt.Sprintf("Error: the user did not confirm the call to %s", original.Name),
+				ID:      original.ID,
+			}})
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		declinedEvent := events.NewEvent()
+		declinedEvent.InvocationID = invocationID
+		declinedEvent.Author = event.Author
+		declinedEvent.Branch = event.Branch
+		declinedEvent.Content = &models.Content{Parts: parts}
+		declined = append(declined, declinedEvent)
+	}
+	return declined
+}
+
+// checkFunctionResponses verifies that every function response in a message
+// answers a pending call of the session
+func checkFunctionResponses(session *sessions.Session, newMessage *models.Content) error {
+	if newMessage == nil {
+		return nil
+	}
+
+	var pending map[string]bool
+	for _, part := range newMessage.Parts {
+		if part.FunctionResponse == nil {
+			continue
+		}
+
+		if pending == nil {
+			pending = make(map[string]bool)
+			for _, call := range pendingCalls(session) {
+				pending[call.FunctionCall.ID] = true
+			}
+		}
+		if !pending[part.FunctionResponse.ID] {
+			return fmt.Errorf("%w: call %q in session %s", ErrCallNotPending, part.FunctionResponse.ID, session.ID)
+		}
+	}
+	return nil
+}
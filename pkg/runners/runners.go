@@ -48,10 +48,10 @@ type Session struct {
 // Runner is an interface for running agents.
 type Runner interface {
 	// Run runs the agent with the given input and produces output.
-	Run(ctx context.Context, agent *agents.Agent, input string) (string, error)
+	Run(ctx context.Context, agent agents.BaseAgent, input string) (string, error)
 
 	// RunInteractive runs the agent in an interactive mode, reading from in and writing to out.
-	RunInteractive(ctx context.Context, agent *agents.Agent, in io.Reader, out io.Writer) error
+	RunInteractive(ctx context.Context, agent agents.BaseAgent, in io.Reader, out io.Writer) error
 
 	// SetSaveSessionEnabled enables or disables session saving.
 	SetSaveSessionEnabled(enabled bool)
@@ -80,7 +80,7 @@ func (r *SimpleRunner) SetSaveSessionEnabled(enabled bool) {
 }
 
 // Run runs the agent with the given input and produces output.
-func (r *SimpleRunner) Run(ctx context.Context, agent *agents.Agent, input string) (string, error) {
+func (r *SimpleRunner) Run(ctx context.Context, agent agents.BaseAgent, input string) (string, error) {
 	if agent == nil {
 		return "", errors.New("agent cannot be nil")
 	}
@@ -91,10 +91,10 @@ func (r *SimpleRunner) Run(ctx context.Context, agent *agents.Agent, input strin
 
 	// Add agent metadata as span attributes
 	span.SetAttribute("agent.name", agent.Name())
-	span.SetAttribute("agent.model", agent.Model())
+	span.SetAttribute("agent.model", agentModel(agent))
 
 	// Process the input with the agent
-	response, err := agent.Process(ctx, input)
+	response, err := agents.ProcessMessage(ctx, agent, input)
 	if err != nil {
 		span.SetAttribute("error", err.Error())
 		return "", err
@@ -109,7 +109,7 @@ func (r *SimpleRunner) Run(ctx context.Context, agent *agents.Agent, input strin
 }
 
 // RunInteractive runs the agent in an interactive mode, reading from in and writing to out.
-func (r *SimpleRunner) RunInteractive(ctx context.Context, agent *agents.Agent, in io.Reader, out io.Writer) error {
+func (r *SimpleRunner) RunInteractive(ctx context.Context, agent agents.BaseAgent, in io.Reader, out io.Writer) error {
 	if agent == nil {
 		return errors.New("agent cannot be nil")
 	}
@@ -120,12 +120,12 @@ func (r *SimpleRunner) RunInteractive(ctx context.Context, agent *agents.Agent,
 
 	// Add agent metadata as span attributes
 	span.SetAttribute("agent.name", agent.Name())
-	span.SetAttribute("agent.model", agent.Model())
+	span.SetAttribute("agent.model", agentModel(agent))
 
 	// Initialize session data if saving is enabled
 	if r.saveSession {
 		r.session.AgentName = agent.Name()
-		r.session.AgentModel = agent.Model()
+		r.session.AgentModel = agentModel(agent)
 		r.session.StartTime = time.Now()
 	}
 
@@ -164,7 +164,7 @@ func (r *SimpleRunner) RunInteractive(ctx context.Context, agent *agents.Agent,
 		interactionSpan.SetAttribute("input", input)
 
 		// Process the input
-		response, err := agent.Process(interactionCtx, input)
+		response, err := agents.ProcessMessage(interactionCtx, agent, input)
 		if err != nil {
 			interactionSpan.SetAttribute("error", err.Error())
 			fmt.Fprintf(out, "Error: %v\n", err)
@@ -198,6 +198,14 @@ func (r *SimpleRunner) RunInteractive(ctx context.Context, agent *agents.Agent,
 	return nil
 }
 
+// agentModel returns the name of the model used by an agent, if it has one
+func agentModel(agent agents.BaseAgent) string {
+	if withModel, ok := agent.(interface{ Model() string }); ok {
+		return withModel.Model()
+	}
+	return ""
+}
+
 // trackInteraction adds an interaction to the current session.
 func (r *SimpleRunner) trackInteraction(input, response string) {
 	r.session.Interactions = append(r.session.Interactions, Interaction{
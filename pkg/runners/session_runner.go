@@ -0,0 +1,327 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/artifacts"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// SessionRunner runs an agent on the sessions of a session service. Each run
+// is an invocation that records the user message and the agent's events in
+// the session, so the next run continues the conversation.
+type SessionRunner struct {
+	// AppName is the application the sessions belong to
+	AppName string
+
+	// Agent is the root agent of the application
+	Agent agents.BaseAgent
+
+	// SessionService stores the sessions
+	SessionService sessions.SessionService
+
+	// ArtifactService stores the artifacts of the sessions, if any
+	ArtifactService artifacts.ArtifactService
+
+	// treeErr holds the problems of the agent tree found by NewSessionRunner
+	treeErr error
+
+	// mu makes checking and recording a new message atomic, so a pending
+	// call is only answered once
+	mu sync.Mutex
+
+	// running holds the cancel functions of the running invocations
+	running   map[string]context.CancelCauseFunc
+	runningMu sync.Mutex
+}
+
+// ErrInvocationNotRunning is returned when cancelling an invocation that has
+// already ended or was never started
+var ErrInvocationNotRunning = errors.New("invocation is not running")
+
+// NewSessionRunner creates a SessionRunner. The agent tree is validated, see
+// agents.ValidateTree; if it is invalid, Run returns its problems.
+func NewSessionRunner(appName string, agent agents.BaseAgent, sessionService sessions.SessionService) *SessionRunner {
+	runner := &SessionRunner{
+		AppName:        appName,
+		Agent:          agent,
+		SessionService: sessionService,
+	}
+	if agent != nil {
+		if err := agents.ValidateTree(agent); err != nil {
+			runner.treeErr = fmt.Errorf("invalid agent tree: %w", err)
+		}
+	}
+	return runner
+}
+
+// GetOrCreateSession returns the session with the given ID, creating it if
+// it does not exist
+func (r *SessionRunner) GetOrCreateSession(ctx context.Context, userID, sessionID string) (*sessions.Session, error) {
+	if session, err := r.SessionService.GetSession(ctx, r.AppName, userID, sessionID, nil); err == nil && session != nil {
+		return session, nil
+	}
+
+	session, err := r.SessionService.CreateSession(ctx, r.AppName, userID, nil, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session %s: %w", sessionID, err)
+	}
+	return session, nil
+}
+
+// Run sends a user message to the agent in an existing session and returns
+// the agent's events. Every complete event is appended to the session before
+// it is sent on the channel. Function responses in the message must answer
+// pending long-running calls, see SubmitFunctionResponse. A message that
+// answers none of the pending tool confirmation requests rejects their calls,
+// and the rejections are recorded in the session before the message.
+//
+// An invocation that runs out of time, see RunConfig.InvocationTimeout, or is
+// cancelled, see Cancel, ends with an error event whose code is
+// events.ErrorCodeDeadlineExceeded or events.ErrorCodeCancelled.
+func (r *SessionRunner) Run(ctx context.Context, userID, sessionID string, newMessage *models.Content, runConfig *types.RunConfig) (<-chan *events.Event, error) {
+	if r.Agent == nil {
+		return nil, errors.New("agent cannot be nil")
+	}
+	if r.treeErr != nil {
+		return nil, r.treeErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, err := r.SessionService.GetSession(ctx, r.AppName, userID, sessionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	if session == nil {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if err := checkFunctionResponses(session, newMessage); err != nil {
+		return nil, err
+	}
+
+	agent, branch := r.agentToRun(session, newMessage)
+	invocationContext := r.newInvocationContext(session, agent, runConfig)
+	invocationContext.Branch = branch
+
+	for _, event := range declinedConfirmations(session, newMessage, invocationContext.InvocationID) {
+		if _, err := r.SessionService.AppendEvent(ctx, session, event); err != nil {
+			return nil, fmt.Errorf("failed to record rejected tool confirmation: %w", err)
+		}
+		invocationContext.AppendEvent(event)
+	}
+
+	userEvent := events.NewEvent()
+	userEvent.InvocationID = invocationContext.InvocationID
+	userEvent.Author = "user"
+	userEvent.Content = newMessage
+	if _, err := r.SessionService.AppendEvent(ctx, session, userEvent); err != nil {
+		return nil, fmt.Errorf("failed to append user message: %w", err)
+	}
+	invocationContext.AppendEvent(userEvent)
+	invocationContext.InvocationEvent = userEvent
+
+	return r.runInvocation(ctx, session, invocationContext)
+}
+
+// agentToRun returns the agent that handles a message and the branch it runs
+// on. Function responses, such as the answer to a tool confirmation request,
+// go to the agent that made the function call, on the branch it made the call
+// on, and its parents continue from there, see agents.ResumeAgent; other
+// messages go to the root agent.
+func (r *SessionRunner) agentToRun(session *sessions.Session, newMessage *models.Content) (agents.BaseAgent, string) {
+	if newMessage == nil {
+		return r.Agent, ""
+	}
+
+	sessionEvents := session.GetAllEvents()
+	for _, part := range newMessage.Parts {
+		if part.FunctionResponse == nil {
+			continue
+		}
+		for i := len(sessionEvents) - 1; i >= 0; i-- {
+			for _, functionCall := range sessionEvents[i].GetFunctionCalls() {
+				if functionCall.ID != part.FunctionResponse.ID {
+					continue
+				}
+				if agent := r.Agent.FindAgent(sessionEvents[i].Author); agent != nil {
+					return agent, sessionEvents[i].Branch
+				}
+				return r.Agent, ""
+			}
+		}
+	}
+	return r.Agent, ""
+}
+
+// newInvocationContext creates the context of a new invocation of an agent in
+// a session, with the session's events as its history
+func (r *SessionRunner) newInvocationContext(session *sessions.Session, agent agents.BaseAgent, runConfig *types.RunConfig) *agents.InvocationContext {
+	invocationContext := agents.NewInvocationContext("e-"+uuid.New().String(), agent, runConfig)
+	invocationContext.Session = session
+	invocationContext.ArtifactService = r.ArtifactService
+
+	for _, event := range session.GetAllEvents() {
+		invocationContext.AppendEvent(event)
+	}
+	return invocationContext
+}
+
+// Cancel stops a running invocation. The invocation ends with an error event
+// whose code is events.ErrorCodeCancelled.
+func (r *SessionRunner) Cancel(invocationID string) error {
+	r.runningMu.Lock()
+	cancel, ok := r.running[invocationID]
+	r.runningMu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvocationNotRunning, invocationID)
+	}
+
+	cancel(fmt.Errorf("invocation %s was cancelled: %w", invocationID, context.Canceled))
+	return nil
+}
+
+// startInvocation returns the context of an invocation, limited by the run's
+// invocation timeout, and registers the invocation so it can be cancelled.
+// The returned function releases the invocation.
+func (r *SessionRunner) startInvocation(ctx context.Context, invocationContext *agents.InvocationContext) (context.Context, func()) {
+	invocationID := invocationContext.InvocationID
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	stopTimer := context.CancelFunc(func() {})
+	if runConfig := invocationContext.RunConfig; runConfig != nil && runConfig.InvocationTimeout > 0 {
+		timeout := runConfig.InvocationTimeout
+		ctx, stopTimer = context.WithTimeoutCause(ctx, timeout,
+			fmt.Errorf("invocation %s timed out after %s: %w", invocationID, timeout, context.DeadlineExceeded))
+	}
+
+	r.runningMu.Lock()
+	if r.running == nil {
+		r.running = make(map[string]context.CancelCauseFunc)
+	}
+	r.running[invocationID] = cancel
+	r.runningMu.Unlock()
+
+	return ctx, func() {
+		r.runningMu.Lock()
+		delete(r.running, invocationID)
+		r.runningMu.Unlock()
+
+		stopTimer()
+		cancel(nil)
+	}
+}
+
+// runInvocation runs the agent and records its events in the session
+func (r *SessionRunner) runInvocation(ctx context.Context, session *sessions.Session, invocationContext *agents.InvocationContext) (<-chan *events.Event, error) {
+	ctx, span := telemetry.StartSpan(ctx, "SessionRunner.Run")
+
+	span.SetAttribute("agent.name", invocationContext.Agent.Name())
+	span.SetAttribute("session.id", session.ID)
+	span.SetAttribute("invocation.id", invocationContext.InvocationID)
+
+	ctx, release := r.startInvocation(ctx, invocationContext)
+
+	agentEvents, err := agents.ResumeAgent(ctx, invocationContext)
+	if err != nil {
+		release()
+		span.SetAttribute("error", err.Error())
+		span.End()
+		return nil, err
+	}
+
+	eventCh := make(chan *events.Event)
+
+	// Events are recorded even after the invocation was stopped
+	recordCtx := context.WithoutCancel(ctx)
+	record := func(event *events.Event) {
+		if !event.Partial {
+			if _, err := r.SessionService.AppendEvent(recordCtx, session, event); err != nil {
+				log.Printf("Failed to append event %s to session %s: %v", event.ID, session.ID, err)
+			}
+		}
+		eventCh <- event
+	}
+
+	go func() {
+		defer close(eventCh)
+		defer span.End()
+		defer release()
+		defer func() {
+			usage := invocationContext.GetUsage()
+			telemetry.SetUsageAttributes(span, "invocation", usage)
+			telemetry.SetUsageAttributes(span, "session", agents.SessionUsage(session))
+		}()
+
+		var lastEvent *events.Event
+		for {
+			select {
+			case event, ok := <-agentEvents:
+				if !ok {
+					return
+				}
+				record(event)
+				lastEvent = event
+				continue
+			case <-ctx.Done():
+			}
+
+			// The agent may be stuck, so the runner ends the invocation.
+			// Events the agent still sends are discarded.
+			go func() {
+				for range agentEvents {
+				}
+			}()
+
+			stopEvent := newStopEvent(ctx, invocationContext)
+			span.SetAttribute("error", stopEvent.ErrorMessage)
+			if lastEvent == nil || lastEvent.ErrorCode != stopEvent.ErrorCode {
+				record(stopEvent)
+			}
+			return
+		}
+	}()
+
+	return eventCh, nil
+}
+
+// newStopEvent creates the error event ending an invocation that ran out of
+// time or was cancelled
+func newStopEvent(ctx context.Context, invocationContext *agents.InvocationContext) *events.Event {
+	event := events.NewEvent()
+	event.InvocationID = invocationContext.InvocationID
+	event.Author = invocationContext.Agent.Name()
+	event.Branch = invocationContext.Branch
+	cause := context.Cause(ctx)
+	event.ErrorCode = events.ContextErrorCode(cause)
+	if event.ErrorCode == "" {
+		event.ErrorCode = events.ContextErrorCode(ctx.Err())
+	}
+	event.ErrorMessage = cause.Error()
+	return event
+}
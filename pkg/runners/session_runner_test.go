@@ -0,0 +1,325 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/agents"
+	"github.com/nvcnvn/adk-golang/pkg/events"
+	_ "github.com/nvcnvn/adk-golang/pkg/flows/llm_flows"
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/sessions"
+	"github.com/nvcnvn/adk-golang/pkg/telemetry"
+	"github.com/nvcnvn/adk-golang/pkg/tools"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+func TestSessionRunnerRejectsInvalidTree(t *testing.T) {
+	root := agents.NewSequentialAgent(agents.SequentialAgentConfig{
+		Name:      "root",
+		SubAgents: []agents.BaseAgent{agents.NewLlmAgent("helper", nil), agents.NewLlmAgent("helper", nil)},
+	})
+	runner := NewSessionRunner("app", root, sessions.NewInMemorySessionService())
+
+	message := &models.Content{Role: models.RoleUser, Parts: []*models.Part{{Text: "Hi"}}}
+	_, err := runner.Run(context.Background(), "user", "session", message, nil)
+	if err == nil || !strings.Contains(err.Error(), "helper") {
+		t.Errorf("Run error = %v, want the duplicate agent name reported", err)
+	}
+}
+
+// recordingLlm is a model that calls its tool, if any, on its first request,
+// answers with text afterwards, and records its requests
+type recordingLlm struct {
+	tool     string
+	requests []*models.LlmRequest
+}
+
+func (m *recordingLlm) SupportedModels() []string { return nil }
+
+func (m *recordingLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	snapshot := *request
+	snapshot.Contents = append([]*models.Content(nil), request.Contents...)
+	m.requests = append(m.requests, &snapshot)
+
+	part := &models.Part{Text: "OK."}
+	if m.tool != "" && len(m.requests) == 1 {
+		part = &models.Part{FunctionCall: &models.FunctionCall{Name: m.tool, ID: "call-" + m.tool, Arguments: "{}"}}
+	}
+	return &models.LlmResponse{Content: &models.Content{Role: models.RoleAssistant, Parts: []*models.Part{part}}}, nil
+}
+
+func (m *recordingLlm) GenerateContentStream(ctx context.Context, request *models.LlmRequest) (<-chan *models.LlmResponse, error) {
+	response, err := m.GenerateContent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	responseCh := make(chan *models.LlmResponse, 1)
+	responseCh <- response
+	close(responseCh)
+	return responseCh, nil
+}
+
+func (m *recordingLlm) Connect(ctx context.Context, request *models.LlmRequest) (models.LlmConnection, error) {
+	return nil, fmt.Errorf("live connections are not supported")
+}
+
+// runMessage sends a text message to the runner and waits for its events
+func runMessage(t *testing.T, runner *SessionRunner, text string) []*events.Event {
+	t.Helper()
+
+	message := &models.Content{Role: models.RoleUser, Parts: []*models.Part{{Text: text}}}
+	eventCh, err := runner.Run(context.Background(), "user", "session", message, &types.RunConfig{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var result []*events.Event
+	for event := range eventCh {
+		if event.ErrorCode != "" {
+			t.Errorf("unexpected error event: %s %s", event.ErrorCode, event.ErrorMessage)
+		}
+		result = append(result, event)
+	}
+	return result
+}
+
+func TestTextMessageDeclinesPendingConfirmation(t *testing.T) {
+	paid := false
+	pay, err := tools.NewFunctionTool(func() string {
+		paid = true
+		return "paid"
+	}, tools.FunctionToolConfig{Name: "pay", RequireConfirmation: true})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+
+	model := &recordingLlm{tool: "pay"}
+	payer := agents.NewLlmAgent("payer", model)
+	payer.CanonicalTools = []tools.Tool{pay}
+
+	runner := NewSessionRunner("app", payer, sessions.NewInMemorySessionService())
+	if _, err := runner.GetOrCreateSession(context.Background(), "user", "session"); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	runMessage(t, runner, "Pay the bill.")
+	pending, err := runner.ListPendingCalls(context.Background(), "user", "session")
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending calls = %v (%v), want the confirmation request", pending, err)
+	}
+
+	runMessage(t, runner, "Never mind.")
+	if paid {
+		t.Errorf("the tool ran without being confirmed")
+	}
+	if pending, _ := runner.ListPendingCalls(context.Background(), "user", "session"); len(pending) != 0 {
+		t.Errorf("pending calls = %v, want none", pending)
+	}
+
+	// The model sees the call answered before the new message
+	if len(model.requests) != 2 {
+		t.Fatalf("model was called %d times, want 2", len(model.requests))
+	}
+	var turns []string
+	for _, content := range model.requests[1].Contents {
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				turns = append(turns, "call "+part.FunctionCall.ID)
+			case part.FunctionResponse != nil:
+				turns = append(turns, "response "+part.FunctionResponse.ID)
+			default:
+				turns = append(turns, part.Text)
+			}
+		}
+	}
+	want := []string{"Pay the bill.", "call call-pay", "response call-pay", "Never mind."}
+	if strings.Join(turns, "|") != strings.Join(want, "|") {
+		t.Errorf("model history = %q, want %q", turns, want)
+	}
+}
+
+func TestResumedCallKeepsItsBranch(t *testing.T) {
+	paid := false
+	pay, err := tools.NewFunctionTool(func() string {
+		paid = true
+		return "paid"
+	}, tools.FunctionToolConfig{Name: "pay", RequireConfirmation: true})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+
+	payerModel := &recordingLlm{}
+	payer := agents.NewLlmAgent("payer", payerModel)
+	payer.CanonicalTools = []tools.Tool{pay}
+	root := agents.NewParallelAgent(agents.ParallelAgentConfig{
+		Name:      "root",
+		SubAgents: []agents.BaseAgent{payer, agents.NewLlmAgent("other", &recordingLlm{})},
+	})
+
+	ctx := context.Background()
+	runner := NewSessionRunner("app", root, sessions.NewInMemorySessionService())
+	session, err := runner.GetOrCreateSession(ctx, "user", "session")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+
+	// The payer paused on its branch while its sibling answered on another
+	functionCall := &models.FunctionCall{Name: "pay", ID: "call-pay", Arguments: "{}"}
+	request, err := json.Marshal(&events.ToolConfirmationRequest{
+		OriginalFunctionCall: functionCall,
+		ToolConfirmation:     &events.ToolConfirmation{Hint: "Pay?"},
+	})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	history := []struct {
+		author, branch string
+		part           *models.Part
+	}{
+		{"user", "", &models.Part{Text: "Pay the bill."}},
+		{"payer", "root.payer", &models.Part{FunctionCall: functionCall}},
+		{"payer", "root.payer", &models.Part{FunctionCall: &models.FunctionCall{
+			Name: events.RequestConfirmationFunctionName, ID: "request", Arguments: string(request)}}},
+		{"other", "root.other", &models.Part{Text: "Other answer."}},
+	}
+	for _, entry := range history {
+		event := events.NewEvent()
+		event.InvocationID = "paused"
+		event.Author = entry.author
+		event.Branch = entry.branch
+		event.Content = &models.Content{Parts: []*models.Part{entry.part}}
+		if entry.part.FunctionCall != nil && entry.part.FunctionCall.ID == "request" {
+			event.LongRunningToolIDs = []string{"request"}
+		}
+		if _, err := runner.SessionService.AppendEvent(ctx, session, event); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	approval, err := events.NewToolConfirmationResponse("request", &events.ToolConfirmation{Confirmed: true})
+	if err != nil {
+		t.Fatalf("NewToolConfirmationResponse: %v", err)
+	}
+	eventCh, err := runner.Run(ctx, "user", "session", approval, &types.RunConfig{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for event := range eventCh {
+		if event.ErrorCode != "" {
+			t.Errorf("unexpected error event: %s %s", event.ErrorCode, event.ErrorMessage)
+		}
+		if event.Author == "payer" && event.Branch != "root.payer" {
+			t.Errorf("payer event on branch %q, want root.payer", event.Branch)
+		}
+	}
+
+	if !paid {
+		t.Errorf("the confirmed tool did not run")
+	}
+	if len(payerModel.requests) != 1 {
+		t.Fatalf("payer model was called %d times, want 1", len(payerModel.requests))
+	}
+	for _, content := range payerModel.requests[0].Contents {
+		for _, part := range content.Parts {
+			if strings.Contains(part.Text, "Other answer.") {
+				t.Errorf("the resumed payer saw its sibling's answer")
+			}
+		}
+	}
+}
+
+func TestOutputKeyIsPersistedInTheSession(t *testing.T) {
+	agent := agents.NewLlmAgent("agent", &recordingLlm{})
+	agent.OutputKey = "answer"
+
+	runner := NewSessionRunner("app", agent, sessions.NewInMemorySessionService())
+	if _, err := runner.GetOrCreateSession(context.Background(), "user", "session"); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	runMessage(t, runner, "Hi")
+
+	session, err := runner.SessionService.GetSession(context.Background(), "app", "user", "session", nil)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if value, _ := session.GetState("answer"); value != "OK." {
+		t.Errorf("answer state = %v, want OK.", value)
+	}
+}
+
+// usageLlm is a recordingLlm that reports the same token usage for every call
+type usageLlm struct {
+	recordingLlm
+	usage models.UsageMetadata
+}
+
+func (m *usageLlm) GenerateContent(ctx context.Context, request *models.LlmRequest) (*models.LlmResponse, error) {
+	response, err := m.recordingLlm.GenerateContent(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	usage := m.usage
+	response.UsageMetadata = &usage
+	return response, nil
+}
+
+func TestRunnerRecordsUsageOnItsSpan(t *testing.T) {
+	tracer := telemetry.NewSimpleTracer()
+	previous := telemetry.GetDefaultTracer()
+	telemetry.SetDefaultTracer(tracer)
+	t.Cleanup(func() { telemetry.SetDefaultTracer(previous) })
+
+	model := &usageLlm{usage: models.UsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 20, CachedContentTokenCount: 60, ThoughtsTokenCount: 30}}
+	runner := NewSessionRunner("app", agents.NewLlmAgent("agent", model), sessions.NewInMemorySessionService())
+	if _, err := runner.GetOrCreateSession(context.Background(), "user", "session"); err != nil {
+		t.Fatalf("GetOrCreateSession: %v", err)
+	}
+	runMessage(t, runner, "Hi")
+	runMessage(t, runner, "Hi again")
+
+	var spans []*telemetry.SimpleSpan
+	for _, span := range tracer.GetSpans() {
+		if span.Name == "SessionRunner.Run" {
+			spans = append(spans, span)
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("got %d runner spans, want 2", len(spans))
+	}
+
+	// The second invocation reports its own usage and the session's total
+	for key, value := range map[string]string{
+		"invocation.usage.input_tokens":        "100",
+		"invocation.usage.output_tokens":       "50",
+		"invocation.usage.cached_input_tokens": "60",
+		"invocation.usage.thought_tokens":      "30",
+		"session.usage.input_tokens":           "200",
+		"session.usage.output_tokens":          "100",
+		"session.usage.cached_input_tokens":    "120",
+		"session.usage.thought_tokens":         "60",
+	} {
+		if got := spans[1].Attributes[key]; got != value {
+			t.Errorf("span attribute %s = %q, want %q", key, got, value)
+		}
+	}
+}
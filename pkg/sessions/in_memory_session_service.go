@@ -190,11 +190,13 @@ func (s *InMemorySessionService) AppendEvent(
 	// Add the event
 	storedSession.AddEvent(event)
 
-	// Update the original session to match the stored one
-	session.Events = make([]*events.Event, len(storedSession.Events))
-	copy(session.Events, storedSession.Events)
-	session.StateMap = storedSession.StateMap
-	session.UpdateTime = storedSession.UpdateTime
+	// Update the original session to match the stored one, unless the caller
+	// holds the stored session itself
+	if session != storedSession {
+		session.Events = storedSession.GetAllEvents()
+		session.StateMap = storedSession.StateMap
+		session.UpdateTime = storedSession.UpdateTime
+	}
 
 	return event, nil
 }
@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"strconv"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+// Span attributes of a model call. They follow the OpenTelemetry semantic
+// conventions for generative AI where one exists.
+const (
+	AttributeResponseModel = "gen_ai.response.model"
+	AttributeInputTokens   = "gen_ai.usage.input_tokens"
+	AttributeOutputTokens  = "gen_ai.usage.output_tokens"
+	AttributeCachedTokens  = "gen_ai.usage.cached_input_tokens"
+	AttributeThoughtTokens = "gen_ai.usage.thought_tokens"
+	AttributeTotalTokens   = "gen_ai.usage.total_tokens"
+)
+
+// SetLlmResponseAttributes records the model and the token usage of a model
+// response on a span. Counts the model does not report are left out.
+func SetLlmResponseAttributes(span Span, response *models.LlmResponse) {
+	if response.ModelVersion != "" {
+		span.SetAttribute(AttributeResponseModel, response.ModelVersion)
+	}
+
+	usage := response.UsageMetadata
+	if usage == nil {
+		return
+	}
+	setCount(span, AttributeInputTokens, usage.PromptTokenCount)
+	setCount(span, AttributeOutputTokens, usage.CandidatesTokenCount)
+	setCount(span, AttributeCachedTokens, usage.CachedContentTokenCount)
+	setCount(span, AttributeThoughtTokens, usage.ThoughtsTokenCount)
+	setCount(span, AttributeTotalTokens, usage.TotalTokenCount)
+}
+
+// SetUsageAttributes records the total usage of several model calls on a
+// span. The attribute names start with the scope of the total, such as
+// "invocation" or "session".
+func SetUsageAttributes(span Span, scope string, usage types.Usage) {
+	span.SetAttribute(scope+".usage.input_tokens", strconv.Itoa(usage.InputTokens))
+	span.SetAttribute(scope+".usage.output_tokens", strconv.Itoa(usage.OutputTokens))
+	span.SetAttribute(scope+".usage.cached_input_tokens", strconv.Itoa(usage.CachedInputTokens))
+	span.SetAttribute(scope+".usage.thought_tokens", strconv.Itoa(usage.ThoughtTokens))
+	span.SetAttribute(scope+".usage.cost", strconv.FormatFloat(usage.Cost, 'f', -1, 64))
+}
+
+// setCount sets a token count attribute if the count is known
+func setCount(span Span, key string, count int) {
+	if count > 0 {
+		span.SetAttribute(key, strconv.Itoa(count))
+	}
+}
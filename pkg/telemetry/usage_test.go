@@ -0,0 +1,101 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
+	"github.com/nvcnvn/adk-golang/pkg/types"
+)
+
+func TestSetLlmResponseAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *models.LlmResponse
+		want     map[string]string
+	}{
+		{
+			name: "full usage",
+			response: &models.LlmResponse{
+				ModelVersion: "gemini-2.5-flash",
+				UsageMetadata: &models.UsageMetadata{
+					PromptTokenCount:        100,
+					CandidatesTokenCount:    20,
+					CachedContentTokenCount: 60,
+					ThoughtsTokenCount:      30,
+					TotalTokenCount:         150,
+				},
+			},
+			want: map[string]string{
+				AttributeResponseModel: "gemini-2.5-flash",
+				AttributeInputTokens:   "100",
+				AttributeOutputTokens:  "20",
+				AttributeCachedTokens:  "60",
+				AttributeThoughtTokens: "30",
+				AttributeTotalTokens:   "150",
+			},
+		},
+		{
+			name: "unreported counts",
+			response: &models.LlmResponse{UsageMetadata: &models.UsageMetadata{
+				PromptTokenCount:     10,
+				CandidatesTokenCount: 2,
+			}},
+			want: map[string]string{
+				AttributeInputTokens:  "10",
+				AttributeOutputTokens: "2",
+			},
+		},
+		{
+			name:     "no usage",
+			response: &models.LlmResponse{ModelVersion: "gemini-2.5-flash"},
+			want:     map[string]string{AttributeResponseModel: "gemini-2.5-flash"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			span := NewSimpleSpan("call")
+			SetLlmResponseAttributes(span, test.response)
+			if !reflect.DeepEqual(span.Attributes, test.want) {
+				t.Errorf("attributes = %v, want %v", span.Attributes, test.want)
+			}
+		})
+	}
+}
+
+func TestSetUsageAttributes(t *testing.T) {
+	span := NewSimpleSpan("invocation")
+	SetUsageAttributes(span, "session", types.Usage{
+		InputTokens:       100,
+		OutputTokens:      50,
+		CachedInputTokens: 60,
+		ThoughtTokens:     30,
+		Cost:              0.0125,
+	})
+
+	want := map[string]string{
+		"session.usage.input_tokens":        "100",
+		"session.usage.output_tokens":       "50",
+		"session.usage.cached_input_tokens": "60",
+		"session.usage.thought_tokens":      "30",
+		"session.usage.cost":                "0.0125",
+	}
+	if !reflect.DeepEqual(span.Attributes, want) {
+		t.Errorf("attributes = %v, want %v", span.Attributes, want)
+	}
+}
@@ -118,3 +118,8 @@ func (a *AgentTool) execute(ctx context.Context, input map[string]interface{}) (
 func (a *AgentTool) SetSkipSummarization(skip bool) {
 	a.skipSummarization = skip
 }
+
+// Agent returns the wrapped agent
+func (a *AgentTool) Agent() WrappableAgent {
+	return a.agent
+}
@@ -35,9 +35,13 @@ var ExitLoopTool = NewTool(
 		},
 	},
 	func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
-		// In a real implementation, this would set a flag in the execution context
-		// to indicate that the loop should exit
-		// For now we just return success
+		// Escalate so the enclosing loop agent stops, and skip summarization so
+		// the calling agent ends its turn without another model call
+		if toolContext, ok := ToolContextFromContext(ctx); ok && toolContext.EventActions != nil {
+			toolContext.EventActions.Escalate = true
+			toolContext.EventActions.SkipSummarization = true
+		}
+
 		return map[string]interface{}{
 			"success": true,
 			"message": "Loop exit signal sent",
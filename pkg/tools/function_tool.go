@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 // FunctionTool wraps a Go function as a tool that can be used by agents.
@@ -44,6 +45,14 @@ type FunctionToolConfig struct {
 
 	// IsLongRunning indicates if the tool takes a long time to execute.
 	IsLongRunning bool
+
+	// RequireConfirmation indicates if each call needs the user's approval
+	// before the function runs.
+	RequireConfirmation bool
+
+	// Timeout limits how long each call may run, overriding the run's tool
+	// timeout. Zero uses the run's tool timeout.
+	Timeout time.Duration
 }
 
 // NewFunctionTool creates a tool that wraps a Go function.
@@ -109,7 +118,10 @@ func NewFunctionTool(fn interface{}, config FunctionToolConfig) (*LlmToolAdaptor
 	functionTool.BaseTool = baseTool
 
 	// Create and return the tool adaptor
-	return NewLlmToolAdaptor(baseTool, config.IsLongRunning), nil
+	adaptor := NewLlmToolAdaptor(baseTool, config.IsLongRunning)
+	adaptor.requireConfirmation = config.RequireConfirmation
+	adaptor.timeout = config.Timeout
+	return adaptor, nil
 }
 
 // execute runs the wrapped function with the given input arguments
@@ -131,9 +143,12 @@ func (ft *FunctionTool) execute(ctx context.Context, input map[string]interface{
 
 		// Check if parameter is ToolContext
 		if paramType == reflect.TypeOf(&ToolContext{}) && ft.takesToolCtx {
-			// This would normally come from the execution context
-			// For now, create an empty one, this will be filled in by the LlmToolAdaptor
-			args = append(args, reflect.ValueOf(&ToolContext{}))
+			// The LlmToolAdaptor passes the tool context through ctx
+			toolContext, ok := ToolContextFromContext(ctx)
+			if !ok {
+				toolContext = &ToolContext{}
+			}
+			args = append(args, reflect.ValueOf(toolContext))
 			continue
 		}
 
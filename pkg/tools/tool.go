@@ -17,6 +17,9 @@ package tools
 
 import (
 	"context"
+	"sort"
+
+	"github.com/nvcnvn/adk-golang/pkg/models"
 )
 
 // Tool represents a capability that can be provided to an agent.
@@ -89,3 +92,50 @@ func (b *BaseTool) Execute(ctx context.Context, input map[string]interface{}) (m
 func (b *BaseTool) Schema() ToolSchema {
 	return b.schema
 }
+
+// ToJSONSchema converts the parameter schema to a JSON schema map.
+// Properties marked as Required are collected into the "required" list.
+func (p ParameterSchema) ToJSONSchema() map[string]interface{} {
+	schema := map[string]interface{}{
+		"type": p.Type,
+	}
+
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+
+	if len(p.Properties) > 0 {
+		properties := make(map[string]interface{}, len(p.Properties))
+		required := make([]string, 0)
+
+		for name, property := range p.Properties {
+			properties[name] = property.ToJSONSchema()
+			if property.Required {
+				required = append(required, name)
+			}
+		}
+
+		schema["properties"] = properties
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+	}
+
+	return schema
+}
+
+// FunctionDeclaration builds the declaration sent to the model for a tool
+func FunctionDeclaration(tool Tool, isLongRunning bool) *models.Tool {
+	declaration := &models.Tool{
+		Name:          tool.Name(),
+		Description:   tool.Description(),
+		IsLongRunning: isLongRunning,
+	}
+
+	if input := tool.Schema().Input; input.Type != "" {
+		declaration.InputSchema = input.ToJSONSchema()
+	}
+
+	return declaration
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"github.com/nvcnvn/adk-golang/pkg/events"
+)
+
+// ConfirmationRequirer is implemented by tools whose calls always need the
+// user's approval before they run
+type ConfirmationRequirer interface {
+	RequiresConfirmation() bool
+}
+
+// ToolConfirmationPolicy decides whether a tool call needs the user's approval
+// before it runs. It returns what the user is asked to confirm, or nil to run
+// the call right away.
+type ToolConfirmationPolicy func(tool Tool, args map[string]interface{}, toolContext *ToolContext) *events.ToolConfirmation
+
+// RequireConfirmation returns a tool whose every call waits for the user's
+// approval. Tools that are already adaptors are copied with their other
+// settings; the given tool is left unchanged.
+func RequireConfirmation(tool Tool) *LlmToolAdaptor {
+	var adaptor *LlmToolAdaptor
+	if existing, ok := tool.(*LlmToolAdaptor); ok {
+		copied := *existing
+		adaptor = &copied
+	} else {
+		adaptor = NewLlmToolAdaptor(tool, false)
+	}
+	adaptor.requireConfirmation = true
+	return adaptor
+}
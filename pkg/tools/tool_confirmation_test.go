@@ -0,0 +1,35 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import "testing"
+
+func TestRequireConfirmationCopiesAdaptor(t *testing.T) {
+	tool, err := NewFunctionTool(func() string { return "paid" }, FunctionToolConfig{Name: "pay", IsLongRunning: true})
+	if err != nil {
+		t.Fatalf("NewFunctionTool: %v", err)
+	}
+
+	confirmed := RequireConfirmation(tool)
+	if !confirmed.RequiresConfirmation() {
+		t.Errorf("the returned tool does not require confirmation")
+	}
+	if !confirmed.IsLongRunning() {
+		t.Errorf("the returned tool lost its other settings")
+	}
+	if tool.RequiresConfirmation() {
+		t.Errorf("the given tool now requires confirmation")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/nvcnvn/adk-golang/pkg/events"
 	"github.com/nvcnvn/adk-golang/pkg/models"
@@ -30,6 +31,7 @@ type InvocationContext interface {
 	IsEndInvocation() bool
 	SetEndInvocation(end bool)
 	GetTranscriptionCache() interface{}
+	GetState(key string) (interface{}, bool)
 }
 
 // ToolContext provides context for tool execution
@@ -39,6 +41,107 @@ type ToolContext struct {
 
 	// EventActions contains actions associated with an event
 	EventActions *events.EventActions
+
+	// FunctionCallID is the ID of the function call being executed
+	FunctionCallID string
+
+	// ToolConfirmation is the user's approval of the call, set when a call
+	// that required confirmation is resumed
+	ToolConfirmation *events.ToolConfirmation
+}
+
+// GetState returns a state value, including changes made earlier in the same tool call
+func (tc *ToolContext) GetState(key string) (interface{}, bool) {
+	if tc.EventActions != nil {
+		if value, ok := tc.EventActions.StateDelta[key]; ok {
+			return value, true
+		}
+	}
+	if tc.InvocationContext == nil {
+		return nil, false
+	}
+	return tc.InvocationContext.GetState(key)
+}
+
+// SetState records a state change in the actions of the function response event
+func (tc *ToolContext) SetState(key string, value interface{}) {
+	if tc.EventActions == nil {
+		tc.EventActions = events.NewEventActions()
+	}
+	if tc.EventActions.StateDelta == nil {
+		tc.EventActions.StateDelta = make(map[string]interface{})
+	}
+	tc.EventActions.StateDelta[key] = value
+}
+
+// RequestConfirmation asks the user to approve the call before it takes
+// effect. The tool's response is discarded and the invocation pauses until the
+// user answers; the call is then run again with ToolConfirmation set.
+func (tc *ToolContext) RequestConfirmation(hint string, payload interface{}) {
+	if tc.EventActions == nil {
+		tc.EventActions = events.NewEventActions()
+	}
+	if tc.EventActions.RequestedToolConfirmations == nil {
+		tc.EventActions.RequestedToolConfirmations = make(map[string]*events.ToolConfirmation)
+	}
+	tc.EventActions.RequestedToolConfirmations[tc.FunctionCallID] = &events.ToolConfirmation{
+		Hint:    hint,
+		Payload: payload,
+	}
+}
+
+// toolContextKey is the context key under which the ToolContext is stored
+type toolContextKey struct{}
+
+// ContextWithToolContext returns a copy of ctx carrying the given ToolContext
+func ContextWithToolContext(ctx context.Context, toolContext *ToolContext) context.Context {
+	return context.WithValue(ctx, toolContextKey{}, toolContext)
+}
+
+// ToolContextFromContext returns the ToolContext carried by ctx, if any
+func ToolContextFromContext(ctx context.Context) (*ToolContext, bool) {
+	toolContext, ok := ctx.Value(toolContextKey{}).(*ToolContext)
+	return toolContext, ok && toolContext != nil
+}
+
+// LlmRequestProcessor is implemented by tools that add themselves to the LLM request
+type LlmRequestProcessor interface {
+	ProcessLlmRequest(ctx context.Context, toolContext *ToolContext, llmRequest *models.LlmRequest) error
+}
+
+// FunctionCallExecutor is implemented by tools that execute model function calls
+type FunctionCallExecutor interface {
+	ExecuteFunctionCall(ctx context.Context, toolContext *ToolContext, functionCall *models.FunctionCall) (string, error)
+}
+
+// AddToLlmRequest makes a tool available to the model. Tools implementing
+// LlmRequestProcessor update the request themselves, other tools are added
+// as plain function declarations.
+func AddToLlmRequest(ctx context.Context, toolContext *ToolContext, tool Tool, llmRequest *models.LlmRequest) error {
+	if processor, ok := tool.(LlmRequestProcessor); ok {
+		return processor.ProcessLlmRequest(ctx, toolContext, llmRequest)
+	}
+	llmRequest.AppendTools(FunctionDeclaration(tool, false))
+	return nil
+}
+
+// AsFunctionCallExecutor returns the tool as a FunctionCallExecutor, wrapping
+// it in an LlmToolAdaptor if it cannot execute function calls itself
+func AsFunctionCallExecutor(tool Tool) FunctionCallExecutor {
+	if executor, ok := tool.(FunctionCallExecutor); ok {
+		return executor
+	}
+	return NewLlmToolAdaptor(tool, false)
+}
+
+// FindTool returns the tool with the given name, or nil if there is none
+func FindTool(toolList []Tool, name string) Tool {
+	for _, tool := range toolList {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	return nil
 }
 
 // LlmToolAdaptor wraps an existing Tool to add LLM-specific functionality
@@ -51,6 +154,12 @@ type LlmToolAdaptor struct {
 	// Whether this tool takes a long time to execute
 	isLongRunning bool
 
+	// Whether every call needs the user's approval before it runs
+	requireConfirmation bool
+
+	// How long each call may run, overriding the run's tool timeout
+	timeout time.Duration
+
 	// ProcessLlmRequestFunc is called before the LLM is called
 	processLlmRequestFunc func(ctx context.Context, toolContext *ToolContext, llmRequest *models.LlmRequest) error
 }
@@ -83,25 +192,46 @@ func (a *LlmToolAdaptor) Schema() ToolSchema {
 	return a.tool.Schema()
 }
 
+// RequiresConfirmation returns whether every call needs the user's approval
+// before it runs
+func (a *LlmToolAdaptor) RequiresConfirmation() bool {
+	return a.requireConfirmation
+}
+
+// Timeout returns how long each call may run, or zero to use the run's tool
+// timeout
+func (a *LlmToolAdaptor) Timeout() time.Duration {
+	return a.timeout
+}
+
 // IsLongRunning returns whether this tool takes a long time to execute
 func (a *LlmToolAdaptor) IsLongRunning() bool {
 	return a.isLongRunning
 }
 
-// ProcessLlmRequest processes the LLM request before it is sent
+// ProcessLlmRequest processes the LLM request before it is sent.
+// By default the tool's function declaration is added to the request.
 func (a *LlmToolAdaptor) ProcessLlmRequest(ctx context.Context, toolContext *ToolContext, llmRequest *models.LlmRequest) error {
 	if a.processLlmRequestFunc != nil {
 		return a.processLlmRequestFunc(ctx, toolContext, llmRequest)
 	}
+	llmRequest.AppendTools(FunctionDeclaration(a.tool, a.isLongRunning))
 	return nil
 }
 
-// ExecuteFunctionCall executes a function call using the wrapped tool
+// ExecuteFunctionCall executes a function call using the wrapped tool.
+// The tool context is made available to the tool through ToolContextFromContext.
 func (a *LlmToolAdaptor) ExecuteFunctionCall(ctx context.Context, toolContext *ToolContext, functionCall *models.FunctionCall) (string, error) {
 	// Parse the arguments from the function call
-	var args map[string]interface{}
-	if err := json.Unmarshal([]byte(functionCall.Arguments), &args); err != nil {
-		return "", fmt.Errorf("failed to parse function arguments: %v", err)
+	args := make(map[string]interface{})
+	if functionCall.Arguments != "" {
+		if err := json.Unmarshal([]byte(functionCall.Arguments), &args); err != nil {
+			return "", fmt.Errorf("failed to parse function arguments: %v", err)
+		}
+	}
+
+	if toolContext != nil {
+		ctx = ContextWithToolContext(ctx, toolContext)
 	}
 
 	// Execute the wrapped tool
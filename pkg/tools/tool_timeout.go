@@ -0,0 +1,37 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"time"
+)
+
+// TimeoutTool is implemented by tools that limit how long each of their calls
+// may run. A zero timeout uses the run's tool timeout.
+type TimeoutTool interface {
+	Timeout() time.Duration
+}
+
+// WithTimeout limits how long each call of a tool may run, overriding the
+// run's tool timeout. Tools that are already adaptors keep their other
+// settings.
+func WithTimeout(tool Tool, timeout time.Duration) *LlmToolAdaptor {
+	adaptor, ok := tool.(*LlmToolAdaptor)
+	if !ok {
+		adaptor = NewLlmToolAdaptor(tool, false)
+	}
+	adaptor.timeout = timeout
+	return adaptor
+}
@@ -49,10 +49,12 @@ var TransferToAgentTool = NewTool(
 			}, nil
 		}
 
-		// Set transfer info to context
-		// In a real implementation, this would use proper context values
-		// that are read by the agent system later
-		// For now we just return the agent name so it can be handled elsewhere
+		// Request the transfer; the flow hands the invocation over to the
+		// target agent once the function response has been emitted
+		if toolContext, ok := ToolContextFromContext(ctx); ok && toolContext.EventActions != nil {
+			toolContext.EventActions.TransferToAgent = agentName
+		}
+
 		return map[string]interface{}{
 			"success":      true,
 			"target_agent": agentName,
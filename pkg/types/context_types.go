@@ -18,6 +18,7 @@ package types
 import (
 	"fmt"
 	"sync"
+	"time"
 )
 
 // StreamingMode defines how responses should be streamed
@@ -41,6 +42,85 @@ type RunConfig struct {
 
 	// SupportCFC indicates if client-function-call (CFC) is supported
 	SupportCFC bool `json:"supportCfc,omitempty"`
+
+	// InvocationTimeout limits how long a whole invocation may run. Zero
+	// means no limit.
+	InvocationTimeout time.Duration `json:"invocationTimeout,omitempty"`
+
+	// LlmCallTimeout limits each call to the model. Zero means no limit.
+	LlmCallTimeout time.Duration `json:"llmCallTimeout,omitempty"`
+
+	// ToolTimeout limits each tool call, unless the tool sets its own
+	// timeout. Zero means no limit.
+	ToolTimeout time.Duration `json:"toolTimeout,omitempty"`
+
+	// InvocationBudget limits what the model calls of one invocation may use
+	InvocationBudget *Budget `json:"invocationBudget,omitempty"`
+
+	// SessionBudget limits what the model calls of all invocations of the
+	// session may use, including earlier ones
+	SessionBudget *Budget `json:"sessionBudget,omitempty"`
+}
+
+// Usage holds the tokens used by model calls and their estimated cost in US
+// dollars
+type Usage struct {
+	// InputTokens is the number of tokens sent to the model
+	InputTokens int `json:"inputTokens"`
+
+	// OutputTokens is the number of tokens generated by the model, including
+	// the thought tokens
+	OutputTokens int `json:"outputTokens"`
+
+	// CachedInputTokens is the number of input tokens read from the model's
+	// context cache
+	CachedInputTokens int `json:"cachedInputTokens,omitempty"`
+
+	// ThoughtTokens is the number of tokens the model spent thinking
+	ThoughtTokens int `json:"thoughtTokens,omitempty"`
+
+	// Cost is the estimated cost in US dollars
+	Cost float64 `json:"cost"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:       u.InputTokens + other.InputTokens,
+		OutputTokens:      u.OutputTokens + other.OutputTokens,
+		CachedInputTokens: u.CachedInputTokens + other.CachedInputTokens,
+		ThoughtTokens:     u.ThoughtTokens + other.ThoughtTokens,
+		Cost:              u.Cost + other.Cost,
+	}
+}
+
+// Budget limits the tokens and estimated cost of model calls. Zero fields
+// are not limited.
+type Budget struct {
+	// MaxInputTokens limits the number of tokens sent to the model
+	MaxInputTokens int `json:"maxInputTokens,omitempty"`
+
+	// MaxOutputTokens limits the number of tokens generated by the model
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+
+	// MaxCost limits the estimated cost in US dollars
+	MaxCost float64 `json:"maxCost,omitempty"`
+}
+
+// Check returns an error explaining which limit the usage reached, if any.
+// A nil budget has no limits.
+func (b *Budget) Check(usage Usage) error {
+	switch {
+	case b == nil:
+		return nil
+	case b.MaxInputTokens > 0 && usage.InputTokens >= b.MaxInputTokens:
+		return fmt.Errorf("%d input tokens used, the limit is %d", usage.InputTokens, b.MaxInputTokens)
+	case b.MaxOutputTokens > 0 && usage.OutputTokens >= b.MaxOutputTokens:
+		return fmt.Errorf("%d output tokens used, the limit is %d", usage.OutputTokens, b.MaxOutputTokens)
+	case b.MaxCost > 0 && usage.Cost >= b.MaxCost:
+		return fmt.Errorf("an estimated $%.4f spent, the limit is $%.4f", usage.Cost, b.MaxCost)
+	}
+	return nil
 }
 
 // TranscriptionEntry represents an audio transcription entry
@@ -60,30 +140,51 @@ type InvocationContextData struct {
 	// RunConfig contains configuration for this invocation
 	RunConfig *RunConfig `json:"runConfig,omitempty"`
 
-	// EndInvocation indicates if the invocation should end
-	EndInvocation bool `json:"endInvocation,omitempty"`
-
 	// Branch is an optional branch identifier
 	Branch string `json:"branch,omitempty"`
 
 	// TranscriptionCache holds cached transcriptions
 	TranscriptionCache []TranscriptionEntry `json:"-"`
 
-	// LlmCallCount counts the number of LLM calls made
-	llmCallCount int
+	// llmCalls counts the number of LLM calls made and records whether the
+	// invocation should end. It is a pointer so that copies of this data made
+	// for sub-agents share the same counter and end flag.
+	llmCalls *llmCallCounter
+}
+
+// llmCallCounter counts LLM calls and their usage, and holds the end flag,
+// across all contexts of one invocation
+type llmCallCounter struct {
+	count         int
+	usage         Usage
+	endInvocation bool
+	mu            sync.Mutex
+}
+
+// counter returns the shared LLM call counter
+func (ctx *InvocationContextData) counter() *llmCallCounter {
+	ctx.Share()
+	return ctx.llmCalls
+}
 
-	// mu protects concurrent access
-	mu sync.Mutex
+// Share initializes the shared state of this data so that copies of it keep
+// referring to the same counters. It must be called before the data is copied
+// or used concurrently.
+func (ctx *InvocationContextData) Share() {
+	if ctx.llmCalls == nil {
+		ctx.llmCalls = &llmCallCounter{}
+	}
 }
 
 // IncrementLlmCallCount increments and checks the LLM call count
 func (ctx *InvocationContextData) IncrementLlmCallCount() error {
-	ctx.mu.Lock()
-	defer ctx.mu.Unlock()
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
 
-	ctx.llmCallCount++
+	counter.count++
 
-	if ctx.RunConfig.MaxLlmCalls > 0 && ctx.llmCallCount > ctx.RunConfig.MaxLlmCalls {
+	if ctx.RunConfig != nil && ctx.RunConfig.MaxLlmCalls > 0 && counter.count > ctx.RunConfig.MaxLlmCalls {
 		return fmt.Errorf("maximum number of LLM calls (%d) exceeded", ctx.RunConfig.MaxLlmCalls)
 	}
 
@@ -92,10 +193,50 @@ func (ctx *InvocationContextData) IncrementLlmCallCount() error {
 
 // GetLlmCallCount returns the current LLM call count
 func (ctx *InvocationContextData) GetLlmCallCount() int {
-	ctx.mu.Lock()
-	defer ctx.mu.Unlock()
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	return counter.count
+}
+
+// AddUsage records the usage of an LLM call and returns the usage of the
+// invocation so far
+func (ctx *InvocationContextData) AddUsage(usage Usage) Usage {
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	counter.usage = counter.usage.Add(usage)
+	return counter.usage
+}
+
+// GetUsage returns the usage of the invocation's LLM calls so far
+func (ctx *InvocationContextData) GetUsage() Usage {
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	return counter.usage
+}
+
+// IsEndInvocation returns whether the invocation should end
+func (ctx *InvocationContextData) IsEndInvocation() bool {
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	return counter.endInvocation
+}
+
+// SetEndInvocation sets whether the invocation should end. The flag is seen
+// by every context of the invocation, including those of parent agents.
+func (ctx *InvocationContextData) SetEndInvocation(end bool) {
+	counter := ctx.counter()
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
 
-	return ctx.llmCallCount
+	counter.endInvocation = end
 }
 
 // EventActionsData contains event actions that can be shared across packages